@@ -0,0 +1,230 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"Goworkspace/Project/domain"
+)
+
+// FileSink дописывает записи в файл по одной JSON-строке и никогда не меняет написанное.
+// Query читает файл целиком: журнал рассчитан на редкие запросы.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+	path string
+	last int64 // ID последней записи
+}
+
+// OpenFileSink открывает или создаёт журнал; нумерация продолжается с последней записи.
+func OpenFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+
+	s := &FileSink{file: file, path: path}
+	if err := s.terminate(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := s.scan(func(rec domain.AuditRecord) bool {
+		s.last = rec.ID
+		return true
+	}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) Append(ctx context.Context, rec domain.AuditRecord) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		rec.ID = s.last + 1
+		line, err := json.Marshal(newFileRecord(rec))
+		if err != nil {
+			return err
+		}
+		// Одна запись - один вызов write: с O_APPEND строки не перемешиваются
+		if _, err := s.file.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("write audit log: %w", err)
+		}
+		s.last = rec.ID
+		return nil
+	}
+}
+
+func (s *FileSink) Query(ctx context.Context, q domain.AuditQuery) ([]domain.AuditRecord, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		res := make([]domain.AuditRecord, 0)
+		err := s.scan(func(rec domain.AuditRecord) bool {
+			if rec.ID > q.AfterID && q.Match(rec) {
+				res = append(res, rec)
+			}
+			return q.Limit <= 0 || len(res) < q.Limit
+		})
+		return res, err
+	}
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+// terminate завершает строку, недописанную при сбое, чтобы следующая запись начиналась с новой строки.
+func (s *FileSink) terminate() error {
+	info, err := s.file.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("read audit log: %w", err)
+	}
+	defer file.Close()
+
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return fmt.Errorf("read audit log: %w", err)
+	}
+	if last[0] != '\n' {
+		if _, err := s.file.Write([]byte{'\n'}); err != nil {
+			return fmt.Errorf("write audit log: %w", err)
+		}
+	}
+	return nil
+}
+
+// scan читает записи по порядку, пока fn возвращает true.
+// Повреждённые строки (сбой во время записи) пропускаются с сообщением в лог.
+func (s *FileSink) scan(fn func(domain.AuditRecord) bool) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("read audit log: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("read audit log: %w", err)
+		}
+		if len(line) > 0 {
+			var rec fileRecord
+			if jsonErr := json.Unmarshal(line, &rec); jsonErr != nil {
+				log.Printf("[ERROR]: audit log %s: skipping damaged line %d: %v", s.path, lineNo, jsonErr)
+			} else if !fn(rec.toDomain()) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// fileRecord - формат строки журнала. Меняется только добавлением полей.
+type fileRecord struct {
+	ID        int64     `json:"id"`
+	Tenant    string    `json:"tenant,omitempty"` // нет в строках, записанных до разделения по арендаторам
+	At        time.Time `json:"at"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	ItemID    int       `json:"item_id"`
+	Before    *fileItem `json:"before,omitempty"`
+	After     *fileItem `json:"after,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+}
+
+type fileItem struct {
+	ID         int               `json:"id"`
+	Name       string            `json:"name"`
+	Version    int               `json:"version"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	ParentID   int               `json:"parent_id,omitempty"`
+	ExpiresAt  *time.Time        `json:"expires_at,omitempty"` // указатель: omitempty не пропускает нулевой time.Time
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+}
+
+func newFileRecord(rec domain.AuditRecord) fileRecord {
+	return fileRecord{
+		ID:        rec.ID,
+		Tenant:    rec.Tenant,
+		At:        rec.At,
+		Actor:     rec.Actor,
+		Action:    string(rec.Action),
+		ItemID:    rec.ItemID,
+		Before:    newFileItem(rec.Before),
+		After:     newFileItem(rec.After),
+		RequestID: rec.RequestID,
+		ClientIP:  rec.ClientIP,
+	}
+}
+
+func newFileItem(item *domain.Item) *fileItem {
+	if item == nil {
+		return nil
+	}
+	res := &fileItem{ID: item.ID, Name: item.Name, Version: item.Version, CreatedAt: item.CreatedAt, UpdatedAt: item.UpdatedAt, ParentID: item.ParentID, Attributes: item.Attributes, Tags: item.Tags}
+	if !item.ExpiresAt.IsZero() {
+		res.ExpiresAt = &item.ExpiresAt
+	}
+	return res
+}
+
+func (r fileRecord) toDomain() domain.AuditRecord {
+	tenant := r.Tenant
+	if tenant == "" {
+		tenant = domain.DefaultTenant
+	}
+	return domain.AuditRecord{
+		ID:        r.ID,
+		Tenant:    tenant,
+		At:        r.At,
+		Actor:     r.Actor,
+		Action:    domain.AuditAction(r.Action),
+		ItemID:    r.ItemID,
+		Before:    r.Before.toDomain(),
+		After:     r.After.toDomain(),
+		RequestID: r.RequestID,
+		ClientIP:  r.ClientIP,
+	}
+}
+
+func (i *fileItem) toDomain() *domain.Item {
+	if i == nil {
+		return nil
+	}
+	res := &domain.Item{ID: i.ID, Name: i.Name, Version: i.Version, CreatedAt: i.CreatedAt, UpdatedAt: i.UpdatedAt, ParentID: i.ParentID, Attributes: i.Attributes, Tags: i.Tags}
+	if i.ExpiresAt != nil {
+		res.ExpiresAt = *i.ExpiresAt
+	}
+	return res
+}
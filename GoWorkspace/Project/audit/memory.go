@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"Goworkspace/Project/domain"
+)
+
+// MemorySink хранит записи в памяти процесса: для тестов и одного экземпляра без требований к хранению.
+type MemorySink struct {
+	mu      sync.RWMutex
+	records []domain.AuditRecord // по возрастанию ID
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+func (s *MemorySink) Append(ctx context.Context, rec domain.AuditRecord) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		rec.ID = int64(len(s.records)) + 1
+		s.records = append(s.records, rec)
+		return nil
+	}
+}
+
+func (s *MemorySink) Query(ctx context.Context, q domain.AuditQuery) ([]domain.AuditRecord, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		start := sort.Search(len(s.records), func(i int) bool { return s.records[i].ID > q.AfterID })
+		return filter(s.records[start:], q), nil
+	}
+}
+
+// filter отбирает до q.Limit подходящих записей (q.Limit <= 0 - все) в порядке records.
+func filter(records []domain.AuditRecord, q domain.AuditQuery) []domain.AuditRecord {
+	res := make([]domain.AuditRecord, 0)
+	for _, rec := range records {
+		if q.Limit > 0 && len(res) >= q.Limit {
+			break
+		}
+		if rec.ID > q.AfterID && q.Match(rec) {
+			res = append(res, rec)
+		}
+	}
+	return res
+}
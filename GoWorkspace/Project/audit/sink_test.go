@@ -0,0 +1,118 @@
+package audit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Goworkspace/Project/audit"
+	"Goworkspace/Project/domain"
+)
+
+var base = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func seed(t *testing.T, sink domain.AuditSink) {
+	t.Helper()
+	records := []domain.AuditRecord{
+		{At: base, Actor: "alice", Action: domain.AuditCreate, ItemID: 1, After: &domain.Item{ID: 1, Name: "a", Attributes: map[string]string{"k": "v"}}},
+		{At: base.Add(time.Minute), Actor: "bob", Action: domain.AuditUpdate, ItemID: 1, Before: &domain.Item{ID: 1, Name: "a"}, After: &domain.Item{ID: 1, Name: "b"}},
+		{At: base.Add(2 * time.Minute), Actor: "alice", Action: domain.AuditDelete, ItemID: 1, Before: &domain.Item{ID: 1, Name: "b"}, RequestID: "req-1", ClientIP: "10.0.0.1"},
+		{At: base.Add(3 * time.Minute), Actor: "alice", Action: domain.AuditCreate, ItemID: 2, After: &domain.Item{ID: 2, Name: "c"}},
+	}
+	for _, rec := range records {
+		if err := sink.Append(context.Background(), rec); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+}
+
+func ids(records []domain.AuditRecord) []int64 {
+	res := make([]int64, 0, len(records))
+	for _, rec := range records {
+		res = append(res, rec.ID)
+	}
+	return res
+}
+
+func testSink(t *testing.T, sink domain.AuditSink) {
+	seed(t, sink)
+
+	cases := []struct {
+		name     string
+		query    domain.AuditQuery
+		expected []int64
+	}{
+		{"all", domain.AuditQuery{}, []int64{1, 2, 3, 4}},
+		{"actor", domain.AuditQuery{Actor: "alice"}, []int64{1, 3, 4}},
+		{"action and item", domain.AuditQuery{Action: domain.AuditDelete, ItemID: 1}, []int64{3}},
+		{"request id", domain.AuditQuery{RequestID: "req-1"}, []int64{3}},
+		{"time range", domain.AuditQuery{From: base.Add(time.Minute), To: base.Add(3 * time.Minute)}, []int64{2, 3}},
+		{"after and limit", domain.AuditQuery{AfterID: 1, Limit: 2}, []int64{2, 3}},
+		{"limit counts matches", domain.AuditQuery{Actor: "alice", Limit: 2}, []int64{1, 3}},
+	}
+	for _, tc := range cases {
+		records, err := sink.Query(context.Background(), tc.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := ids(records); len(got) != len(tc.expected) || (len(got) > 0 && (got[0] != tc.expected[0] || got[len(got)-1] != tc.expected[len(tc.expected)-1])) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+
+	records, _ := sink.Query(context.Background(), domain.AuditQuery{RequestID: "req-1"})
+	rec := records[0]
+	if rec.Before == nil || rec.Before.Name != "b" || rec.After != nil || rec.ClientIP != "10.0.0.1" || !rec.At.Equal(base.Add(2*time.Minute)) {
+		t.Fatalf("record not preserved: %+v", rec)
+	}
+}
+
+func TestMemorySink(t *testing.T) {
+	testSink(t, audit.NewMemorySink())
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	t.Run("Query", func(t *testing.T) {
+		sink, err := audit.OpenFileSink(path)
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		defer sink.Close()
+
+		testSink(t, sink)
+
+		records, _ := sink.Query(context.Background(), domain.AuditQuery{ItemID: 1, Action: domain.AuditCreate})
+		if len(records) != 1 || records[0].After.Attributes["k"] != "v" {
+			t.Fatalf("attributes not preserved: %+v", records)
+		}
+	})
+
+	t.Run("Reopen continues numbering after damaged tail", func(t *testing.T) {
+		// Сбой посреди записи оставляет недописанную строку
+		file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		file.WriteString(`{"id":5,"act`)
+		file.Close()
+
+		sink, err := audit.OpenFileSink(path)
+		if err != nil {
+			t.Fatalf("reopen: %v", err)
+		}
+		defer sink.Close()
+
+		if err := sink.Append(context.Background(), domain.AuditRecord{At: base, Actor: "carol", Action: domain.AuditRestore, ItemID: 1}); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+
+		records, err := sink.Query(context.Background(), domain.AuditQuery{})
+		if err != nil {
+			t.Fatalf("query: %v", err)
+		}
+		if got := ids(records); len(got) != 5 || got[4] != 5 || records[4].Actor != "carol" {
+			t.Fatalf("expected records 1-5 with carol last, got %v", got)
+		}
+	})
+}
@@ -6,19 +6,96 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
+	"Goworkspace/Project/audit"
 	"Goworkspace/Project/domain"
+	"Goworkspace/Project/events"
 	"Goworkspace/Project/storage"
+	"Goworkspace/Project/stream"
 	"Goworkspace/Project/transport"
+	"Goworkspace/Project/webhook"
 )
 
 func main() {
-	st := storage.NewMemoryStorage()
-	service := domain.NewService(st)
+	clock := domain.SystemClock{} // общие часы сервиса и хранилища
+	st := storage.NewMemoryStorage(clock)
+	bus := events.NewBus()
+	bus.SubscribeAsync("log", events.DefaultBuffer, func(ctx context.Context, e domain.Event) error {
+		log.Printf("[INFO]: event %s: tenant=%s id=%d", e.EventType(), domain.TenantFromContext(ctx), e.ItemID())
+		return nil
+	})
 
-	r := transport.NewRouter(service)
+	// AUDIT_LOG - путь к журналу аудита; без него журнал живёт только в памяти
+	var auditSink domain.AuditSink = audit.NewMemorySink()
+	if path := os.Getenv("AUDIT_LOG"); path != "" {
+		fileSink, err := audit.OpenFileSink(path)
+		if err != nil {
+			log.Fatalf("[ERROR]: %v", err)
+		}
+		defer fileSink.Close()
+		auditSink = fileSink
+	}
+
+	service := domain.NewService(st, clock, domain.WithEventPublisher(bus), domain.WithAuditSink(auditSink))
+
+	webhookCfg := webhook.DefaultConfig()
+	webhookCfg.Timeout = envDuration("WEBHOOK_TIMEOUT", webhookCfg.Timeout)
+	webhookCfg.BaseBackoff = envDuration("WEBHOOK_BASE_BACKOFF", webhookCfg.BaseBackoff)
+	webhookCfg.AllowPrivate = envBool("WEBHOOK_ALLOW_PRIVATE") // только для локальной разработки
+	webhooks := webhook.NewDispatcher(webhookCfg)
+	bus.SubscribeAsync("webhooks", events.DefaultBuffer, webhooks.Handle)
+
+	broker := stream.NewBroker(stream.DefaultHistory, stream.DefaultClientBuffer)
+	bus.Subscribe("stream", broker.Handle)
+
+	idempotency := transport.NewIdempotencyStore(envDuration("IDEMPOTENCY_TTL", transport.DefaultIdempotencyTTL))
+
+	routerOpts := []transport.RouterOption{
+		transport.WithIdempotencyStore(idempotency),
+		transport.WithWebhooks(webhooks),
+		transport.WithEventStream(broker, envDuration("SSE_HEARTBEAT", transport.DefaultHeartbeat)),
+	}
+	if envBool("LEGACY_ERRORS") {
+		routerOpts = append(routerOpts, transport.WithLegacyErrors())
+	}
+	r := transport.NewRouter(service, routerOpts...)
+
+	// Фоновые задачи живут до остановки сервера
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	defer bgCancel()
+	var bg sync.WaitGroup
+
+	retention := envDuration("TRASH_RETENTION", 24*time.Hour)
+	sweepInterval := envDuration("TRASH_SWEEP_INTERVAL", time.Minute)
+
+	bg.Add(1)
+	go func() {
+		defer bg.Done()
+		service.RunTrashSweeper(bgCtx, sweepInterval, retention)
+	}()
+
+	bg.Add(1)
+	go func() {
+		defer bg.Done()
+		service.RunChangeCompactor(bgCtx, envDuration("CHANGES_COMPACT_INTERVAL", time.Minute), envDuration("CHANGES_RETENTION", 24*time.Hour))
+	}()
+
+	// Истёкшие элементы не видны сразу; жнец удаляет их и публикует удаление, пока шина открыта
+	bg.Add(1)
+	go func() {
+		defer bg.Done()
+		service.RunExpiryReaper(bgCtx, envDuration("EXPIRY_REAP_INTERVAL", time.Second))
+	}()
+
+	bg.Add(1)
+	go func() {
+		defer bg.Done()
+		idempotency.RunSweeper(bgCtx, envDuration("IDEMPOTENCY_SWEEP_INTERVAL", time.Minute))
+	}()
 
 	srv := &http.Server{
 		Addr:         ":8080",
@@ -27,6 +104,8 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// Открытые потоки /items/events иначе держали бы Shutdown до таймаута
+	srv.RegisterOnShutdown(broker.Close)
 
 	go func() {
 		log.Println("[INFO]: server started on :8080")
@@ -51,5 +130,47 @@ func main() {
 		log.Printf("[ERROR]: graceful shutdown failed: %v", err)
 	}
 
+	bgCancel()
+	bg.Wait()
+
+	// Запросов больше нет: дожидаемся подписчиков с уже опубликованными событиями
+	if err := bus.Close(ctx); err != nil {
+		log.Printf("[ERROR]: event bus close failed: %v", err)
+	}
+	// Шина закрыта, новых доставок не будет; недоставленное к сроку уходит в dead-letter
+	if err := webhooks.Close(ctx); err != nil {
+		log.Printf("[ERROR]: webhook dispatcher close failed: %v", err)
+	}
+
 	log.Println("[INFO]: server stopped")
 }
+
+func envBool(key string) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return false
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("[ERROR]: invalid %s=%q, using false", key, value)
+		return false
+	}
+
+	return b
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("[ERROR]: invalid %s=%q, using default %s", key, value, def)
+		return def
+	}
+
+	return d
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxAttributes           = 32  // Максимальное число атрибутов у элемента
+	MaxAttributeKeyLength   = 64  // Максимальная длина ключа атрибута
+	MaxAttributeValueLength = 256 // Максимальная длина значения атрибута в байтах
+)
+
+// checkAttributes проверяет число атрибутов, формат ключей и размер значений.
+// Ключ: латиница в нижнем регистре, цифры, '_', '-', '.', начинается с буквы.
+// Нарушения добавляются в поле field и в field.<ключ>.
+func (e *ValidationError) checkAttributes(field string, attrs map[string]string) {
+	if len(attrs) > MaxAttributes {
+		e.Add(field, CodeTooMany, fmt.Sprintf("at most %d attributes allowed, got %d", MaxAttributes, len(attrs)), ErrInvalidValue)
+	}
+
+	// Порядок ключей фиксирован, чтобы ответ не менялся от запроса к запросу
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyField := field + "." + key
+		if !validKey(key, MaxAttributeKeyLength) {
+			e.Add(keyField, CodeInvalid, keyRule(MaxAttributeKeyLength), ErrInvalidValue)
+		}
+		e.checkText(keyField, attrs[key], MaxAttributeValueLength)
+	}
+}
+
+// validKey проверяет ключ атрибута или тег: латиница в нижнем регистре, цифры, '_', '-', '.',
+// первый символ - буква.
+func validKey(key string, maxLen int) bool {
+	if key == "" || len(key) > maxLen {
+		return false
+	}
+
+	for i, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z':
+		case i > 0 && (r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func keyRule(maxLen int) string {
+	return fmt.Sprintf("must be 1-%d characters of a-z, 0-9, '_', '-', '.' starting with a letter", maxLen)
+}
+
+// checkText проверяет длину строки в байтах, кодировку UTF-8 и отсутствие управляющих символов.
+func (e *ValidationError) checkText(field, value string, maxLen int) {
+	if len(value) > maxLen {
+		e.Add(field, CodeTooLong, fmt.Sprintf("must be at most %d bytes", maxLen), ErrInvalidValue)
+		return
+	}
+	if !utf8.ValidString(value) {
+		e.Add(field, CodeInvalid, "must be valid UTF-8", ErrInvalidValue)
+		return
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			e.Add(field, CodeInvalid, "must not contain control characters", ErrInvalidValue)
+			return
+		}
+	}
+}
+
+// cloneAttributes копирует карту, чтобы вызывающий не мог изменить сохранённый элемент.
+// Пустая карта превращается в nil.
+func cloneAttributes(attrs map[string]string) map[string]string {
+	if len(attrs) == 0 {
+		return nil
+	}
+	return maps.Clone(attrs)
+}
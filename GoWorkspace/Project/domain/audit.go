@@ -0,0 +1,171 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate    AuditAction = "create"
+	AuditUpdate    AuditAction = "update"
+	AuditDelete    AuditAction = "delete"
+	AuditRestore   AuditAction = "restore"
+	AuditAddTag    AuditAction = "tag.add"
+	AuditRemoveTag AuditAction = "tag.remove"
+	AuditExpire    AuditAction = "expire" // удаление по истечении срока жизни
+)
+
+var auditActions = []AuditAction{AuditCreate, AuditUpdate, AuditDelete, AuditRestore, AuditAddTag, AuditRemoveTag, AuditExpire}
+
+// SystemActor - автор изменений, сделанных не по запросу клиента.
+const SystemActor = "system"
+
+// Actor - кто и откуда выполняет запрос. Транспорт кладёт его в контекст через ContextWithActor.
+type Actor struct {
+	Name      string
+	RequestID string
+	ClientIP  string
+}
+
+type actorKey struct{}
+
+func ContextWithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext возвращает автора запроса; без него - SystemActor.
+func ActorFromContext(ctx context.Context) Actor {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	if !ok || actor.Name == "" {
+		actor.Name = SystemActor
+	}
+	return actor
+}
+
+// AuditRecord - одна изменяющая операция над элементом.
+type AuditRecord struct {
+	ID        int64 // выдаёт AuditSink, по возрастанию
+	Tenant    string
+	At        time.Time
+	Actor     string
+	Action    AuditAction
+	ItemID    int
+	Before    *Item // nil - элемента не было (создание, восстановление)
+	After     *Item // nil - элемента не стало (удаление)
+	RequestID string
+	ClientIP  string
+}
+
+// AuditQuery - фильтр журнала аудита, заданные поля объединяются по И.
+type AuditQuery struct {
+	Tenant    string // Service.Audit подставляет арендатора запроса
+	Actor     string
+	Action    AuditAction
+	ItemID    int
+	RequestID string
+	From, To  time.Time // [From, To); нулевое время - без границы
+	AfterID   int64     // записи с ID > AfterID, для постраничного чтения
+	Limit     int
+}
+
+// Match сообщает, подходит ли запись под фильтр (без AfterID и Limit).
+func (q AuditQuery) Match(rec AuditRecord) bool {
+	return (q.Tenant == "" || rec.Tenant == q.Tenant) &&
+		(q.Actor == "" || rec.Actor == q.Actor) &&
+		(q.Action == "" || rec.Action == q.Action) &&
+		(q.ItemID == 0 || rec.ItemID == q.ItemID) &&
+		(q.RequestID == "" || rec.RequestID == q.RequestID) &&
+		(q.From.IsZero() || !rec.At.Before(q.From)) &&
+		(q.To.IsZero() || rec.At.Before(q.To))
+}
+
+// AuditSink хранит записи аудита. Append присваивает записи ID.
+type AuditSink interface {
+	Append(ctx context.Context, rec AuditRecord) error
+	Query(ctx context.Context, q AuditQuery) ([]AuditRecord, error) // по возрастанию ID, не больше q.Limit
+}
+
+type AuditPage struct {
+	Records   []AuditRecord
+	NextAfter int64 // 0 - записей больше нет
+}
+
+// Audit ищет записи журнала аудита арендатора из ctx.
+func (s *Service) Audit(ctx context.Context, q AuditQuery) (AuditPage, error) {
+	var v ValidationError
+	if q.Limit == 0 {
+		q.Limit = DefaultListLimit
+	}
+	if q.Limit < 0 || q.Limit > MaxListLimit {
+		v.Add("limit", CodeOutOfRange, fmt.Sprintf("must be between 1 and %d", MaxListLimit), ErrInvalidValue)
+	}
+	if q.Action != "" && !slices.Contains(auditActions, q.Action) {
+		v.Add("action", CodeInvalid, "unknown action", ErrInvalidValue)
+	}
+	if q.ItemID < 0 {
+		v.Add("item_id", CodeOutOfRange, "must be a positive integer", ErrInvalidValue)
+	}
+	if q.AfterID < 0 {
+		v.Add("after", CodeOutOfRange, "must not be negative", ErrInvalidValue)
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		v.Add("to", CodeOutOfRange, "must be after from", ErrInvalidValue)
+	}
+	if err := v.Err(); err != nil {
+		return AuditPage{}, err
+	}
+
+	q.Tenant = TenantFromContext(ctx)
+
+	// Запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+	limit := q.Limit
+	q.Limit++
+	records, err := s.auditSink.Query(ctx, q)
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return AuditPage{}, err
+		}
+		return AuditPage{}, ErrInternal
+	}
+
+	page := AuditPage{Records: records}
+	if len(records) > limit {
+		page.Records = records[:limit]
+		page.NextAfter = page.Records[limit-1].ID
+	}
+
+	return page, nil
+}
+
+// audit записывает операцию. Запись в хранилище уже сделана, поэтому сбой
+// аудита не отменяет запрос, а только логируется.
+func (s *Service) audit(ctx context.Context, action AuditAction, itemID int, before, after *Item) {
+	actor := ActorFromContext(ctx)
+	rec := AuditRecord{
+		Tenant:    TenantFromContext(ctx),
+		At:        s.clock.Now(),
+		Actor:     actor.Name,
+		Action:    action,
+		ItemID:    itemID,
+		Before:    before,
+		After:     after,
+		RequestID: actor.RequestID,
+		ClientIP:  actor.ClientIP,
+	}
+	if err := s.auditSink.Append(context.WithoutCancel(ctx), rec); err != nil {
+		log.Printf("[ERROR]: audit %s id=%d tenant=%s actor=%s request_id=%s: %v", action, itemID, rec.Tenant, actor.Name, actor.RequestID, err)
+	}
+}
+
+type noopAuditSink struct{}
+
+func (noopAuditSink) Append(context.Context, AuditRecord) error { return nil }
+
+func (noopAuditSink) Query(context.Context, AuditQuery) ([]AuditRecord, error) { return nil, nil }
@@ -0,0 +1,143 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"Goworkspace/Project/audit"
+	"Goworkspace/Project/domain"
+	"Goworkspace/Project/storage"
+)
+
+type failingSink struct{}
+
+func (failingSink) Append(context.Context, domain.AuditRecord) error { return errors.New("disk full") }
+func (failingSink) Query(context.Context, domain.AuditQuery) ([]domain.AuditRecord, error) {
+	return nil, errors.New("disk full")
+}
+
+func TestService_Audit(t *testing.T) {
+	actor := domain.Actor{Name: "alice", RequestID: "req-1", ClientIP: "10.0.0.1"}
+	ctx := domain.ContextWithActor(context.Background(), actor)
+
+	newService := func() (*domain.Service, *audit.MemorySink) {
+		sink := audit.NewMemorySink()
+		return domain.NewService(storage.NewMemoryStorage(domain.SystemClock{}), domain.SystemClock{}, domain.WithAuditSink(sink)), sink
+	}
+
+	records := func(t *testing.T, service *domain.Service, q domain.AuditQuery) []domain.AuditRecord {
+		t.Helper()
+		page, err := service.Audit(context.Background(), q)
+		if err != nil {
+			t.Fatalf("audit: %v", err)
+		}
+		return page.Records
+	}
+
+	t.Run("Mutations record actor and states", func(t *testing.T) {
+		service, _ := newService()
+
+		item, _ := service.Create(ctx, domain.Item{Name: "a"})
+		service.Update(ctx, domain.Item{ID: item.ID, Name: "b"})
+		service.Modify(ctx, item.ID, 0, func(i domain.Item) (domain.Item, error) {
+			i.Name = "c"
+			return i, nil
+		})
+		service.AddTag(ctx, item.ID, 0, "red")
+		service.AddTag(ctx, item.ID, 0, "red") // повтор ничего не меняет
+		service.RemoveTag(ctx, item.ID, 0, "red")
+		service.Delete(ctx, item.ID, 0)
+		service.Restore(ctx, item.ID)
+		service.CreateBatch(ctx, itemsNamed("x", "y"))
+		service.DeleteBatch(ctx, domain.BulkDelete{Name: domain.NameFilter{Exact: "x"}, DryRun: true})
+		service.DeleteBatch(ctx, domain.BulkDelete{Name: domain.NameFilter{Exact: "x"}})
+
+		got := records(t, service, domain.AuditQuery{})
+		var actions []string
+		for _, rec := range got {
+			actions = append(actions, fmt.Sprintf("%s:%d", rec.Action, rec.ItemID))
+			if rec.Actor != "alice" || rec.RequestID != "req-1" || rec.ClientIP != "10.0.0.1" || rec.At.IsZero() {
+				t.Fatalf("unexpected record: %+v", rec)
+			}
+		}
+		expected := "[create:1 update:1 update:1 tag.add:1 tag.remove:1 delete:1 restore:1 create:2 create:3 delete:2]"
+		if fmt.Sprint(actions) != expected {
+			t.Fatalf("expected %s, got %v", expected, actions)
+		}
+
+		update := got[1]
+		if update.Before == nil || update.Before.Name != "a" || update.After == nil || update.After.Name != "b" {
+			t.Fatalf("unexpected update states: %+v %+v", update.Before, update.After)
+		}
+		if patch := got[2]; patch.Before.Name != "b" || patch.After.Name != "c" {
+			t.Fatalf("unexpected modify states: %+v %+v", patch.Before, patch.After)
+		}
+		if del := got[5]; del.Before == nil || del.Before.Name != "c" || del.After != nil {
+			t.Fatalf("unexpected delete states: %+v %+v", del.Before, del.After)
+		}
+		if batchDel := got[9]; batchDel.Before == nil || batchDel.Before.Name != "x" {
+			t.Fatalf("unexpected batch delete state: %+v", batchDel.Before)
+		}
+	})
+
+	t.Run("Calls without actor are attributed to system", func(t *testing.T) {
+		service, _ := newService()
+		service.Create(context.Background(), domain.Item{Name: "a"})
+
+		if got := records(t, service, domain.AuditQuery{Actor: domain.SystemActor}); len(got) != 1 {
+			t.Fatalf("expected system record, got %+v", got)
+		}
+	})
+
+	t.Run("Concurrent updates keep exact before states", func(t *testing.T) {
+		service, _ := newService()
+		item, _ := service.Create(ctx, domain.Item{Name: "a"})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				// Запись без версии не должна проигрывать гонку с соседними
+				if _, err := service.Update(ctx, domain.Item{ID: item.ID, Name: fmt.Sprintf("n%d", i)}); err != nil {
+					t.Errorf("unconditional update %d: %v", i, err)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		updates := records(t, service, domain.AuditQuery{Action: domain.AuditUpdate, Limit: domain.MaxListLimit})
+		if len(updates) != 50 {
+			t.Fatalf("expected 50 update records, got %d", len(updates))
+		}
+		for _, rec := range updates {
+			if rec.Before.Version+1 != rec.After.Version {
+				t.Fatalf("before v%d does not precede after v%d", rec.Before.Version, rec.After.Version)
+			}
+		}
+	})
+
+	t.Run("Sink failure does not fail the request", func(t *testing.T) {
+		service := domain.NewService(storage.NewMemoryStorage(domain.SystemClock{}), domain.SystemClock{}, domain.WithAuditSink(failingSink{}))
+
+		if _, err := service.Create(ctx, domain.Item{Name: "a"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := service.Audit(ctx, domain.AuditQuery{}); !errors.Is(err, domain.ErrInternal) {
+			t.Fatalf("expected ErrInternal from query, got: %v", err)
+		}
+	})
+
+	t.Run("Invalid query", func(t *testing.T) {
+		service, _ := newService()
+
+		_, err := service.Audit(ctx, domain.AuditQuery{Action: "rename", ItemID: -1, Limit: domain.MaxListLimit + 1})
+		var validationErr *domain.ValidationError
+		if !errors.As(err, &validationErr) || len(validationErr.Violations) != 3 {
+			t.Fatalf("expected 3 violations, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,235 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const MaxBatchSize = 1000 // Максимальное число элементов в пакетном запросе
+
+var ErrDuplicateInBatch = errors.New("duplicate in batch") // Повтор имени внутри одного пакета
+
+// IndexError - ошибка элемента пакета с его позицией в запросе.
+type IndexError struct {
+	Index int
+	Err   error
+}
+
+// BatchError собирает ошибки всех отклонённых элементов пакета.
+// Пакет применяется целиком или не применяется вовсе.
+type BatchError struct {
+	Errors []IndexError
+}
+
+func (e *BatchError) Error() string {
+	parts := make([]string, 0, len(e.Errors))
+	for _, itemErr := range e.Errors {
+		parts = append(parts, fmt.Sprintf("[%d]: %v", itemErr.Index, itemErr.Err))
+	}
+	return "batch rejected: " + strings.Join(parts, "; ")
+}
+
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, itemErr := range e.Errors {
+		errs = append(errs, itemErr.Err)
+	}
+	return errs
+}
+
+// checkBatchSize проверяет, что пакет не пустой и не больше MaxBatchSize.
+func checkBatchSize(field string, size int) error {
+	switch {
+	case size == 0:
+		return NewValidationError(field, CodeRequired, "must not be empty", ErrInvalidValue)
+	case size > MaxBatchSize:
+		return NewValidationError(field, CodeTooMany, fmt.Sprintf("at most %d items allowed, got %d", MaxBatchSize, size), ErrInvalidValue)
+	}
+	return nil
+}
+
+// CreateBatch создаёт все элементы пакета или ни одного. Как и в Create,
+// из входных данных используются Name, Attributes, ParentID и срок жизни; родитель должен уже существовать.
+func (s *Service) CreateBatch(ctx context.Context, inputs []Item) ([]Item, error) {
+	if err := checkBatchSize("items", len(inputs)); err != nil {
+		return nil, err
+	}
+
+	var batchErr BatchError
+	seen := make(map[string]int, len(inputs))
+	items := make([]Item, 0, len(inputs))
+	now := s.clock.Now()
+
+	for i, input := range inputs {
+		var v ValidationError
+		v.checkItem(input)
+		v.checkExpiry(input, now)
+		if err := v.Err(); err != nil {
+			batchErr.Errors = append(batchErr.Errors, IndexError{Index: i, Err: err})
+			continue
+		}
+		if first, ok := seen[input.Name]; ok {
+			batchErr.Errors = append(batchErr.Errors, IndexError{Index: i, Err: fmt.Errorf("%w: same as index %d", ErrDuplicateInBatch, first)})
+			continue
+		}
+		seen[input.Name] = i
+		items = append(items, Item{Name: input.Name, ParentID: input.ParentID, Attributes: cloneAttributes(input.Attributes), CreatedAt: now, UpdatedAt: now, ExpiresAt: expiresAt(input, now)})
+	}
+
+	if len(batchErr.Errors) > 0 {
+		return nil, &batchErr
+	}
+
+	created, err := s.storage.CreateItems(ctx, items)
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		var storageBatchErr *BatchError
+		if errors.As(err, &storageBatchErr) {
+			return nil, err
+		}
+		return nil, ErrInternal
+	}
+
+	for _, item := range created {
+		s.events.Publish(ctx, ItemCreated{Item: item, At: now})
+		s.audit(ctx, AuditCreate, item.ID, nil, snapshot(item))
+	}
+	return created, nil
+}
+
+type DeleteStatus string
+
+const (
+	DeleteStatusDeleted  DeleteStatus = "deleted"
+	DeleteStatusNotFound DeleteStatus = "not_found"
+
+	DeleteStatusHasChildren DeleteStatus = "has_children" // дети не удаляются вместе с элементом
+)
+
+// BulkDelete - массовое удаление: либо по списку IDs, либо по фильтру имени.
+// DryRun только сообщает, что было бы удалено.
+type BulkDelete struct {
+	IDs    []int
+	Name   NameFilter
+	DryRun bool
+}
+
+type DeleteResult struct {
+	ID     int
+	Status DeleteStatus
+}
+
+func (s *Service) DeleteBatch(ctx context.Context, req BulkDelete) ([]DeleteResult, error) {
+	var v ValidationError
+
+	// Фильтр сравнивается после нормализации: из одних пробелов получается пустой
+	var filterErr ValidationError
+	req.Name = req.Name.normalize(&filterErr)
+
+	// Нужен ровно один способ выбора: пустой фильтр удалил бы всё
+	hasIDs, hasFilter := len(req.IDs) > 0, req.Name != NameFilter{}
+	switch {
+	case hasIDs && hasFilter:
+		v.Add("ids", CodeConflict, "cannot be combined with filter", ErrInvalidValue)
+	case !hasIDs && !hasFilter:
+		v.Add("ids", CodeRequired, "either ids or filter is required", ErrInvalidValue)
+	case len(req.IDs) > MaxBatchSize:
+		v.Add("ids", CodeTooMany, fmt.Sprintf("at most %d ids allowed, got %d", MaxBatchSize, len(req.IDs)), ErrInvalidValue)
+	}
+
+	v.Merge("filter.", &filterErr)
+
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	var batchErr BatchError
+	ids := make([]int, 0, len(req.IDs))
+	seen := make(map[int]bool, len(req.IDs))
+	for i, id := range req.IDs {
+		var idErr ValidationError
+		idErr.checkID("id", id)
+		if err := idErr.Err(); err != nil {
+			batchErr.Errors = append(batchErr.Errors, IndexError{Index: i, Err: err})
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(batchErr.Errors) > 0 {
+		return nil, &batchErr
+	}
+	req.IDs = ids
+
+	now := s.clock.Now()
+	results, before, err := s.deleteItems(ctx, req, now)
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, ErrInternal
+	}
+
+	if !req.DryRun {
+		for _, res := range results {
+			if res.Status == DeleteStatusDeleted {
+				s.events.Publish(ctx, ItemDeleted{ID: res.ID, At: now})
+				item := before[res.ID]
+				s.audit(ctx, AuditDelete, res.ID, &item, nil)
+			}
+		}
+	}
+	return results, nil
+}
+
+// deleteItems удаляет пакет и возвращает состояние удалённых элементов до удаления.
+// Пробный прогон ничего не меняет и выполняется без транзакции.
+func (s *Service) deleteItems(ctx context.Context, req BulkDelete, at time.Time) ([]DeleteResult, map[int]Item, error) {
+	if req.DryRun {
+		results, err := s.storage.DeleteItems(ctx, req, at)
+		return results, nil, err
+	}
+
+	var (
+		results []DeleteResult
+		before  = make(map[int]Item)
+	)
+	err := s.storage.WithTx(ctx, func(tx Storage) error {
+		var items []Item
+		if len(req.IDs) > 0 {
+			for _, id := range req.IDs {
+				item, err := tx.GetItem(ctx, id)
+				if errors.Is(err, ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				items = append(items, item)
+			}
+		} else {
+			var err error
+			if items, err = tx.ListItems(ctx, ListOptions{Name: req.Name}); err != nil {
+				return err
+			}
+		}
+		for _, item := range items {
+			before[item.ID] = item
+		}
+
+		var err error
+		results, err = tx.DeleteItems(ctx, req, at)
+		return err
+	})
+
+	return results, before, err
+}
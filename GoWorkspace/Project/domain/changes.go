@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+var ErrRevisionCompacted = errors.New("revision compacted") // Изменения после ревизии уже не хранятся
+
+// Change - запись журнала изменений. Ревизии общие для всего хранилища и идут подряд с 1.
+type Change struct {
+	Revision int64
+	Type     EventType // item.created, item.updated или item.deleted
+	ItemID   int
+	Item     Item // состояние после записи; пусто у item.deleted
+	At       time.Time
+}
+
+type ChangePage struct {
+	Changes  []Change
+	Revision int64 // текущая ревизия хранилища
+	HasMore  bool  // есть изменения после последнего в Changes
+}
+
+// Changes возвращает изменения с ревизией больше since по возрастанию.
+// ErrRevisionCompacted - часть изменений после since уже удалена из журнала:
+// клиенту нужна полная синхронизация. since больше текущей ревизии - ErrInvalidValue.
+func (s *Service) Changes(ctx context.Context, since int64, limit int) (ChangePage, error) {
+	var v ValidationError
+	if since < 0 {
+		v.Add("since", CodeOutOfRange, "must not be negative", ErrInvalidValue)
+	}
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	if limit < 0 || limit > MaxListLimit {
+		v.Add("limit", CodeOutOfRange, fmt.Sprintf("must be between 1 and %d", MaxListLimit), ErrInvalidValue)
+	}
+	if err := v.Err(); err != nil {
+		return ChangePage{}, err
+	}
+
+	// Запрашиваем на одно изменение больше, чтобы узнать, есть ли продолжение
+	changes, revision, err := s.storage.ListChanges(ctx, since, limit+1)
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return ChangePage{}, err
+		}
+		if errors.Is(err, ErrRevisionCompacted) {
+			return ChangePage{}, ErrRevisionCompacted
+		}
+		if errors.Is(err, ErrInvalidValue) {
+			return ChangePage{}, err
+		}
+		return ChangePage{}, ErrInternal
+	}
+
+	page := ChangePage{Changes: changes, Revision: revision}
+	if len(changes) > limit {
+		page.Changes = changes[:limit]
+		page.HasMore = true
+	}
+
+	return page, nil
+}
+
+// CompactChanges удаляет из журнала изменения старше retention.
+func (s *Service) CompactChanges(ctx context.Context, retention time.Duration) (int, error) {
+	if retention < 0 {
+		return 0, NewValidationError("retention", CodeOutOfRange, "must not be negative", ErrInvalidValue)
+	}
+
+	compacted, err := s.storage.CompactChanges(ctx, s.clock.Now().Add(-retention))
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return 0, err
+		}
+		return 0, ErrInternal
+	}
+
+	return compacted, nil
+}
+
+// RunChangeCompactor каждые interval удаляет из журналов всех арендаторов изменения старше retention.
+// Блокируется до отмены ctx.
+func (s *Service) RunChangeCompactor(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			compacted, err := s.forEachTenant(ctx, func(ctx context.Context) (int, error) {
+				return s.CompactChanges(ctx, retention)
+			})
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("[ERROR]: change compactor: %v", err)
+				}
+				continue
+			}
+			if compacted > 0 {
+				log.Printf("[INFO]: change compactor: removed %d changes", compacted)
+			}
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// Clock - источник текущего времени для Service. В тестах подменяется фиксированным.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock возвращает системное время в UTC.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now().UTC()
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+// cursorCodec подписывает курсоры пагинации HMAC-SHA256,
+// чтобы клиент не мог подделать позицию в выборке.
+type cursorCodec struct {
+	key []byte
+}
+
+type cursorPayload struct {
+	AfterID int `json:"a"`
+}
+
+func newCursorCodec() *cursorCodec {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic("domain: cannot generate cursor key: " + err.Error())
+	}
+	return &cursorCodec{key: key}
+}
+
+func (c *cursorCodec) encode(afterID int) string {
+	payload, _ := json.Marshal(cursorPayload{AfterID: afterID})
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + base64.RawURLEncoding.EncodeToString(c.sign(body))
+}
+
+func (c *cursorCodec) decode(cursor string) (int, error) {
+	body, sig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return 0, ErrInvalidValue
+	}
+
+	gotSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotSig, c.sign(body)) {
+		return 0, ErrInvalidValue
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return 0, ErrInvalidValue
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.AfterID < 0 {
+		return 0, ErrInvalidValue
+	}
+
+	return payload.AfterID, nil
+}
+
+func (c *cursorCodec) sign(body string) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventItemCreated EventType = "item.created"
+	EventItemUpdated EventType = "item.updated"
+	EventItemDeleted EventType = "item.deleted"
+)
+
+// Event - факт успешной записи в хранилище. Конкретные типы: ItemCreated, ItemUpdated, ItemDeleted.
+type Event interface {
+	EventType() EventType
+	ItemID() int
+	OccurredAt() time.Time
+}
+
+// EventPublisher доставляет события подписчикам. Publish не возвращает ошибку:
+// сбой подписчика не должен влиять на запрос, который изменил данные.
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+// ItemCreated публикуется после создания элемента, в том числе пакетного,
+// и после восстановления из корзины: для подписчиков элемент появляется заново.
+type ItemCreated struct {
+	Item Item
+	At   time.Time
+}
+
+// ItemUpdated публикуется после изменения имени, атрибутов или тегов.
+type ItemUpdated struct {
+	Item Item
+	At   time.Time
+}
+
+// ItemDeleted публикуется после удаления элемента в корзину.
+type ItemDeleted struct {
+	ID int
+	At time.Time
+}
+
+func (e ItemCreated) EventType() EventType  { return EventItemCreated }
+func (e ItemCreated) ItemID() int           { return e.Item.ID }
+func (e ItemCreated) OccurredAt() time.Time { return e.At }
+
+func (e ItemUpdated) EventType() EventType  { return EventItemUpdated }
+func (e ItemUpdated) ItemID() int           { return e.Item.ID }
+func (e ItemUpdated) OccurredAt() time.Time { return e.At }
+
+func (e ItemDeleted) EventType() EventType  { return EventItemDeleted }
+func (e ItemDeleted) ItemID() int           { return e.ID }
+func (e ItemDeleted) OccurredAt() time.Time { return e.At }
+
+type noopPublisher struct{}
+
+func (noopPublisher) Publish(context.Context, Event) {}
@@ -0,0 +1,152 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"Goworkspace/Project/domain"
+	"Goworkspace/Project/storage"
+)
+
+type recordingPublisher struct {
+	events []domain.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event domain.Event) {
+	p.events = append(p.events, event)
+}
+
+func (p *recordingPublisher) types() []domain.EventType {
+	types := make([]domain.EventType, 0, len(p.events))
+	for _, e := range p.events {
+		types = append(types, e.EventType())
+	}
+	return types
+}
+
+func TestService_Events(t *testing.T) {
+	ctx := context.Background()
+
+	newService := func() (*domain.Service, *recordingPublisher) {
+		pub := &recordingPublisher{}
+		return domain.NewService(storage.NewMemoryStorage(domain.SystemClock{}), domain.SystemClock{}, domain.WithEventPublisher(pub)), pub
+	}
+
+	t.Run("Writes publish typed events", func(t *testing.T) {
+		service, pub := newService()
+
+		item, err := service.Create(ctx, domain.Item{Name: "a"})
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		item.Name = "b"
+		if item, err = service.Update(ctx, item); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if _, err = service.AddTag(ctx, item.ID, 0, "red"); err != nil {
+			t.Fatalf("add tag: %v", err)
+		}
+		if _, err = service.RemoveTag(ctx, item.ID, 0, "red"); err != nil {
+			t.Fatalf("remove tag: %v", err)
+		}
+		if err = service.Delete(ctx, item.ID, 0); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err = service.Restore(ctx, item.ID); err != nil {
+			t.Fatalf("restore: %v", err)
+		}
+
+		want := []domain.EventType{
+			domain.EventItemCreated, domain.EventItemUpdated, domain.EventItemUpdated,
+			domain.EventItemUpdated, domain.EventItemDeleted, domain.EventItemCreated,
+		}
+		got := pub.types()
+		if len(got) != len(want) {
+			t.Fatalf("expected events %v, got %v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("expected events %v, got %v", want, got)
+			}
+			if pub.events[i].ItemID() != item.ID || pub.events[i].OccurredAt().IsZero() {
+				t.Fatalf("event %d: unexpected %+v", i, pub.events[i])
+			}
+		}
+
+		updated, ok := pub.events[1].(domain.ItemUpdated)
+		if !ok || updated.Item.Name != "b" {
+			t.Fatalf("expected ItemUpdated with new name, got %+v", pub.events[1])
+		}
+	})
+
+	t.Run("Repeated AddTag publishes nothing", func(t *testing.T) {
+		service, pub := newService()
+
+		item, _ := service.Create(ctx, domain.Item{Name: "a"})
+		service.AddTag(ctx, item.ID, 0, "red")
+		pub.events = nil
+
+		if _, err := service.AddTag(ctx, item.ID, 0, "red"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(pub.events) != 0 {
+			t.Fatalf("expected no events, got %v", pub.types())
+		}
+	})
+
+	t.Run("Batches publish one event per item", func(t *testing.T) {
+		service, pub := newService()
+
+		created, err := service.CreateBatch(ctx, itemsNamed("a", "b", "c"))
+		if err != nil {
+			t.Fatalf("create batch: %v", err)
+		}
+		if len(pub.events) != 3 {
+			t.Fatalf("expected 3 events, got %v", pub.types())
+		}
+		pub.events = nil
+
+		ids := []int{created[0].ID, created[1].ID, 999}
+		if _, err := service.DeleteBatch(ctx, domain.BulkDelete{IDs: ids, DryRun: true}); err != nil {
+			t.Fatalf("dry run: %v", err)
+		}
+		if len(pub.events) != 0 {
+			t.Fatalf("dry run should publish nothing, got %v", pub.types())
+		}
+
+		if _, err := service.DeleteBatch(ctx, domain.BulkDelete{IDs: ids}); err != nil {
+			t.Fatalf("delete batch: %v", err)
+		}
+		got := pub.types()
+		if len(got) != 2 || got[0] != domain.EventItemDeleted || got[1] != domain.EventItemDeleted {
+			t.Fatalf("expected 2 delete events for existing items, got %v", got)
+		}
+	})
+
+	t.Run("Failed writes publish nothing", func(t *testing.T) {
+		pub := &recordingPublisher{}
+		mock := &MockStorage{forcedError: errors.New("db down")}
+		service := domain.NewService(mock, domain.SystemClock{}, domain.WithEventPublisher(pub))
+
+		service.Create(ctx, domain.Item{Name: "a"})
+		service.Update(ctx, domain.Item{ID: 1, Name: "a", Version: 1})
+		service.Delete(ctx, 1, 0)
+		service.AddTag(ctx, 1, 0, "red")
+
+		if _, err := service.Update(ctx, domain.Item{ID: 1, Version: 1}); err == nil {
+			t.Fatal("expected validation error")
+		}
+		if len(pub.events) != 0 {
+			t.Fatalf("expected no events, got %v", pub.types())
+		}
+	})
+
+	t.Run("Nil publisher keeps the default", func(t *testing.T) {
+		service := domain.NewService(&MockStorage{}, domain.SystemClock{}, domain.WithEventPublisher(nil))
+
+		if _, err := service.Create(ctx, domain.Item{Name: "a"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
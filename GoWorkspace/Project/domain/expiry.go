@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// checkExpiry проверяет срок жизни нового элемента: TTL или ExpiresAt, но не оба сразу,
+// и срок не должен уже истечь.
+func (e *ValidationError) checkExpiry(item Item, now time.Time) {
+	switch {
+	case item.TTL != 0 && !item.ExpiresAt.IsZero():
+		e.Add("ttl", CodeConflict, "must not be set together with expires_at", ErrInvalidValue)
+	case item.TTL < 0:
+		e.Add("ttl", CodeOutOfRange, "must be positive", ErrInvalidValue)
+	case !item.ExpiresAt.IsZero() && !item.ExpiresAt.After(now):
+		e.Add("expires_at", CodeOutOfRange, "must be in the future", ErrInvalidValue)
+	}
+}
+
+// expiresAt возвращает момент истечения нового элемента; ноль - бессрочный.
+func expiresAt(item Item, now time.Time) time.Time {
+	if item.TTL > 0 {
+		return now.Add(item.TTL)
+	}
+	return item.ExpiresAt
+}
+
+// ReapExpired окончательно удаляет истёкшие элементы арендатора из ctx и публикует их удаление.
+// В корзину такие элементы не попадают.
+func (s *Service) ReapExpired(ctx context.Context) (int, error) {
+	items, err := s.storage.ReapExpired(ctx)
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return 0, err
+		}
+		return 0, ErrInternal
+	}
+
+	now := s.clock.Now()
+	for _, item := range items {
+		s.events.Publish(ctx, ItemDeleted{ID: item.ID, At: now})
+		s.audit(ctx, AuditExpire, item.ID, snapshot(item), nil)
+	}
+
+	return len(items), nil
+}
+
+// RunExpiryReaper каждые interval удаляет истёкшие элементы всех арендаторов.
+// Блокируется до отмены ctx.
+func (s *Service) RunExpiryReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reaped, err := s.forEachTenant(ctx, s.ReapExpired)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("[ERROR]: expiry reaper: %v", err)
+				}
+				continue
+			}
+			if reaped > 0 {
+				log.Printf("[INFO]: expiry reaper: removed %d items", reaped)
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"Goworkspace/Project/audit"
+	"Goworkspace/Project/domain"
+	"Goworkspace/Project/storage"
+)
+
+func TestService_Expiry(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	// Сервис и хранилище живут по одним часам
+	newService := func(opts ...domain.ServiceOption) (*domain.Service, *FakeClock) {
+		clock := &FakeClock{now: start}
+		return domain.NewService(storage.NewMemoryStorage(clock), clock, opts...), clock
+	}
+
+	t.Run("Invalid expiry", func(t *testing.T) {
+		service, _ := newService()
+
+		cases := []struct {
+			input domain.Item
+			field string
+			code  string
+		}{
+			{domain.Item{Name: "a", TTL: time.Minute, ExpiresAt: start.Add(time.Hour)}, "ttl", domain.CodeConflict},
+			{domain.Item{Name: "a", TTL: -time.Minute}, "ttl", domain.CodeOutOfRange},
+			{domain.Item{Name: "a", ExpiresAt: start}, "expires_at", domain.CodeOutOfRange},
+		}
+		for _, tc := range cases {
+			_, err := service.Create(ctx, tc.input)
+			var validationErr *domain.ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Violations[0].Field != tc.field || validationErr.Violations[0].Code != tc.code {
+				t.Fatalf("%+v: expected %s %s, got: %v", tc.input, tc.field, tc.code, err)
+			}
+		}
+
+		_, err := service.CreateBatch(ctx, []domain.Item{{Name: "ok"}, {Name: "late", ExpiresAt: start.Add(-time.Second)}})
+		var batchErr *domain.BatchError
+		if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[0].Index != 1 {
+			t.Fatalf("expected batch error at index 1, got: %v", err)
+		}
+	})
+
+	t.Run("Children do not outlive the parent", func(t *testing.T) {
+		service, _ := newService()
+		if _, err := service.Create(ctx, domain.Item{Name: "booking", TTL: time.Hour}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, input := range []domain.Item{{Name: "forever", ParentID: 1}, {Name: "longer", ParentID: 1, TTL: 2 * time.Hour}} {
+			if _, err := service.Create(ctx, input); !errors.Is(err, domain.ErrInvalidValue) {
+				t.Fatalf("%s: expected ErrInvalidValue, got: %v", input.Name, err)
+			}
+		}
+		if _, err := service.Create(ctx, domain.Item{Name: "seat", ParentID: 1, TTL: time.Hour}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Бессрочный элемент нельзя перенести под истекающий
+		plain, _ := service.Create(ctx, domain.Item{Name: "plain"})
+		plain.ParentID = 1
+		if _, err := service.Update(ctx, plain); !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("expected ErrInvalidValue, got: %v", err)
+		}
+	})
+
+	t.Run("Expired items are hidden at once and reaped later", func(t *testing.T) {
+		pub := &recordingPublisher{}
+		sink := audit.NewMemorySink()
+		service, clock := newService(domain.WithEventPublisher(pub), domain.WithAuditSink(sink))
+
+		booking, err := service.Create(ctx, domain.Item{Name: "booking", TTL: time.Minute})
+		if err != nil || !booking.ExpiresAt.Equal(start.Add(time.Minute)) {
+			t.Fatalf("expected expiry in a minute, got: %v, %v", booking.ExpiresAt, err)
+		}
+		if _, err := service.Create(ctx, domain.Item{Name: "seat", ParentID: booking.ID, ExpiresAt: start.Add(30 * time.Second)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := service.Create(ctx, domain.Item{Name: "keep"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Обновление не меняет срок жизни
+		booking.Name = "booking-2"
+		updated, err := service.Update(ctx, booking)
+		if err != nil || !updated.ExpiresAt.Equal(booking.ExpiresAt) {
+			t.Fatalf("expected expiry to survive update, got: %v, %v", updated.ExpiresAt, err)
+		}
+
+		clock.now = start.Add(time.Minute)
+		if _, err := service.Get(ctx, booking.ID); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound for expired item, got: %v", err)
+		}
+		page, err := service.List(ctx, domain.ListQuery{})
+		if err != nil || len(page.Items) != 1 || page.Items[0].Name != "keep" {
+			t.Fatalf("expected only the live item, got: %+v, %v", page.Items, err)
+		}
+
+		pub.events = nil
+		reaped, err := service.ReapExpired(ctx)
+		if err != nil || reaped != 2 {
+			t.Fatalf("expected 2 reaped items, got: %d, %v", reaped, err)
+		}
+		if len(pub.events) != 2 || pub.events[0].EventType() != domain.EventItemDeleted || pub.events[0].ItemID() != 2 {
+			t.Fatalf("expected delete events starting with the child, got %v", pub.types())
+		}
+		records, _ := service.Audit(ctx, domain.AuditQuery{Action: domain.AuditExpire})
+		if len(records.Records) != 2 || records.Records[1].Before == nil || records.Records[1].Before.Name != "booking-2" {
+			t.Fatalf("unexpected audit: %+v", records.Records)
+		}
+
+		if reaped, _ := service.ReapExpired(ctx); reaped != 0 {
+			t.Fatalf("expected nothing left to reap, got %d", reaped)
+		}
+		if trash, _ := service.ListTrash(ctx); len(trash) != 0 {
+			t.Fatalf("expired items must not go to trash, got: %+v", trash)
+		}
+	})
+
+	t.Run("Write removes expired items before reaper", func(t *testing.T) {
+		pub := &recordingPublisher{}
+		service, clock := newService(domain.WithEventPublisher(pub))
+		if _, err := service.Create(ctx, domain.Item{Name: "booking", TTL: time.Minute}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		clock.now = start.Add(time.Hour)
+		// Имя истёкшего элемента свободно сразу
+		if _, err := service.Create(ctx, domain.Item{Name: "booking"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		pub.events = nil
+		if reaped, err := service.ReapExpired(ctx); err != nil || reaped != 1 || pub.events[0].ItemID() != 1 {
+			t.Fatalf("expected the replaced item to be reported, got: %d, %v, %v", reaped, err, pub.types())
+		}
+	})
+
+	t.Run("Reaper iterates tenants", func(t *testing.T) {
+		service, clock := newService(domain.WithAuditSink(audit.NewMemorySink()))
+		for _, tenant := range []string{"acme", "globex"} {
+			if _, err := service.Create(domain.ContextWithTenant(ctx, tenant), domain.Item{Name: "booking", TTL: time.Minute}); err != nil {
+				t.Fatalf("%s: unexpected error: %v", tenant, err)
+			}
+		}
+		clock.now = start.Add(time.Hour)
+
+		reaperCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			service.RunExpiryReaper(reaperCtx, time.Millisecond)
+		}()
+
+		reaped := func(tenant string) bool {
+			page, _ := service.Audit(domain.ContextWithTenant(ctx, tenant), domain.AuditQuery{Action: domain.AuditExpire})
+			return len(page.Records) == 1
+		}
+		deadline := time.Now().Add(time.Second)
+		for !reaped("acme") || !reaped("globex") {
+			if time.Now().After(deadline) {
+				t.Fatal("reaper did not remove expired items of every tenant")
+			}
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+		<-done
+	})
+
+	t.Run("Storage error", func(t *testing.T) {
+		service := domain.NewService(&MockStorage{forcedError: errors.New("boom")}, domain.SystemClock{})
+		if _, err := service.ReapExpired(ctx); !errors.Is(err, domain.ErrInternal) {
+			t.Fatalf("expected ErrInternal, got: %v", err)
+		}
+	})
+}
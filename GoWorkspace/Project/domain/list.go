@@ -0,0 +1,59 @@
+package domain
+
+import "strings"
+
+const (
+	DefaultListLimit = 50  // Размер страницы по умолчанию
+	MaxListLimit     = 500 // Максимальный размер страницы
+	MaxFilterLength  = 256 // Максимальная длина значения фильтра
+)
+
+// ListOptions - параметры выборки для Storage.ListItems.
+// Элементы возвращаются по возрастанию ID, начиная с ID > AfterID.
+type ListOptions struct {
+	AfterID    int
+	Limit      int
+	Name       NameFilter
+	Attributes map[string]string // элемент должен содержать все пары ключ-значение
+	Tags       []string          // элемент должен иметь все теги
+	Parent     int               // только дети этого элемента; 0 - без фильтра
+}
+
+// ListQuery - запрос клиента к Service.List.
+// Cursor - непрозрачная строка из ItemPage.NextCursor предыдущей страницы.
+type ListQuery struct {
+	Cursor     string
+	Limit      int
+	Name       NameFilter
+	Attributes map[string]string
+	Tags       []string
+	Parent     int
+}
+
+// NameFilter - условия на имя элемента, заданные поля объединяются по И.
+// Exact нельзя сочетать с остальными условиями.
+type NameFilter struct {
+	Exact    string // точное совпадение
+	Prefix   string // начало имени, с учётом регистра
+	Contains string // подстрока без учёта регистра
+}
+
+type ItemPage struct {
+	Items      []Item
+	NextCursor string // пустая строка - страниц больше нет
+}
+
+// normalize проверяет фильтр и приводит его к виду, который ожидает Storage.
+// Поля в нарушениях называются так же, как параметры запроса списка.
+func (f NameFilter) normalize(v *ValidationError) NameFilter {
+	if f.Exact != "" && (f.Prefix != "" || f.Contains != "") {
+		v.Add("name", CodeConflict, "cannot be combined with name_prefix or name_contains", ErrInvalidValue)
+	}
+
+	v.checkText("name", f.Exact, MaxFilterLength)
+	v.checkText("name_prefix", f.Prefix, MaxFilterLength)
+	v.checkText("name_contains", f.Contains, MaxFilterLength)
+
+	f.Contains = strings.ToLower(strings.TrimSpace(f.Contains))
+	return f
+}
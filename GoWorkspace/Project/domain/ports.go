@@ -2,10 +2,43 @@ package domain
 
 import (
 	"context"
+	"time"
 )
 
 type Storage interface {
-	CreateItem(ctx context.Context, item Item) (Item, error) // Создать элемент
-	GetItem(ctx context.Context, id int) (Item, error)       // Отправить элемент
-	DeleteItem(ctx context.Context, id int) error            // Удалить элемент
+	CreateItem(ctx context.Context, item Item) (Item, error)                     // Создать элемент
+	GetItem(ctx context.Context, id int) (Item, error)                           // Отправить элемент
+	UpdateItem(ctx context.Context, item Item) (Item, Item, error)               // Изменить элемент; возвращает новое и прежнее состояние
+	DeleteItem(ctx context.Context, id, version int, at time.Time) (Item, error) // Удалить элемент без детей (version 0 - без проверки); возвращает удалённый
+
+	CreateItems(ctx context.Context, items []Item) ([]Item, error)                         // Создать все элементы или ни одного
+	DeleteItems(ctx context.Context, req BulkDelete, at time.Time) ([]DeleteResult, error) // Удалить по списку ID или по фильтру
+	ListItems(ctx context.Context, opts ListOptions) ([]Item, error)                       // Список элементов по возрастанию ID
+
+	ListDeleted(ctx context.Context) ([]DeletedItem, error)              // Содержимое корзины
+	RestoreItem(ctx context.Context, id int, at time.Time) (Item, error) // Вернуть элемент из корзины
+	PurgeDeleted(ctx context.Context, before time.Time) (int, error)     // Окончательно удалить элементы, удалённые до before
+
+	// Теги хранятся в инвертированном индексе, UpdateItem их не меняет. Возвращается новое и прежнее состояние
+	AddTag(ctx context.Context, id, version int, tag string, at time.Time) (Item, Item, error)    // Добавить тег
+	RemoveTag(ctx context.Context, id, version int, tag string, at time.Time) (Item, Item, error) // Снять тег (ErrNotFound, если его нет)
+	ListTags(ctx context.Context) ([]TagCount, error)                                             // Теги с числом элементов, по алфавиту
+
+	// Родитель живого элемента всегда существует: ссылки проверяются при записи
+	DeleteTree(ctx context.Context, id, version int, at time.Time) ([]Item, error) // Удалить элемент с потомками; удалённые - потомки раньше предков
+	ListSubtree(ctx context.Context, id, depth int) ([]Item, error)                // Элемент и потомки до глубины depth (0 - все), предки раньше потомков
+
+	// Истёкший элемент (ExpiresAt) не виден сразу, а удаляется окончательно при следующей записи
+	ReapExpired(ctx context.Context) ([]Item, error) // Истёкшие элементы, удалённые с прошлого вызова
+
+	// Каждая запись получает ревизию хранилища и попадает в журнал изменений
+	ListChanges(ctx context.Context, since int64, limit int) ([]Change, int64, error) // Изменения после since и текущая ревизия
+	CompactChanges(ctx context.Context, before time.Time) (int, error)                // Удалить из журнала изменения до before
+
+	// Данные разделены по арендатору из ctx (TenantFromContext)
+	Tenants(ctx context.Context) ([]string, error) // Арендаторы, у которых есть данные, по алфавиту
+
+	// WithTx выполняет fn атомарно: ошибка или паника в fn откатывает все изменения, сделанные через tx.
+	// tx работает только с арендатором из ctx
+	WithTx(ctx context.Context, fn func(tx Storage) error) error
 }
@@ -3,41 +3,115 @@ package domain
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"time"
 )
 
 type Service struct {
 	storage Storage
+	clock   Clock
+	cursor  *cursorCodec
+	events  EventPublisher
+
+	auditSink AuditSink
+}
+
+type ServiceOption func(*Service)
+
+// WithEventPublisher подключает публикацию событий после успешных записей.
+func WithEventPublisher(p EventPublisher) ServiceOption {
+	return func(s *Service) {
+		if p != nil {
+			s.events = p
+		}
+	}
 }
 
-func NewService(st Storage) *Service {
-	return &Service{storage: st}
+// WithAuditSink включает журнал аудита изменяющих операций.
+func WithAuditSink(sink AuditSink) ServiceOption {
+	return func(s *Service) {
+		if sink != nil {
+			s.auditSink = sink
+		}
+	}
+}
+
+// NewService создаёт сервис. При clock == nil используется SystemClock.
+func NewService(st Storage, clock Clock, opts ...ServiceOption) *Service {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	s := &Service{storage: st, clock: clock, cursor: newCursorCodec(), events: noopPublisher{}, auditSink: noopAuditSink{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 type Item struct {
-	ID   int
-	Name string
+	ID        int
+	Name      string
+	Version   int // растёт при каждой записи; 0 во входных данных - без проверки версии
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ParentID  int // 0 - корневой элемент; родитель всегда существует
+
+	ExpiresAt time.Time     // 0 - бессрочный; истёкший элемент не виден и удаляется ReapExpired
+	TTL       time.Duration // только во входных данных Create: срок жизни от момента создания
+
+	Attributes map[string]string // произвольные метаданные, ограничения - в checkAttributes
+	Tags       []string          // по возрастанию, без повторов; меняются только через AddTag/RemoveTag
+}
+
+// DeletedItem - элемент в корзине после мягкого удаления.
+type DeletedItem struct {
+	Item
+	DeletedAt time.Time
 }
 
-func (s *Service) Create(ctx context.Context, name string) (Item, error) {
-	if name == "" {
-		return Item{}, ErrEmptyName
+// Create сохраняет новый элемент. Из входных данных используются Name, Attributes, ParentID
+// и срок жизни: TTL или ExpiresAt.
+func (s *Service) Create(ctx context.Context, input Item) (Item, error) {
+	now := s.clock.Now()
+
+	var v ValidationError
+	v.checkItem(input)
+	v.checkExpiry(input, now)
+	if err := v.Err(); err != nil {
+		return Item{}, err
 	}
 
-	itemName := Item{Name: name}
-	item, err := s.storage.CreateItem(ctx, itemName)
+	newItem := Item{Name: input.Name, ParentID: input.ParentID, Attributes: cloneAttributes(input.Attributes), CreatedAt: now, UpdatedAt: now, ExpiresAt: expiresAt(input, now)}
+	item, err := s.storage.CreateItem(ctx, newItem)
 
 	if err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 			return Item{}, err
 		}
+		if errors.Is(err, ErrAlreadyExists) || errors.Is(err, ErrInvalidValue) {
+			return Item{}, err // занятое имя или неверный родитель
+		}
 		return Item{}, ErrInternal
 	}
+
+	s.events.Publish(ctx, ItemCreated{Item: item, At: now})
+	s.audit(ctx, AuditCreate, item.ID, nil, snapshot(item))
 	return item, nil
 }
 
+// snapshot - независимая копия элемента для журнала аудита.
+func snapshot(item Item) *Item {
+	item.Attributes = cloneAttributes(item.Attributes)
+	item.Tags = slices.Clone(item.Tags)
+	return &item
+}
+
 func (s *Service) Get(ctx context.Context, id int) (Item, error) {
-	if id < 1 {
-		return Item{}, ErrInvalidValue
+	var v ValidationError
+	v.checkID("id", id)
+	if err := v.Err(); err != nil {
+		return Item{}, err
 	}
 
 	item, err := s.storage.GetItem(ctx, id)
@@ -55,20 +129,191 @@ func (s *Service) Get(ctx context.Context, id int) (Item, error) {
 	return item, nil
 }
 
-func (s *Service) Delete(ctx context.Context, id int) error {
-	if id < 1 {
-		return ErrInvalidValue
+func (s *Service) Update(ctx context.Context, item Item) (Item, error) {
+	var v ValidationError
+	v.checkID("id", item.ID)
+	v.checkVersion(item.Version)
+	v.checkItem(item)
+	if err := v.Err(); err != nil {
+		return Item{}, err
+	}
+
+	item.Attributes = cloneAttributes(item.Attributes)
+	item.UpdatedAt = s.clock.Now()
+	item.TTL = 0 // срок жизни задаётся только при создании, ExpiresAt хранилище сохраняет
+
+	// Прежнее состояние для аудита хранилище отдаёт из той же записи
+	updated, before, err := s.storage.UpdateItem(ctx, item)
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return Item{}, err
+		}
+		if errors.Is(err, ErrNotFound) {
+			return Item{}, ErrNotFound
+		}
+		if errors.Is(err, ErrAlreadyExists) || errors.Is(err, ErrInvalidValue) {
+			return Item{}, err // занятое имя или неверный родитель
+		}
+		if errors.Is(err, ErrVersionConflict) {
+			return Item{}, ErrVersionConflict
+		}
+		return Item{}, ErrInternal
+	}
+
+	s.events.Publish(ctx, ItemUpdated{Item: updated, At: updated.UpdatedAt})
+	s.audit(ctx, AuditUpdate, updated.ID, &before, snapshot(updated))
+	return updated, nil
+}
+
+func (s *Service) List(ctx context.Context, query ListQuery) (ItemPage, error) {
+	var v ValidationError
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	if limit < 0 || limit > MaxListLimit {
+		v.Add("limit", CodeOutOfRange, fmt.Sprintf("must be between 1 and %d", MaxListLimit), ErrInvalidValue)
+	}
+
+	filter := query.Name.normalize(&v)
+	v.checkAttributes("attr", query.Attributes)
+	tags := normalizeTags(&v, query.Tags)
+
+	afterID := 0
+	if query.Cursor != "" {
+		id, err := s.cursor.decode(query.Cursor)
+		if err != nil {
+			v.Add("cursor", CodeInvalid, "malformed or tampered cursor", ErrInvalidValue)
+		}
+		afterID = id
+	}
+
+	if err := v.Err(); err != nil {
+		return ItemPage{}, err
+	}
+
+	// Запрашиваем на один элемент больше, чтобы узнать, есть ли следующая страница
+	items, err := s.storage.ListItems(ctx, ListOptions{AfterID: afterID, Limit: limit + 1, Name: filter, Attributes: query.Attributes, Tags: tags, Parent: query.Parent})
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return ItemPage{}, err
+		}
+		return ItemPage{}, ErrInternal
+	}
+
+	page := ItemPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = s.cursor.encode(page.Items[limit-1].ID)
+	}
+
+	return page, nil
+}
+
+// Modify атомарно читает элемент, применяет к нему change и сохраняет результат
+// с той же проверкой, что и Update. version > 0 требует совпадения текущей версии.
+// Ошибка change возвращается вызывающему без изменений.
+func (s *Service) Modify(ctx context.Context, id, version int, change func(Item) (Item, error)) (Item, error) {
+	var v ValidationError
+	v.checkID("id", id)
+	v.checkVersion(version)
+	if err := v.Err(); err != nil {
+		return Item{}, err
 	}
 
-	if err := s.storage.DeleteItem(ctx, id); err != nil {
+	var (
+		before    Item
+		updated   Item
+		changeErr error
+	)
+
+	err := s.storage.WithTx(ctx, func(tx Storage) error {
+		current, err := tx.GetItem(ctx, id)
+		if err != nil {
+			return err
+		}
+		if version != 0 && version != current.Version {
+			return ErrVersionConflict
+		}
+		before = *snapshot(current) // change может изменить current
+
+		next, err := change(current)
+		if err != nil {
+			changeErr = err
+			return err
+		}
+		var v ValidationError
+		v.checkItem(next)
+		if err := v.Err(); err != nil {
+			changeErr = err
+			return changeErr
+		}
+
+		next.ID = id
+		next.Attributes = cloneAttributes(next.Attributes)
+		next.Version = current.Version
+		next.UpdatedAt = s.clock.Now()
+
+		updated, _, err = tx.UpdateItem(ctx, next)
+		return err
+	})
+
+	if changeErr != nil {
+		return Item{}, changeErr
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return Item{}, err
+		}
+		if errors.Is(err, ErrNotFound) {
+			return Item{}, ErrNotFound
+		}
+		if errors.Is(err, ErrAlreadyExists) || errors.Is(err, ErrInvalidValue) {
+			return Item{}, err // занятое имя или неверный родитель
+		}
+		if errors.Is(err, ErrVersionConflict) {
+			return Item{}, ErrVersionConflict
+		}
+		return Item{}, ErrInternal
+	}
+
+	s.events.Publish(ctx, ItemUpdated{Item: updated, At: updated.UpdatedAt})
+	s.audit(ctx, AuditUpdate, updated.ID, &before, snapshot(updated))
+	return updated, nil
+}
+
+// Delete удаляет элемент без детей; с детьми - ErrHasChildren (см. DeleteTree).
+// version > 0 требует, чтобы текущая версия совпадала.
+func (s *Service) Delete(ctx context.Context, id int, version int) error {
+	var v ValidationError
+	v.checkID("id", id)
+	v.checkVersion(version)
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	now := s.clock.Now()
+	before, err := s.storage.DeleteItem(ctx, id, version, now)
+	if err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 			return err
 		}
 		if errors.Is(err, ErrNotFound) {
 			return ErrNotFound
 		}
+		if errors.Is(err, ErrVersionConflict) {
+			return ErrVersionConflict
+		}
+		if errors.Is(err, ErrHasChildren) {
+			return ErrHasChildren
+		}
 		return ErrInternal
 	}
 
+	s.events.Publish(ctx, ItemDeleted{ID: id, At: now})
+	s.audit(ctx, AuditDelete, id, &before, nil)
 	return nil
 }
@@ -2,15 +2,27 @@ package domain_test
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"Goworkspace/Project/domain"
+	"Goworkspace/Project/storage"
 )
 
 type MockStorage struct {
 	storageCalled bool
 	forcedError   error
+	items         []domain.Item
+	changes       []domain.Change
+	listOptions   domain.ListOptions
+	bulkDelete    domain.BulkDelete
+	purgeBefore   time.Time
+	purgeCalls    atomic.Int32
 }
 
 func (m *MockStorage) CreateItem(ctx context.Context, item domain.Item) (domain.Item, error) {
@@ -21,17 +33,115 @@ func (m *MockStorage) GetItem(ctx context.Context, id int) (domain.Item, error)
 	m.storageCalled = true
 	return domain.Item{ID: id}, m.forcedError
 }
-func (m *MockStorage) DeleteItem(ctx context.Context, id int) error {
+func (m *MockStorage) UpdateItem(ctx context.Context, item domain.Item) (domain.Item, domain.Item, error) {
 	m.storageCalled = true
-	return m.forcedError
+	return item, domain.Item{ID: item.ID, Version: item.Version}, m.forcedError
+}
+func (m *MockStorage) DeleteItem(ctx context.Context, id, version int, at time.Time) (domain.Item, error) {
+	m.storageCalled = true
+	return domain.Item{ID: id, Version: version}, m.forcedError
+}
+func (m *MockStorage) DeleteTree(ctx context.Context, id, version int, at time.Time) ([]domain.Item, error) {
+	m.storageCalled = true
+	return nil, m.forcedError
+}
+func (m *MockStorage) ListSubtree(ctx context.Context, id, depth int) ([]domain.Item, error) {
+	m.storageCalled = true
+	return nil, m.forcedError
+}
+func (m *MockStorage) ReapExpired(ctx context.Context) ([]domain.Item, error) {
+	m.storageCalled = true
+	return m.items, m.forcedError
+}
+func (m *MockStorage) ListItems(ctx context.Context, opts domain.ListOptions) ([]domain.Item, error) {
+	m.storageCalled = true
+	m.listOptions = opts
+	var res []domain.Item
+	for _, item := range m.items {
+		if item.ID > opts.AfterID && len(res) < opts.Limit {
+			res = append(res, item)
+		}
+	}
+	return res, m.forcedError
+}
+
+func (m *MockStorage) CreateItems(ctx context.Context, items []domain.Item) ([]domain.Item, error) {
+	m.storageCalled = true
+	for i := range items {
+		items[i].ID = i + 1
+	}
+	return items, m.forcedError
+}
+func (m *MockStorage) DeleteItems(ctx context.Context, req domain.BulkDelete, at time.Time) ([]domain.DeleteResult, error) {
+	m.storageCalled = true
+	m.bulkDelete = req
+	results := make([]domain.DeleteResult, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		results = append(results, domain.DeleteResult{ID: id, Status: domain.DeleteStatusDeleted})
+	}
+	return results, m.forcedError
+}
+func (m *MockStorage) WithTx(ctx context.Context, fn func(tx domain.Storage) error) error {
+	return fn(m)
+}
+func (m *MockStorage) AddTag(ctx context.Context, id, version int, tag string, at time.Time) (domain.Item, domain.Item, error) {
+	m.storageCalled = true
+	return domain.Item{ID: id, Version: version + 1, Tags: []string{tag}, UpdatedAt: at}, domain.Item{ID: id, Version: version}, m.forcedError
+}
+func (m *MockStorage) RemoveTag(ctx context.Context, id, version int, tag string, at time.Time) (domain.Item, domain.Item, error) {
+	m.storageCalled = true
+	return domain.Item{ID: id, Version: version + 1, UpdatedAt: at}, domain.Item{ID: id, Version: version, Tags: []string{tag}}, m.forcedError
+}
+func (m *MockStorage) ListTags(ctx context.Context) ([]domain.TagCount, error) {
+	m.storageCalled = true
+	return nil, m.forcedError
+}
+func (m *MockStorage) ListChanges(ctx context.Context, since int64, limit int) ([]domain.Change, int64, error) {
+	m.storageCalled = true
+	changes := m.changes[:min(limit, len(m.changes))]
+	return changes, since + int64(len(m.changes)), m.forcedError
+}
+func (m *MockStorage) CompactChanges(ctx context.Context, before time.Time) (int, error) {
+	m.storageCalled = true
+	return 0, m.forcedError
+}
+func (m *MockStorage) Tenants(ctx context.Context) ([]string, error) {
+	return []string{domain.DefaultTenant}, nil
+}
+func (m *MockStorage) ListDeleted(ctx context.Context) ([]domain.DeletedItem, error) {
+	m.storageCalled = true
+	return nil, m.forcedError
+}
+func (m *MockStorage) RestoreItem(ctx context.Context, id int, at time.Time) (domain.Item, error) {
+	m.storageCalled = true
+	return domain.Item{ID: id}, m.forcedError
+}
+func (m *MockStorage) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	m.purgeCalls.Add(1)
+	m.purgeBefore = before
+	return 0, m.forcedError
+}
+
+type FakeClock struct {
+	now time.Time
+}
+
+func (c *FakeClock) Now() time.Time { return c.now }
+
+func itemsNamed(names ...string) []domain.Item {
+	items := make([]domain.Item, 0, len(names))
+	for _, name := range names {
+		items = append(items, domain.Item{Name: name})
+	}
+	return items
 }
 
 func TestService_Create(t *testing.T) {
 	t.Run("Empty name returns ErrEmptyName", func(t *testing.T) {
 		mock := &MockStorage{}
-		svc := domain.NewService(mock)
+		svc := domain.NewService(mock, domain.SystemClock{})
 
-		_, err := svc.Create(context.Background(), "")
+		_, err := svc.Create(context.Background(), domain.Item{Name: ""})
 		if !errors.Is(err, domain.ErrEmptyName) {
 			t.Fatalf("expected ErrEmptyName, got %v", err)
 		}
@@ -42,9 +152,9 @@ func TestService_Create(t *testing.T) {
 
 	t.Run("Success returns item", func(t *testing.T) {
 		mock := &MockStorage{}
-		svc := domain.NewService(mock)
+		svc := domain.NewService(mock, domain.SystemClock{})
 
-		item, err := svc.Create(context.Background(), "Alex")
+		item, err := svc.Create(context.Background(), domain.Item{Name: "Alex"})
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -58,19 +168,30 @@ func TestService_Create(t *testing.T) {
 
 	t.Run("Storage error returns ErrInternal", func(t *testing.T) {
 		mock := &MockStorage{forcedError: errors.New("db fail")}
-		svc := domain.NewService(mock)
+		svc := domain.NewService(mock, domain.SystemClock{})
 
-		_, err := svc.Create(context.Background(), "Alex")
+		_, err := svc.Create(context.Background(), domain.Item{Name: "Alex"})
 		if !errors.Is(err, domain.ErrInternal) {
 			t.Fatalf("expected ErrInternal, got %v", err)
 		}
 	})
 
+	t.Run("Duplicate name returns ErrAlreadyExists", func(t *testing.T) {
+		mock := &MockStorage{forcedError: &domain.AlreadyExistsError{ID: 7}}
+		svc := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := svc.Create(context.Background(), domain.Item{Name: "Alex"})
+		var existsErr *domain.AlreadyExistsError
+		if !errors.As(err, &existsErr) || existsErr.ID != 7 {
+			t.Fatalf("expected AlreadyExistsError with id=7, got %v", err)
+		}
+	})
+
 	t.Run("Context canceled returns context.Canceled", func(t *testing.T) {
 		mock := &MockStorage{forcedError: context.Canceled}
-		svc := domain.NewService(mock)
+		svc := domain.NewService(mock, domain.SystemClock{})
 
-		_, err := svc.Create(context.Background(), "Alex")
+		_, err := svc.Create(context.Background(), domain.Item{Name: "Alex"})
 		if !errors.Is(err, context.Canceled) {
 			t.Fatalf("expected context.Canceled, got %v", err)
 		}
@@ -78,9 +199,9 @@ func TestService_Create(t *testing.T) {
 
 	t.Run("Context timeout returns context.DeadlineExceeded", func(t *testing.T) {
 		mock := &MockStorage{forcedError: context.DeadlineExceeded}
-		svc := domain.NewService(mock)
+		svc := domain.NewService(mock, domain.SystemClock{})
 
-		_, err := svc.Create(context.Background(), "Alex")
+		_, err := svc.Create(context.Background(), domain.Item{Name: "Alex"})
 		if !errors.Is(err, context.DeadlineExceeded) {
 			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
 		}
@@ -90,7 +211,7 @@ func TestService_Create(t *testing.T) {
 func TestService_Get(t *testing.T) {
 	t.Run("Zero ID returns ErrInvalidValue", func(t *testing.T) {
 		mock := &MockStorage{}
-		service := domain.NewService(mock)
+		service := domain.NewService(mock, domain.SystemClock{})
 
 		_, err := service.Get(context.Background(), 0)
 		if !errors.Is(err, domain.ErrInvalidValue) {
@@ -100,7 +221,7 @@ func TestService_Get(t *testing.T) {
 
 	t.Run("Negative ID returns ErrInvalidValue", func(t *testing.T) {
 		mock := &MockStorage{}
-		service := domain.NewService(mock)
+		service := domain.NewService(mock, domain.SystemClock{})
 
 		_, err := service.Get(context.Background(), -1)
 		if !errors.Is(err, domain.ErrInvalidValue) {
@@ -110,7 +231,7 @@ func TestService_Get(t *testing.T) {
 
 	t.Run("Success returns item", func(t *testing.T) {
 		mock := &MockStorage{}
-		service := domain.NewService(mock)
+		service := domain.NewService(mock, domain.SystemClock{})
 
 		item, err := service.Get(context.Background(), 1)
 		if err != nil {
@@ -123,7 +244,7 @@ func TestService_Get(t *testing.T) {
 
 	t.Run("Storage error returns ErrNotFound", func(t *testing.T) {
 		mock := &MockStorage{forcedError: domain.ErrNotFound}
-		service := domain.NewService(mock)
+		service := domain.NewService(mock, domain.SystemClock{})
 
 		_, err := service.Get(context.Background(), 1)
 		if !errors.Is(err, domain.ErrNotFound) {
@@ -133,7 +254,7 @@ func TestService_Get(t *testing.T) {
 
 	t.Run("Storage error returns ErrInternal", func(t *testing.T) {
 		mock := &MockStorage{forcedError: errors.New("DB error")}
-		service := domain.NewService(mock)
+		service := domain.NewService(mock, domain.SystemClock{})
 
 		_, err := service.Get(context.Background(), 1)
 		if !errors.Is(err, domain.ErrInternal) {
@@ -143,7 +264,7 @@ func TestService_Get(t *testing.T) {
 
 	t.Run("Context canceled returns context.Canceled", func(t *testing.T) {
 		mock := &MockStorage{forcedError: context.Canceled}
-		svc := domain.NewService(mock)
+		svc := domain.NewService(mock, domain.SystemClock{})
 
 		_, err := svc.Get(context.Background(), 1)
 		if !errors.Is(err, context.Canceled) {
@@ -153,7 +274,7 @@ func TestService_Get(t *testing.T) {
 
 	t.Run("Context timeout returns context.DeadlineExceeded", func(t *testing.T) {
 		mock := &MockStorage{forcedError: context.DeadlineExceeded}
-		svc := domain.NewService(mock)
+		svc := domain.NewService(mock, domain.SystemClock{})
 
 		_, err := svc.Get(context.Background(), 1)
 		if !errors.Is(err, context.DeadlineExceeded) {
@@ -162,12 +283,203 @@ func TestService_Get(t *testing.T) {
 	})
 }
 
+func TestService_Update(t *testing.T) {
+	t.Run("Zero ID returns ErrInvalidValue", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.Update(context.Background(), domain.Item{ID: 0, Name: "Alex"})
+		if !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("expected ErrInvalidValue, got: %v", err)
+		}
+		if mock.storageCalled {
+			t.Fatal("storage should not be called for invalid id")
+		}
+	})
+
+	t.Run("Empty name returns ErrEmptyName", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.Update(context.Background(), domain.Item{ID: 1})
+		if !errors.Is(err, domain.ErrEmptyName) {
+			t.Fatalf("expected ErrEmptyName, got: %v", err)
+		}
+		if mock.storageCalled {
+			t.Fatal("storage should not be called for empty name")
+		}
+	})
+
+	t.Run("Success returns item", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		item, err := service.Update(context.Background(), domain.Item{ID: 1, Name: "Alice"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if item.ID != 1 || item.Name != "Alice" {
+			t.Fatalf("expected item id:1 name:Alice, got: %+v", item)
+		}
+	})
+
+	t.Run("Storage error returns ErrNotFound", func(t *testing.T) {
+		mock := &MockStorage{forcedError: domain.ErrNotFound}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.Update(context.Background(), domain.Item{ID: 1, Name: "Alice"})
+		if !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("Duplicate name returns ErrAlreadyExists", func(t *testing.T) {
+		mock := &MockStorage{forcedError: &domain.AlreadyExistsError{ID: 2}}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.Update(context.Background(), domain.Item{ID: 1, Name: "Alice"})
+		if !errors.Is(err, domain.ErrAlreadyExists) {
+			t.Fatalf("expected ErrAlreadyExists, got: %v", err)
+		}
+	})
+
+	t.Run("Storage error returns ErrInternal", func(t *testing.T) {
+		mock := &MockStorage{forcedError: errors.New("DB error")}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.Update(context.Background(), domain.Item{ID: 1, Name: "Alice"})
+		if !errors.Is(err, domain.ErrInternal) {
+			t.Fatalf("expected ErrInternal, got: %v", err)
+		}
+	})
+
+	t.Run("Stale version returns ErrVersionConflict", func(t *testing.T) {
+		mock := &MockStorage{forcedError: domain.ErrVersionConflict}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.Update(context.Background(), domain.Item{ID: 1, Name: "Alice", Version: 3})
+		if !errors.Is(err, domain.ErrVersionConflict) {
+			t.Fatalf("expected ErrVersionConflict, got: %v", err)
+		}
+	})
+
+	t.Run("Negative version returns ErrInvalidValue", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.Update(context.Background(), domain.Item{ID: 1, Name: "Alice", Version: -1})
+		if !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("expected ErrInvalidValue, got: %v", err)
+		}
+	})
+
+	t.Run("Context canceled returns context.Canceled", func(t *testing.T) {
+		mock := &MockStorage{forcedError: context.Canceled}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.Update(context.Background(), domain.Item{ID: 1, Name: "Alice"})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got: %v", err)
+		}
+	})
+}
+
+func TestService_Modify(t *testing.T) {
+	rename := func(name string) func(domain.Item) (domain.Item, error) {
+		return func(item domain.Item) (domain.Item, error) {
+			item.Name = name
+			return item, nil
+		}
+	}
+
+	t.Run("Zero ID returns ErrInvalidValue", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.Modify(context.Background(), 0, 0, rename("Alice"))
+		if !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("expected ErrInvalidValue, got: %v", err)
+		}
+		if mock.storageCalled {
+			t.Fatal("storage should not be called for invalid id")
+		}
+	})
+
+	t.Run("Success applies change", func(t *testing.T) {
+		clock := &FakeClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+		service := domain.NewService(&MockStorage{}, clock)
+
+		item, err := service.Modify(context.Background(), 1, 0, rename("Alice"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if item.ID != 1 || item.Name != "Alice" || !item.UpdatedAt.Equal(clock.now) {
+			t.Fatalf("unexpected item: %+v", item)
+		}
+	})
+
+	t.Run("Stale version returns ErrVersionConflict", func(t *testing.T) {
+		called := false
+		service := domain.NewService(&MockStorage{}, domain.SystemClock{})
+
+		_, err := service.Modify(context.Background(), 1, 3, func(item domain.Item) (domain.Item, error) {
+			called = true
+			return item, nil
+		})
+		if !errors.Is(err, domain.ErrVersionConflict) {
+			t.Fatalf("expected ErrVersionConflict, got: %v", err)
+		}
+		if called {
+			t.Fatal("change should not be called on version conflict")
+		}
+	})
+
+	t.Run("Change error is returned as is", func(t *testing.T) {
+		errChange := errors.New("bad patch")
+		service := domain.NewService(&MockStorage{}, domain.SystemClock{})
+
+		_, err := service.Modify(context.Background(), 1, 0, func(domain.Item) (domain.Item, error) {
+			return domain.Item{}, errChange
+		})
+		if !errors.Is(err, errChange) {
+			t.Fatalf("expected change error, got: %v", err)
+		}
+	})
+
+	t.Run("Empty name returns ErrEmptyName", func(t *testing.T) {
+		service := domain.NewService(&MockStorage{}, domain.SystemClock{})
+
+		_, err := service.Modify(context.Background(), 1, 0, rename(""))
+		if !errors.Is(err, domain.ErrEmptyName) {
+			t.Fatalf("expected ErrEmptyName, got: %v", err)
+		}
+	})
+
+	t.Run("Storage error returns ErrNotFound", func(t *testing.T) {
+		service := domain.NewService(&MockStorage{forcedError: domain.ErrNotFound}, domain.SystemClock{})
+
+		_, err := service.Modify(context.Background(), 1, 0, rename("Alice"))
+		if !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("Storage error returns ErrInternal", func(t *testing.T) {
+		service := domain.NewService(&MockStorage{forcedError: errors.New("db down")}, domain.SystemClock{})
+
+		_, err := service.Modify(context.Background(), 1, 0, rename("Alice"))
+		if !errors.Is(err, domain.ErrInternal) {
+			t.Fatalf("expected ErrInternal, got: %v", err)
+		}
+	})
+}
+
 func TestService_Delete(t *testing.T) {
 	t.Run("Zero ID returns ErrInvalidValue", func(t *testing.T) {
 		mock := &MockStorage{}
-		service := domain.NewService(mock)
+		service := domain.NewService(mock, domain.SystemClock{})
 
-		err := service.Delete(context.Background(), 0)
+		err := service.Delete(context.Background(), 0, 0)
 		if !errors.Is(err, domain.ErrInvalidValue) {
 			t.Fatalf("expected ErrInvalidValue, got: %v", err)
 		}
@@ -175,9 +487,9 @@ func TestService_Delete(t *testing.T) {
 
 	t.Run("Negative ID returns ErrInvalidValue", func(t *testing.T) {
 		mock := &MockStorage{}
-		service := domain.NewService(mock)
+		service := domain.NewService(mock, domain.SystemClock{})
 
-		err := service.Delete(context.Background(), -1)
+		err := service.Delete(context.Background(), -1, 0)
 		if !errors.Is(err, domain.ErrInvalidValue) {
 			t.Fatalf("expected ErrInvalidValue, got: %v", err)
 		}
@@ -185,19 +497,29 @@ func TestService_Delete(t *testing.T) {
 
 	t.Run("Storage error returns ErrNotFound", func(t *testing.T) {
 		mock := &MockStorage{forcedError: domain.ErrNotFound}
-		service := domain.NewService(mock)
+		service := domain.NewService(mock, domain.SystemClock{})
 
-		err := service.Delete(context.Background(), 1)
+		err := service.Delete(context.Background(), 1, 0)
 		if !errors.Is(err, domain.ErrNotFound) {
 			t.Fatalf("expected ErrNotFound, got: %v", err)
 		}
 	})
 
+	t.Run("Stale version returns ErrVersionConflict", func(t *testing.T) {
+		mock := &MockStorage{forcedError: domain.ErrVersionConflict}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		err := service.Delete(context.Background(), 1, 2)
+		if !errors.Is(err, domain.ErrVersionConflict) {
+			t.Fatalf("expected ErrVersionConflict, got: %v", err)
+		}
+	})
+
 	t.Run("Storage error returns ErrInternal", func(t *testing.T) {
 		mock := &MockStorage{forcedError: errors.New("DB error")}
-		service := domain.NewService(mock)
+		service := domain.NewService(mock, domain.SystemClock{})
 
-		err := service.Delete(context.Background(), 1)
+		err := service.Delete(context.Background(), 1, 0)
 		if !errors.Is(err, domain.ErrInternal) {
 			t.Fatalf("expected ErrInternal, got: %v", err)
 		}
@@ -205,9 +527,9 @@ func TestService_Delete(t *testing.T) {
 
 	t.Run("Context canceled returns context.Canceled", func(t *testing.T) {
 		mock := &MockStorage{forcedError: context.Canceled}
-		service := domain.NewService(mock)
+		service := domain.NewService(mock, domain.SystemClock{})
 
-		err := service.Delete(context.Background(), 1)
+		err := service.Delete(context.Background(), 1, 0)
 		if !errors.Is(err, context.Canceled) {
 			t.Fatalf("expected context.Canceled, got: %v", err)
 		}
@@ -215,11 +537,787 @@ func TestService_Delete(t *testing.T) {
 
 	t.Run("Context timeout returns context.DeadlineExceeded", func(t *testing.T) {
 		mock := &MockStorage{forcedError: context.DeadlineExceeded}
-		service := domain.NewService(mock)
+		service := domain.NewService(mock, domain.SystemClock{})
 
-		err := service.Delete(context.Background(), 1)
+		err := service.Delete(context.Background(), 1, 0)
 		if !errors.Is(err, context.DeadlineExceeded) {
 			t.Fatalf("expected context.DeadlineExceeded, got: %v", err)
 		}
 	})
 }
+
+func TestService_List(t *testing.T) {
+	items := []domain.Item{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}}
+
+	t.Run("Default limit is applied", func(t *testing.T) {
+		mock := &MockStorage{items: items}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		page, err := service.List(context.Background(), domain.ListQuery{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(page.Items) != 3 || page.NextCursor != "" {
+			t.Fatalf("expected 3 items without cursor, got: %+v", page)
+		}
+		if mock.listOptions.Limit != domain.DefaultListLimit+1 {
+			t.Fatalf("expected storage limit %d, got: %d", domain.DefaultListLimit+1, mock.listOptions.Limit)
+		}
+	})
+
+	t.Run("Cursor continues from last item", func(t *testing.T) {
+		mock := &MockStorage{items: items}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		page, err := service.List(context.Background(), domain.ListQuery{Limit: 2})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(page.Items) != 2 || page.NextCursor == "" {
+			t.Fatalf("expected 2 items with cursor, got: %+v", page)
+		}
+
+		page, err = service.List(context.Background(), domain.ListQuery{Cursor: page.NextCursor, Limit: 2})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mock.listOptions.AfterID != 2 {
+			t.Fatalf("expected AfterID=2, got: %d", mock.listOptions.AfterID)
+		}
+		if len(page.Items) != 1 || page.Items[0].ID != 3 || page.NextCursor != "" {
+			t.Fatalf("expected last item without cursor, got: %+v", page)
+		}
+	})
+
+	t.Run("Invalid limit returns ErrInvalidValue", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		for _, limit := range []int{-1, domain.MaxListLimit + 1} {
+			_, err := service.List(context.Background(), domain.ListQuery{Limit: limit})
+			if !errors.Is(err, domain.ErrInvalidValue) {
+				t.Fatalf("limit %d: expected ErrInvalidValue, got: %v", limit, err)
+			}
+		}
+		if mock.storageCalled {
+			t.Fatal("storage should not be called for invalid limit")
+		}
+	})
+
+	t.Run("Tampered cursor returns ErrInvalidValue", func(t *testing.T) {
+		mock := &MockStorage{items: items}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		page, _ := service.List(context.Background(), domain.ListQuery{Limit: 1})
+		body, sig, _ := strings.Cut(page.NextCursor, ".")
+		forged := base64.RawURLEncoding.EncodeToString([]byte(`{"a":0}`)) + "." + sig
+
+		for _, cursor := range []string{"garbage", body + ".", forged} {
+			_, err := service.List(context.Background(), domain.ListQuery{Cursor: cursor})
+			if !errors.Is(err, domain.ErrInvalidValue) {
+				t.Fatalf("cursor %q: expected ErrInvalidValue, got: %v", cursor, err)
+			}
+		}
+	})
+
+	t.Run("Cursor from another service is rejected", func(t *testing.T) {
+		page, _ := domain.NewService(&MockStorage{items: items}, domain.SystemClock{}).List(context.Background(), domain.ListQuery{Limit: 1})
+
+		_, err := domain.NewService(&MockStorage{items: items}, domain.SystemClock{}).List(context.Background(), domain.ListQuery{Cursor: page.NextCursor})
+		if !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("expected ErrInvalidValue, got: %v", err)
+		}
+	})
+
+	t.Run("Storage error returns ErrInternal", func(t *testing.T) {
+		mock := &MockStorage{forcedError: errors.New("DB error")}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.List(context.Background(), domain.ListQuery{})
+		if !errors.Is(err, domain.ErrInternal) {
+			t.Fatalf("expected ErrInternal, got: %v", err)
+		}
+	})
+
+	t.Run("Context canceled returns context.Canceled", func(t *testing.T) {
+		mock := &MockStorage{forcedError: context.Canceled}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.List(context.Background(), domain.ListQuery{})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got: %v", err)
+		}
+	})
+}
+
+func TestService_ListFilter(t *testing.T) {
+	t.Run("Contains filter is normalized", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.List(context.Background(), domain.ListQuery{Name: domain.NameFilter{Prefix: "Al", Contains: "  LeX "}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mock.listOptions.Name.Contains != "lex" || mock.listOptions.Name.Prefix != "Al" {
+			t.Fatalf("unexpected filter passed to storage: %+v", mock.listOptions.Name)
+		}
+	})
+
+	t.Run("Malformed filter returns ErrInvalidValue", func(t *testing.T) {
+		filters := []domain.NameFilter{
+			{Exact: "Alex", Prefix: "Al"},
+			{Exact: "Alex", Contains: "le"},
+			{Prefix: strings.Repeat("a", domain.MaxFilterLength+1)},
+			{Contains: "\xff\xfe"},
+			{Exact: "Al\x00ex"},
+		}
+
+		for _, filter := range filters {
+			mock := &MockStorage{}
+			service := domain.NewService(mock, domain.SystemClock{})
+
+			_, err := service.List(context.Background(), domain.ListQuery{Name: filter})
+			if !errors.Is(err, domain.ErrInvalidValue) {
+				t.Fatalf("filter %+v: expected ErrInvalidValue, got: %v", filter, err)
+			}
+			if mock.storageCalled {
+				t.Fatalf("filter %+v: storage should not be called", filter)
+			}
+		}
+	})
+}
+
+func TestService_Attributes(t *testing.T) {
+	t.Run("Invalid attributes return ErrInvalidValue", func(t *testing.T) {
+		tooMany := make(map[string]string, domain.MaxAttributes+1)
+		for i := 0; i <= domain.MaxAttributes; i++ {
+			tooMany[fmt.Sprintf("k%d", i)] = "v"
+		}
+
+		cases := []map[string]string{
+			tooMany,
+			{"": "v"},
+			{"Owner": "v"},
+			{"1st": "v"},
+			{"own er": "v"},
+			{strings.Repeat("k", domain.MaxAttributeKeyLength+1): "v"},
+			{"owner": strings.Repeat("v", domain.MaxAttributeValueLength+1)},
+			{"owner": "\xff\xfe"},
+			{"owner": "a\nb"},
+		}
+
+		for _, attrs := range cases {
+			mock := &MockStorage{}
+			service := domain.NewService(mock, domain.SystemClock{})
+
+			_, err := service.Create(context.Background(), domain.Item{Name: "Alex", Attributes: attrs})
+			if !errors.Is(err, domain.ErrInvalidValue) {
+				t.Fatalf("attributes %q: expected ErrInvalidValue, got: %v", attrs, err)
+			}
+			if _, err := service.Update(context.Background(), domain.Item{ID: 1, Name: "Alex", Attributes: attrs}); !errors.Is(err, domain.ErrInvalidValue) {
+				t.Fatalf("attributes %q: expected ErrInvalidValue on update, got: %v", attrs, err)
+			}
+			if mock.storageCalled {
+				t.Fatalf("attributes %q: storage should not be called", attrs)
+			}
+		}
+	})
+
+	t.Run("Valid keys are accepted", func(t *testing.T) {
+		service := domain.NewService(&MockStorage{}, domain.SystemClock{})
+
+		attrs := map[string]string{"owner": "", "env.name": "prod", "team_1-a": "Команда"}
+		item, err := service.Create(context.Background(), domain.Item{Name: "Alex", Attributes: attrs})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(item.Attributes) != 3 {
+			t.Fatalf("unexpected attributes: %+v", item.Attributes)
+		}
+	})
+
+	t.Run("Caller map is copied", func(t *testing.T) {
+		service := domain.NewService(&MockStorage{}, domain.SystemClock{})
+
+		attrs := map[string]string{"owner": "alice"}
+		item, _ := service.Create(context.Background(), domain.Item{Name: "Alex", Attributes: attrs})
+		attrs["owner"] = "mallory"
+
+		if item.Attributes["owner"] != "alice" {
+			t.Fatalf("item must not share the caller map, got: %+v", item.Attributes)
+		}
+	})
+
+	t.Run("Invalid attribute filter returns ErrInvalidValue", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.List(context.Background(), domain.ListQuery{Attributes: map[string]string{"Bad": "x"}})
+		if !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("expected ErrInvalidValue, got: %v", err)
+		}
+		if mock.storageCalled {
+			t.Fatal("storage should not be called for invalid filter")
+		}
+	})
+
+	t.Run("Attribute filter is passed to storage", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.List(context.Background(), domain.ListQuery{Attributes: map[string]string{"env": "prod"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mock.listOptions.Attributes["env"] != "prod" {
+			t.Fatalf("unexpected options passed to storage: %+v", mock.listOptions)
+		}
+	})
+}
+
+func TestService_Tags(t *testing.T) {
+	t.Run("Invalid tag returns ErrInvalidValue", func(t *testing.T) {
+		for _, tag := range []string{"", "Red", "1st", "a b", strings.Repeat("t", domain.MaxTagLength+1)} {
+			mock := &MockStorage{}
+			service := domain.NewService(mock, domain.SystemClock{})
+
+			if _, err := service.AddTag(context.Background(), 1, 0, tag); !errors.Is(err, domain.ErrInvalidValue) {
+				t.Fatalf("tag %q: expected ErrInvalidValue, got: %v", tag, err)
+			}
+			if _, err := service.RemoveTag(context.Background(), 1, 0, tag); !errors.Is(err, domain.ErrInvalidValue) {
+				t.Fatalf("tag %q: expected ErrInvalidValue on remove, got: %v", tag, err)
+			}
+			if mock.storageCalled {
+				t.Fatalf("tag %q: storage should not be called", tag)
+			}
+		}
+	})
+
+	t.Run("Zero ID returns ErrInvalidValue", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.AddTag(context.Background(), 0, 0, "red")
+		if !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("expected ErrInvalidValue, got: %v", err)
+		}
+		if mock.storageCalled {
+			t.Fatal("storage should not be called for invalid id")
+		}
+	})
+
+	t.Run("AddTag passes clock time to storage", func(t *testing.T) {
+		clock := &FakeClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+		service := domain.NewService(&MockStorage{}, clock)
+
+		item, err := service.AddTag(context.Background(), 1, 0, "red")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !item.UpdatedAt.Equal(clock.now) || len(item.Tags) != 1 {
+			t.Fatalf("unexpected item: %+v", item)
+		}
+	})
+
+	t.Run("Storage errors are mapped", func(t *testing.T) {
+		cases := map[error]error{
+			domain.ErrNotFound:                              domain.ErrNotFound,
+			domain.ErrVersionConflict:                       domain.ErrVersionConflict,
+			fmt.Errorf("%w: limit", domain.ErrInvalidValue): domain.ErrInvalidValue,
+			errors.New("db down"):                           domain.ErrInternal,
+			context.Canceled:                                context.Canceled,
+		}
+
+		for forced, expected := range cases {
+			service := domain.NewService(&MockStorage{forcedError: forced}, domain.SystemClock{})
+
+			if _, err := service.RemoveTag(context.Background(), 1, 0, "red"); !errors.Is(err, expected) {
+				t.Fatalf("storage error %v: expected %v, got: %v", forced, expected, err)
+			}
+		}
+	})
+
+	t.Run("Tag filter is validated and deduplicated", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		if _, err := service.List(context.Background(), domain.ListQuery{Tags: []string{"b", "a", "b"}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fmt.Sprint(mock.listOptions.Tags) != "[a b]" {
+			t.Fatalf("unexpected tags passed to storage: %v", mock.listOptions.Tags)
+		}
+
+		mock = &MockStorage{}
+		service = domain.NewService(mock, domain.SystemClock{})
+
+		if _, err := service.List(context.Background(), domain.ListQuery{Tags: []string{"Bad"}}); !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("expected ErrInvalidValue, got: %v", err)
+		}
+		if mock.storageCalled {
+			t.Fatal("storage should not be called for invalid filter")
+		}
+	})
+}
+
+func TestService_ValidationError(t *testing.T) {
+	violations := func(t *testing.T, err error) []string {
+		t.Helper()
+		var validationErr *domain.ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("expected ValidationError, got: %v", err)
+		}
+		res := make([]string, 0, len(validationErr.Violations))
+		for _, violation := range validationErr.Violations {
+			res = append(res, violation.Field+":"+violation.Code)
+		}
+		return res
+	}
+
+	t.Run("Update reports every violation", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.Update(context.Background(), domain.Item{ID: 0, Version: -1, Attributes: map[string]string{"Bad": "x", "ok": "a\x00"}})
+
+		expected := "[id:out_of_range version:out_of_range name:required attributes.Bad:invalid attributes.ok:invalid]"
+		if got := fmt.Sprint(violations(t, err)); got != expected {
+			t.Fatalf("expected %s, got: %s", expected, got)
+		}
+		if !errors.Is(err, domain.ErrEmptyName) || !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("violations must unwrap to sentinels, got: %v", err)
+		}
+		if mock.storageCalled {
+			t.Fatal("storage should not be called for invalid input")
+		}
+	})
+
+	t.Run("List reports every violation", func(t *testing.T) {
+		service := domain.NewService(&MockStorage{}, domain.SystemClock{})
+
+		_, err := service.List(context.Background(), domain.ListQuery{
+			Limit:  domain.MaxListLimit + 1,
+			Cursor: "garbage",
+			Name:   domain.NameFilter{Exact: "a", Prefix: strings.Repeat("p", domain.MaxFilterLength+1)},
+			Tags:   []string{"Bad"},
+		})
+
+		expected := "[limit:out_of_range name:conflict name_prefix:too_long tag:invalid cursor:invalid]"
+		if got := fmt.Sprint(violations(t, err)); got != expected {
+			t.Fatalf("expected %s, got: %s", expected, got)
+		}
+	})
+
+	t.Run("Batch delete prefixes filter fields", func(t *testing.T) {
+		service := domain.NewService(&MockStorage{}, domain.SystemClock{})
+
+		_, err := service.DeleteBatch(context.Background(), domain.BulkDelete{Name: domain.NameFilter{Exact: "a", Contains: "b"}})
+
+		expected := "[filter.name:conflict]"
+		if got := fmt.Sprint(violations(t, err)); got != expected {
+			t.Fatalf("expected %s, got: %s", expected, got)
+		}
+	})
+
+	t.Run("Valid input has no error", func(t *testing.T) {
+		var v domain.ValidationError
+		if v.Err() != nil {
+			t.Fatal("empty ValidationError must convert to nil error")
+		}
+	})
+}
+
+func TestService_Restore(t *testing.T) {
+	t.Run("Zero ID returns ErrInvalidValue", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.Restore(context.Background(), 0)
+		if !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("expected ErrInvalidValue, got: %v", err)
+		}
+	})
+
+	t.Run("Success returns item", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		item, err := service.Restore(context.Background(), 3)
+		if err != nil || item.ID != 3 {
+			t.Fatalf("expected item id=3, got: %+v, %v", item, err)
+		}
+	})
+
+	t.Run("Storage error returns ErrNotFound", func(t *testing.T) {
+		mock := &MockStorage{forcedError: domain.ErrNotFound}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.Restore(context.Background(), 1)
+		if !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("Taken name returns ErrAlreadyExists", func(t *testing.T) {
+		mock := &MockStorage{forcedError: &domain.AlreadyExistsError{ID: 5}}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.Restore(context.Background(), 1)
+		if !errors.Is(err, domain.ErrAlreadyExists) {
+			t.Fatalf("expected ErrAlreadyExists, got: %v", err)
+		}
+	})
+}
+
+func TestService_PurgeTrash(t *testing.T) {
+	t.Run("Cutoff is now minus retention", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		before := time.Now()
+		if _, err := service.PurgeTrash(context.Background(), time.Hour); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := before.Add(-time.Hour)
+		if mock.purgeBefore.Before(expected) || mock.purgeBefore.After(time.Now().Add(-time.Hour)) {
+			t.Fatalf("unexpected cutoff %v, expected about %v", mock.purgeBefore, expected)
+		}
+	})
+
+	t.Run("Negative retention returns ErrInvalidValue", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.PurgeTrash(context.Background(), -time.Second)
+		if !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("expected ErrInvalidValue, got: %v", err)
+		}
+	})
+
+	t.Run("Sweeper runs periodically and stops on cancel", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			service.RunTrashSweeper(ctx, time.Millisecond, time.Hour)
+			close(done)
+		}()
+
+		deadline := time.After(time.Second)
+		for mock.purgeCalls.Load() < 2 {
+			select {
+			case <-deadline:
+				t.Fatal("sweeper did not run")
+			case <-time.After(time.Millisecond):
+			}
+		}
+
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("sweeper did not stop after cancel")
+		}
+	})
+}
+
+func TestService_Timestamps(t *testing.T) {
+	clock := &FakeClock{now: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+
+	t.Run("Create sets CreatedAt and UpdatedAt from clock", func(t *testing.T) {
+		service := domain.NewService(&MockStorage{}, clock)
+
+		item, err := service.Create(context.Background(), domain.Item{Name: "Alex"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !item.CreatedAt.Equal(clock.now) || !item.UpdatedAt.Equal(clock.now) {
+			t.Fatalf("expected timestamps %v, got: %+v", clock.now, item)
+		}
+	})
+
+	t.Run("Update sets UpdatedAt from clock", func(t *testing.T) {
+		service := domain.NewService(&MockStorage{}, clock)
+
+		item, err := service.Update(context.Background(), domain.Item{ID: 1, Name: "Alice"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !item.UpdatedAt.Equal(clock.now) {
+			t.Fatalf("expected UpdatedAt %v, got: %v", clock.now, item.UpdatedAt)
+		}
+	})
+
+	t.Run("Purge cutoff uses clock", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, clock)
+
+		service.PurgeTrash(context.Background(), time.Hour)
+		if !mock.purgeBefore.Equal(clock.now.Add(-time.Hour)) {
+			t.Fatalf("expected cutoff %v, got: %v", clock.now.Add(-time.Hour), mock.purgeBefore)
+		}
+	})
+
+	t.Run("Deletion time uses clock", func(t *testing.T) {
+		clock := &FakeClock{now: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+		service := domain.NewService(storage.NewMemoryStorage(clock), clock)
+		ctx := context.Background()
+
+		service.Create(ctx, domain.Item{Name: "Alex"})
+		if err := service.Delete(ctx, 1, 0); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		trash, _ := service.ListTrash(ctx)
+		if len(trash) != 1 || !trash[0].DeletedAt.Equal(clock.now) {
+			t.Fatalf("expected DeletedAt %v, got: %+v", clock.now, trash)
+		}
+		page, _ := service.Changes(ctx, 0, 0)
+		if len(page.Changes) != 2 || !page.Changes[1].At.Equal(clock.now) {
+			t.Fatalf("expected deletion logged at %v, got: %+v", clock.now, page.Changes)
+		}
+
+		clock.now = clock.now.Add(48 * time.Hour)
+		if purged, err := service.PurgeTrash(ctx, 24*time.Hour); err != nil || purged != 1 {
+			t.Fatalf("expected 1 purged item, got: %d, %v", purged, err)
+		}
+	})
+
+	t.Run("Nil clock falls back to system clock", func(t *testing.T) {
+		service := domain.NewService(&MockStorage{}, nil)
+
+		item, _ := service.Create(context.Background(), domain.Item{Name: "Alex"})
+		if time.Since(item.CreatedAt) > time.Minute {
+			t.Fatalf("expected current time, got: %v", item.CreatedAt)
+		}
+	})
+}
+
+func TestService_CreateBatch(t *testing.T) {
+	t.Run("Success returns all items", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		items, err := service.CreateBatch(context.Background(), itemsNamed("a", "b", "c"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(items) != 3 || items[2].Name != "c" || items[2].CreatedAt.IsZero() {
+			t.Fatalf("unexpected items: %+v", items)
+		}
+	})
+
+	t.Run("Empty or oversized batch returns ErrInvalidValue", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		for _, inputs := range [][]domain.Item{nil, make([]domain.Item, domain.MaxBatchSize+1)} {
+			_, err := service.CreateBatch(context.Background(), inputs)
+			if !errors.Is(err, domain.ErrInvalidValue) {
+				t.Fatalf("batch of %d: expected ErrInvalidValue, got: %v", len(inputs), err)
+			}
+		}
+		if mock.storageCalled {
+			t.Fatal("storage should not be called for invalid batch")
+		}
+	})
+
+	t.Run("Every invalid entry is reported with its index", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.CreateBatch(context.Background(), itemsNamed("a", "", "b", "a", ""))
+
+		var batchErr *domain.BatchError
+		if !errors.As(err, &batchErr) {
+			t.Fatalf("expected BatchError, got: %v", err)
+		}
+		if len(batchErr.Errors) != 3 {
+			t.Fatalf("expected 3 errors, got: %+v", batchErr.Errors)
+		}
+
+		expected := []struct {
+			index int
+			err   error
+		}{{1, domain.ErrEmptyName}, {3, domain.ErrDuplicateInBatch}, {4, domain.ErrEmptyName}}
+		for i, exp := range expected {
+			if batchErr.Errors[i].Index != exp.index || !errors.Is(batchErr.Errors[i].Err, exp.err) {
+				t.Fatalf("error %d: expected index %d %v, got: %+v", i, exp.index, exp.err, batchErr.Errors[i])
+			}
+		}
+		if !errors.Is(err, domain.ErrEmptyName) {
+			t.Fatal("BatchError should unwrap to entry errors")
+		}
+		if mock.storageCalled {
+			t.Fatal("storage should not be called for invalid batch")
+		}
+	})
+
+	t.Run("Storage conflicts are passed through", func(t *testing.T) {
+		storageErr := &domain.BatchError{Errors: []domain.IndexError{{Index: 0, Err: &domain.AlreadyExistsError{ID: 9}}}}
+		mock := &MockStorage{forcedError: storageErr}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.CreateBatch(context.Background(), itemsNamed("a"))
+		if !errors.Is(err, domain.ErrAlreadyExists) {
+			t.Fatalf("expected ErrAlreadyExists inside batch error, got: %v", err)
+		}
+	})
+
+	t.Run("Storage error returns ErrInternal", func(t *testing.T) {
+		mock := &MockStorage{forcedError: errors.New("DB error")}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.CreateBatch(context.Background(), itemsNamed("a"))
+		if !errors.Is(err, domain.ErrInternal) {
+			t.Fatalf("expected ErrInternal, got: %v", err)
+		}
+	})
+}
+
+func TestService_DeleteBatch(t *testing.T) {
+	t.Run("IDs are deduplicated", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		results, err := service.DeleteBatch(context.Background(), domain.BulkDelete{IDs: []int{3, 1, 3}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 2 || fmt.Sprint(mock.bulkDelete.IDs) != "[3 1]" {
+			t.Fatalf("unexpected ids passed to storage: %v", mock.bulkDelete.IDs)
+		}
+	})
+
+	t.Run("Whitespace-only filter is required", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.DeleteBatch(context.Background(), domain.BulkDelete{Name: domain.NameFilter{Contains: "   "}})
+		var validationErr *domain.ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Violations[0].Code != domain.CodeRequired {
+			t.Fatalf("expected required violation, got: %v", err)
+		}
+		if mock.storageCalled {
+			t.Fatal("storage must not be called")
+		}
+	})
+
+	t.Run("Filter is normalized", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.DeleteBatch(context.Background(), domain.BulkDelete{Name: domain.NameFilter{Contains: " TEST "}, DryRun: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mock.bulkDelete.Name.Contains != "test" || !mock.bulkDelete.DryRun {
+			t.Fatalf("unexpected request passed to storage: %+v", mock.bulkDelete)
+		}
+	})
+
+	t.Run("Exactly one selector is required", func(t *testing.T) {
+		requests := []domain.BulkDelete{
+			{},
+			{DryRun: true},
+			{IDs: []int{1}, Name: domain.NameFilter{Prefix: "a"}},
+			{IDs: make([]int, domain.MaxBatchSize+1)},
+			{Name: domain.NameFilter{Exact: "a", Prefix: "b"}},
+		}
+
+		for _, req := range requests {
+			mock := &MockStorage{}
+			service := domain.NewService(mock, domain.SystemClock{})
+
+			_, err := service.DeleteBatch(context.Background(), req)
+			if !errors.Is(err, domain.ErrInvalidValue) {
+				t.Fatalf("request %+v: expected ErrInvalidValue, got: %v", req, err)
+			}
+			if mock.storageCalled {
+				t.Fatalf("request %+v: storage should not be called", req)
+			}
+		}
+	})
+
+	t.Run("Invalid IDs are reported with index", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.DeleteBatch(context.Background(), domain.BulkDelete{IDs: []int{1, 0, -5}})
+
+		var batchErr *domain.BatchError
+		if !errors.As(err, &batchErr) || len(batchErr.Errors) != 2 || batchErr.Errors[0].Index != 1 || batchErr.Errors[1].Index != 2 {
+			t.Fatalf("expected BatchError for indexes 1 and 2, got: %v", err)
+		}
+	})
+
+	t.Run("Storage error returns ErrInternal", func(t *testing.T) {
+		mock := &MockStorage{forcedError: errors.New("DB error")}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.DeleteBatch(context.Background(), domain.BulkDelete{IDs: []int{1}})
+		if !errors.Is(err, domain.ErrInternal) {
+			t.Fatalf("expected ErrInternal, got: %v", err)
+		}
+	})
+}
+
+func TestService_Changes(t *testing.T) {
+	t.Run("Invalid since and limit are reported together", func(t *testing.T) {
+		mock := &MockStorage{}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		_, err := service.Changes(context.Background(), -1, domain.MaxListLimit+1)
+		var validationErr *domain.ValidationError
+		if !errors.As(err, &validationErr) || len(validationErr.Violations) != 2 {
+			t.Fatalf("expected 2 violations, got: %v", err)
+		}
+		if mock.storageCalled {
+			t.Fatal("storage should not be called")
+		}
+	})
+
+	t.Run("Page reports more changes", func(t *testing.T) {
+		mock := &MockStorage{changes: []domain.Change{{Revision: 1}, {Revision: 2}, {Revision: 3}}}
+		service := domain.NewService(mock, domain.SystemClock{})
+
+		page, err := service.Changes(context.Background(), 0, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(page.Changes) != 2 || !page.HasMore || page.Revision != 3 {
+			t.Fatalf("unexpected page: %+v", page)
+		}
+
+		page, _ = service.Changes(context.Background(), 0, 3)
+		if len(page.Changes) != 3 || page.HasMore {
+			t.Fatalf("unexpected last page: %+v", page)
+		}
+	})
+
+	t.Run("Storage errors", func(t *testing.T) {
+		cases := map[error]error{
+			domain.ErrRevisionCompacted:                 domain.ErrRevisionCompacted,
+			domain.ErrInvalidValue:                      domain.ErrInvalidValue,
+			fmt.Errorf("wrapped: %w", context.Canceled): context.Canceled,
+			errors.New("disk failure"):                  domain.ErrInternal,
+		}
+		for storageErr, expected := range cases {
+			service := domain.NewService(&MockStorage{forcedError: storageErr}, domain.SystemClock{})
+			if _, err := service.Changes(context.Background(), 5, 0); !errors.Is(err, expected) {
+				t.Errorf("%v: expected %v, got: %v", storageErr, expected, err)
+			}
+		}
+	})
+
+	t.Run("CompactChanges rejects negative retention", func(t *testing.T) {
+		service := domain.NewService(&MockStorage{}, domain.SystemClock{})
+
+		if _, err := service.CompactChanges(context.Background(), -time.Second); !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("expected ErrInvalidValue, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	MaxTagsPerItem = 32 // Максимальное число тегов у элемента и в фильтре списка
+	MaxTagLength   = 64 // Максимальная длина тега
+)
+
+// TagCount - тег и число живых элементов с ним.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// checkTag проверяет формат тега: те же правила, что и для ключа атрибута.
+func (e *ValidationError) checkTag(tag string) {
+	if !validKey(tag, MaxTagLength) {
+		e.Add("tag", CodeInvalid, keyRule(MaxTagLength), ErrInvalidValue)
+	}
+}
+
+// normalizeTags проверяет теги фильтра и убирает повторы.
+func normalizeTags(v *ValidationError, tags []string) []string {
+	if len(tags) > MaxTagsPerItem {
+		v.Add("tag", CodeTooMany, fmt.Sprintf("at most %d tags allowed, got %d", MaxTagsPerItem, len(tags)), ErrInvalidValue)
+	}
+	for _, tag := range tags {
+		v.checkTag(tag)
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+	normalized := slices.Clone(tags)
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+// AddTag навешивает тег на элемент. Повторное добавление ничего не меняет.
+// version > 0 требует, чтобы текущая версия совпадала.
+func (s *Service) AddTag(ctx context.Context, id, version int, tag string) (Item, error) {
+	if err := validateTagRequest(id, version, tag); err != nil {
+		return Item{}, err
+	}
+
+	item, before, err := s.storage.AddTag(ctx, id, version, tag, s.clock.Now())
+	if err != nil {
+		return Item{}, tagError(err)
+	}
+
+	// Повторное добавление не меняет версию: о нём не публикуем и не пишем в аудит
+	if item.Version != before.Version {
+		s.events.Publish(ctx, ItemUpdated{Item: item, At: item.UpdatedAt})
+		s.audit(ctx, AuditAddTag, id, &before, snapshot(item))
+	}
+	return item, nil
+}
+
+// RemoveTag снимает тег с элемента. Если тега нет, возвращает ErrNotFound.
+func (s *Service) RemoveTag(ctx context.Context, id, version int, tag string) (Item, error) {
+	if err := validateTagRequest(id, version, tag); err != nil {
+		return Item{}, err
+	}
+
+	item, before, err := s.storage.RemoveTag(ctx, id, version, tag, s.clock.Now())
+	if err != nil {
+		return Item{}, tagError(err)
+	}
+
+	s.events.Publish(ctx, ItemUpdated{Item: item, At: item.UpdatedAt})
+	s.audit(ctx, AuditRemoveTag, id, &before, snapshot(item))
+	return item, nil
+}
+
+func (s *Service) ListTags(ctx context.Context) ([]TagCount, error) {
+	tags, err := s.storage.ListTags(ctx)
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, ErrInternal
+	}
+
+	return tags, nil
+}
+
+func validateTagRequest(id, version int, tag string) error {
+	var v ValidationError
+	v.checkID("id", id)
+	v.checkVersion(version)
+	v.checkTag(tag)
+	return v.Err()
+}
+
+func tagError(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return err
+	case errors.Is(err, ErrNotFound):
+		return ErrNotFound
+	case errors.Is(err, ErrVersionConflict):
+		return ErrVersionConflict
+	case errors.Is(err, ErrInvalidValue):
+		return err // превышен лимит тегов
+	default:
+		return ErrInternal
+	}
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DefaultTenant - арендатор запросов, в которых он не указан, и данных, созданных до разделения.
+const DefaultTenant = "default"
+
+const MaxTenantLength = 64
+
+type tenantKey struct{}
+
+// ContextWithTenant задаёт арендатора, в чьём пространстве выполняются операции.
+// Хранилище разделяет данные по нему: ID, имена, корзина и журнал изменений у каждого свои.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext возвращает арендатора запроса; без него - DefaultTenant.
+func TenantFromContext(ctx context.Context) string {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	if !ok || tenant == "" {
+		return DefaultTenant
+	}
+	return tenant
+}
+
+// ValidateTenant принимает имена из строчных латинских букв, цифр, '-' и '_':
+// имя попадает в ключи, логи и журналы.
+func ValidateTenant(tenant string) error {
+	if tenant == "" {
+		return NewValidationError("tenant", CodeRequired, "must not be empty", ErrInvalidValue)
+	}
+	if len(tenant) > MaxTenantLength {
+		return NewValidationError("tenant", CodeTooLong, "must be at most 64 characters", ErrInvalidValue)
+	}
+	for _, r := range tenant {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return NewValidationError("tenant", CodeInvalid, "must contain only a-z, 0-9, '-' and '_'", ErrInvalidValue)
+		}
+	}
+	return nil
+}
+
+// forEachTenant выполняет fn для каждого арендатора с данными, передавая его в контексте.
+// Ошибка одного арендатора не мешает остальным; возвращается сумма и первая ошибка.
+func (s *Service) forEachTenant(ctx context.Context, fn func(ctx context.Context) (int, error)) (int, error) {
+	tenants, err := s.storage.Tenants(ctx)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return 0, err
+		}
+		return 0, ErrInternal
+	}
+
+	total := 0
+	var first error
+	for _, tenant := range tenants {
+		n, err := fn(ContextWithTenant(ctx, tenant))
+		total += n
+		if err != nil && first == nil {
+			first = fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return total, first
+}
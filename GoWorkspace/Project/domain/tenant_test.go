@@ -0,0 +1,144 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Goworkspace/Project/audit"
+	"Goworkspace/Project/domain"
+	"Goworkspace/Project/storage"
+)
+
+func TestService_Tenants(t *testing.T) {
+	tenantCtx := func(i int) context.Context {
+		return domain.ContextWithTenant(context.Background(), fmt.Sprintf("team-%d", i))
+	}
+
+	t.Run("Concurrent load does not cross tenants", func(t *testing.T) {
+		service := domain.NewService(storage.NewMemoryStorage(domain.SystemClock{}), domain.SystemClock{}, domain.WithAuditSink(audit.NewMemorySink()))
+		const tenants, workers = 4, 8
+
+		// У всех арендаторов одинаковые имена: конфликт или удаление по фильтру
+		// у одного не должны задеть остальных
+		var wg sync.WaitGroup
+		for i := 0; i < tenants; i++ {
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func(ctx context.Context, w int) {
+					defer wg.Done()
+					item, err := service.Create(ctx, domain.Item{Name: fmt.Sprintf("keep-%d", w)})
+					if err != nil {
+						t.Errorf("create: %v", err)
+						return
+					}
+					if _, err := service.AddTag(ctx, item.ID, 0, "shared"); err != nil {
+						t.Errorf("tag: %v", err)
+					}
+					if _, err := service.Create(ctx, domain.Item{Name: fmt.Sprintf("drop-%d", w)}); err != nil {
+						t.Errorf("create: %v", err)
+					}
+				}(tenantCtx(i), w)
+			}
+		}
+		wg.Wait()
+
+		for i := 0; i < tenants; i++ {
+			wg.Add(1)
+			go func(ctx context.Context) {
+				defer wg.Done()
+				if _, err := service.DeleteBatch(ctx, domain.BulkDelete{Name: domain.NameFilter{Prefix: "drop-"}}); err != nil {
+					t.Errorf("delete batch: %v", err)
+				}
+			}(tenantCtx(i))
+		}
+		wg.Wait()
+
+		for i := 0; i < tenants; i++ {
+			ctx := tenantCtx(i)
+			page, err := service.List(ctx, domain.ListQuery{Limit: 100})
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			if len(page.Items) != workers {
+				t.Fatalf("team-%d: expected %d items, got %d", i, workers, len(page.Items))
+			}
+			for _, item := range page.Items {
+				if !strings.HasPrefix(item.Name, "keep-") || item.ID > 2*workers {
+					t.Fatalf("team-%d: unexpected item %+v", i, item)
+				}
+			}
+
+			tags, _ := service.ListTags(ctx)
+			if len(tags) != 1 || tags[0].Count != workers {
+				t.Fatalf("team-%d: unexpected tags %+v", i, tags)
+			}
+
+			records, err := service.Audit(ctx, domain.AuditQuery{Limit: domain.MaxListLimit})
+			if err != nil {
+				t.Fatalf("audit: %v", err)
+			}
+			if len(records.Records) != 4*workers {
+				t.Fatalf("team-%d: expected %d audit records, got %d", i, 4*workers, len(records.Records))
+			}
+			for _, rec := range records.Records {
+				if rec.Tenant != fmt.Sprintf("team-%d", i) {
+					t.Fatalf("team-%d: foreign audit record %+v", i, rec)
+				}
+			}
+		}
+	})
+
+	t.Run("Sweeper purges every tenant", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		service := domain.NewService(st, domain.SystemClock{})
+		for i := 0; i < 3; i++ {
+			item, _ := service.Create(tenantCtx(i), domain.Item{Name: "a"})
+			service.Delete(tenantCtx(i), item.ID, 0)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go service.RunTrashSweeper(ctx, time.Millisecond, 0)
+
+		deadline := time.After(time.Second)
+		for i := 0; i < 3; i++ {
+			for {
+				trash, _ := service.ListTrash(tenantCtx(i))
+				if len(trash) == 0 {
+					break
+				}
+				select {
+				case <-deadline:
+					t.Fatalf("team-%d trash was not purged", i)
+				case <-time.After(time.Millisecond):
+				}
+			}
+		}
+	})
+}
+
+func TestValidateTenant(t *testing.T) {
+	cases := map[string]bool{
+		"acme":                  true,
+		"team_1-eu":             true,
+		"":                      false,
+		"Acme":                  false,
+		"a/b":                   false,
+		strings.Repeat("a", 65): false,
+	}
+
+	for tenant, valid := range cases {
+		err := domain.ValidateTenant(tenant)
+		if (err == nil) != valid {
+			t.Fatalf("%q: expected valid=%v, got: %v", tenant, valid, err)
+		}
+		if err != nil && !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("%q: expected ErrInvalidValue, got: %v", tenant, err)
+		}
+	}
+}
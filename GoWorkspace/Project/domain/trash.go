@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+func (s *Service) ListTrash(ctx context.Context) ([]DeletedItem, error) {
+	items, err := s.storage.ListDeleted(ctx)
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, ErrInternal
+	}
+
+	return items, nil
+}
+
+// Restore возвращает элемент из корзины. Элемент, чей родитель удалён, восстанавливается
+// только после родителя (ErrParentDeleted).
+func (s *Service) Restore(ctx context.Context, id int) (Item, error) {
+	var v ValidationError
+	v.checkID("id", id)
+	if err := v.Err(); err != nil {
+		return Item{}, err
+	}
+
+	now := s.clock.Now()
+	item, err := s.storage.RestoreItem(ctx, id, now)
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return Item{}, err
+		}
+		if errors.Is(err, ErrNotFound) {
+			return Item{}, ErrNotFound
+		}
+		if errors.Is(err, ErrAlreadyExists) {
+			return Item{}, err
+		}
+		if errors.Is(err, ErrParentDeleted) {
+			return Item{}, ErrParentDeleted
+		}
+		return Item{}, ErrInternal
+	}
+
+	s.events.Publish(ctx, ItemCreated{Item: item, At: now})
+	s.audit(ctx, AuditRestore, item.ID, nil, snapshot(item))
+	return item, nil
+}
+
+// PurgeTrash окончательно удаляет элементы, пролежавшие в корзине дольше retention.
+func (s *Service) PurgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	if retention < 0 {
+		return 0, NewValidationError("retention", CodeOutOfRange, "must not be negative", ErrInvalidValue)
+	}
+
+	purged, err := s.storage.PurgeDeleted(ctx, s.clock.Now().Add(-retention))
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return 0, err
+		}
+		return 0, ErrInternal
+	}
+
+	return purged, nil
+}
+
+// RunTrashSweeper каждые interval очищает корзины всех арендаторов от элементов старше retention.
+// Блокируется до отмены ctx.
+func (s *Service) RunTrashSweeper(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.forEachTenant(ctx, func(ctx context.Context) (int, error) {
+				return s.PurgeTrash(ctx, retention)
+			})
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("[ERROR]: trash sweeper: %v", err)
+				}
+				continue
+			}
+			if purged > 0 {
+				log.Printf("[INFO]: trash sweeper: purged %d items", purged)
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+// TreeNode - элемент с потомками, результат Service.Subtree.
+type TreeNode struct {
+	Item
+	Children []TreeNode // по возрастанию ID
+}
+
+// Children возвращает страницу детей элемента parentID. Фильтры и курсор - как в List.
+func (s *Service) Children(ctx context.Context, parentID int, query ListQuery) (ItemPage, error) {
+	var v ValidationError
+	v.checkID("id", parentID)
+	if err := v.Err(); err != nil {
+		return ItemPage{}, err
+	}
+
+	// Без проверки пустая страница не отличалась бы от несуществующего родителя
+	if _, err := s.Get(ctx, parentID); err != nil {
+		return ItemPage{}, err
+	}
+
+	query.Parent = parentID
+	return s.List(ctx, query)
+}
+
+// Subtree возвращает элемент с потомками до глубины depth (0 - все уровни).
+func (s *Service) Subtree(ctx context.Context, id, depth int) (TreeNode, error) {
+	var v ValidationError
+	v.checkID("id", id)
+	if depth < 0 {
+		v.Add("depth", CodeOutOfRange, "must not be negative", ErrInvalidValue)
+	}
+	if err := v.Err(); err != nil {
+		return TreeNode{}, err
+	}
+
+	items, err := s.storage.ListSubtree(ctx, id, depth)
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return TreeNode{}, err
+		}
+		if errors.Is(err, ErrNotFound) {
+			return TreeNode{}, ErrNotFound
+		}
+		return TreeNode{}, ErrInternal
+	}
+
+	return buildTree(items), nil
+}
+
+// buildTree собирает дерево из items: первый - корень, предки идут раньше потомков.
+func buildTree(items []Item) TreeNode {
+	children := make(map[int][]Item, len(items))
+	for _, item := range items[1:] {
+		children[item.ParentID] = append(children[item.ParentID], item)
+	}
+
+	var build func(item Item) TreeNode
+	build = func(item Item) TreeNode {
+		node := TreeNode{Item: item}
+		for _, child := range children[item.ID] {
+			node.Children = append(node.Children, build(child))
+		}
+		return node
+	}
+	return build(items[0])
+}
+
+// DeleteTree удаляет элемент вместе со всеми потомками атомарно и возвращает ID удалённых,
+// потомков раньше предков. version > 0 требует, чтобы версия элемента совпадала.
+func (s *Service) DeleteTree(ctx context.Context, id, version int) ([]int, error) {
+	var v ValidationError
+	v.checkID("id", id)
+	v.checkVersion(version)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	deleted, err := s.storage.DeleteTree(ctx, id, version, now)
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		if errors.Is(err, ErrVersionConflict) {
+			return nil, ErrVersionConflict
+		}
+		return nil, ErrInternal
+	}
+
+	ids := make([]int, 0, len(deleted))
+	for _, item := range deleted {
+		ids = append(ids, item.ID)
+		s.events.Publish(ctx, ItemDeleted{ID: item.ID, At: now})
+		s.audit(ctx, AuditDelete, item.ID, snapshot(item), nil)
+	}
+	return ids, nil
+}
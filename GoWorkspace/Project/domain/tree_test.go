@@ -0,0 +1,101 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"Goworkspace/Project/audit"
+	"Goworkspace/Project/domain"
+	"Goworkspace/Project/storage"
+)
+
+func TestService_Tree(t *testing.T) {
+	ctx := context.Background()
+
+	// folder(1) -> docs(2) -> readme(4); folder(1) -> notes(3)
+	newService := func(t *testing.T, opts ...domain.ServiceOption) *domain.Service {
+		t.Helper()
+		service := domain.NewService(storage.NewMemoryStorage(domain.SystemClock{}), domain.SystemClock{}, opts...)
+		for _, item := range []domain.Item{{Name: "folder"}, {Name: "docs", ParentID: 1}, {Name: "notes", ParentID: 1}, {Name: "readme", ParentID: 2}} {
+			if _, err := service.Create(ctx, item); err != nil {
+				t.Fatalf("create %s: %v", item.Name, err)
+			}
+		}
+		return service
+	}
+
+	t.Run("Invalid parent", func(t *testing.T) {
+		service := newService(t)
+
+		_, err := service.Create(ctx, domain.Item{Name: "x", ParentID: -1})
+		var validationErr *domain.ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Violations[0].Field != "parent_id" {
+			t.Fatalf("expected parent_id violation, got: %v", err)
+		}
+		if _, err := service.Create(ctx, domain.Item{Name: "x", ParentID: 42}); !errors.As(err, &validationErr) {
+			t.Fatalf("expected validation error for missing parent, got: %v", err)
+		}
+		if _, err := service.Update(ctx, domain.Item{ID: 1, Name: "folder", ParentID: 4}); !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("expected cycle to be rejected, got: %v", err)
+		}
+	})
+
+	t.Run("Children and subtree", func(t *testing.T) {
+		service := newService(t)
+
+		page, err := service.Children(ctx, 1, domain.ListQuery{Limit: 1})
+		if err != nil || len(page.Items) != 1 || page.Items[0].Name != "docs" || page.NextCursor == "" {
+			t.Fatalf("unexpected first page: %+v, %v", page, err)
+		}
+		page, _ = service.Children(ctx, 1, domain.ListQuery{Cursor: page.NextCursor})
+		if len(page.Items) != 1 || page.Items[0].Name != "notes" || page.NextCursor != "" {
+			t.Fatalf("unexpected second page: %+v", page)
+		}
+		if _, err := service.Children(ctx, 42, domain.ListQuery{}); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got: %v", err)
+		}
+
+		tree, err := service.Subtree(ctx, 1, 0)
+		if err != nil {
+			t.Fatalf("subtree: %v", err)
+		}
+		if len(tree.Children) != 2 || tree.Children[0].Name != "docs" || len(tree.Children[0].Children) != 1 || tree.Children[0].Children[0].Name != "readme" {
+			t.Fatalf("unexpected tree: %+v", tree)
+		}
+		if shallow, _ := service.Subtree(ctx, 1, 1); len(shallow.Children[0].Children) != 0 {
+			t.Fatalf("depth 1 must not include grandchildren: %+v", shallow)
+		}
+	})
+
+	t.Run("Delete with children requires cascade", func(t *testing.T) {
+		pub := &recordingPublisher{}
+		sink := audit.NewMemorySink()
+		service := newService(t, domain.WithEventPublisher(pub), domain.WithAuditSink(sink))
+		pub.events = nil
+
+		if err := service.Delete(ctx, 1, 0); !errors.Is(err, domain.ErrHasChildren) {
+			t.Fatalf("expected ErrHasChildren, got: %v", err)
+		}
+
+		deleted, err := service.DeleteTree(ctx, 1, 0)
+		if err != nil || fmt.Sprint(deleted) != "[4 3 2 1]" {
+			t.Fatalf("unexpected cascade: %v, %v", deleted, err)
+		}
+		if len(pub.events) != 4 || pub.events[0].ItemID() != 4 {
+			t.Fatalf("expected 4 delete events starting with the leaf, got %v", pub.types())
+		}
+		page, _ := service.Audit(ctx, domain.AuditQuery{Action: domain.AuditDelete})
+		if len(page.Records) != 4 || page.Records[3].Before == nil || page.Records[3].Before.Name != "folder" {
+			t.Fatalf("unexpected audit: %+v", page.Records)
+		}
+
+		if _, err := service.Restore(ctx, 2); !errors.Is(err, domain.ErrParentDeleted) {
+			t.Fatalf("expected ErrParentDeleted, got: %v", err)
+		}
+		if _, err := service.DeleteTree(ctx, 1, 0); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got: %v", err)
+		}
+	})
+}
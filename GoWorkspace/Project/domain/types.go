@@ -1,11 +1,31 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	ErrEmptyName    = errors.New("empty name")            // пустое имя
-	ErrInvalidValue = errors.New("invalid value")         // Ошибка значения
-	ErrNotFound     = errors.New("not found")             // Нет данных
-	ErrInternal     = errors.New("server internal error") // Ошибка сервера
-	ErrBadRequest   = errors.New("bad request")           // ошибка запроса
+	ErrEmptyName       = errors.New("empty name")            // пустое имя
+	ErrInvalidValue    = errors.New("invalid value")         // Ошибка значения
+	ErrNotFound        = errors.New("not found")             // Нет данных
+	ErrInternal        = errors.New("server internal error") // Ошибка сервера
+	ErrBadRequest      = errors.New("bad request")           // ошибка запроса
+	ErrAlreadyExists   = errors.New("already exists")        // Повторное значение
+	ErrVersionConflict = errors.New("version conflict")      // Версия элемента изменилась
+	ErrHasChildren     = errors.New("item has children")     // Удаление без каскада, а у элемента есть дети
+	ErrParentDeleted   = errors.New("parent is deleted")     // Восстановление раньше родителя
 )
+
+// AlreadyExistsError сообщает ID элемента, который уже хранит такое имя.
+type AlreadyExistsError struct {
+	ID int
+}
+
+func (e *AlreadyExistsError) Error() string {
+	return fmt.Sprintf("%v: id=%d", ErrAlreadyExists, e.ID)
+}
+
+func (e *AlreadyExistsError) Is(target error) bool {
+	return target == ErrAlreadyExists
+}
@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Коды нарушений в FieldViolation.Code. Клиенты могут на них опираться.
+const (
+	CodeRequired   = "required"     // значение обязательно
+	CodeOutOfRange = "out_of_range" // число вне допустимого диапазона
+	CodeTooLong    = "too_long"     // строка длиннее лимита
+	CodeTooMany    = "too_many"     // элементов больше лимита
+	CodeInvalid    = "invalid"      // неверный формат или недопустимые символы
+	CodeConflict   = "conflict"     // поля нельзя задавать одновременно
+	CodeMalformed  = "malformed"    // тело запроса не разбирается
+	CodeNotFound   = "not_found"    // ссылка на несуществующий объект
+)
+
+// FieldViolation - нарушение правила для одного поля запроса.
+// Err - сентинел (ErrEmptyName, ErrInvalidValue, ErrBadRequest), по которому
+// ошибку можно проверить через errors.Is.
+type FieldViolation struct {
+	Field   string
+	Code    string
+	Message string
+	Err     error
+}
+
+// ValidationError собирает все нарушения запроса, а не только первое.
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+// NewValidationError создаёт ошибку с одним нарушением.
+func NewValidationError(field, code, message string, err error) *ValidationError {
+	v := &ValidationError{}
+	v.Add(field, code, message, err)
+	return v
+}
+
+// Add добавляет нарушение.
+func (e *ValidationError) Add(field, code, message string, err error) {
+	e.Violations = append(e.Violations, FieldViolation{Field: field, Code: code, Message: message, Err: err})
+}
+
+// Merge добавляет нарушения из other, дописывая prefix к именам полей.
+func (e *ValidationError) Merge(prefix string, other *ValidationError) {
+	if other == nil {
+		return
+	}
+	for _, violation := range other.Violations {
+		violation.Field = prefix + violation.Field
+		e.Violations = append(e.Violations, violation)
+	}
+}
+
+// Err возвращает nil, если нарушений нет. Проверять нужно результат Err, а не сам
+// ValidationError, иначе пустая ошибка окажется ненулевым интерфейсом.
+func (e *ValidationError) Err() error {
+	if e == nil || len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		parts = append(parts, fmt.Sprintf("%s: %s", violation.Field, violation.Message))
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		errs = append(errs, violation.Err)
+	}
+	return errs
+}
+
+// checkID добавляет нарушение, если id не положительный.
+func (e *ValidationError) checkID(field string, id int) {
+	if id < 1 {
+		e.Add(field, CodeOutOfRange, "must be a positive integer", ErrInvalidValue)
+	}
+}
+
+// checkVersion добавляет нарушение для отрицательной версии; 0 - без проверки версии.
+func (e *ValidationError) checkVersion(version int) {
+	if version < 0 {
+		e.Add("version", CodeOutOfRange, "must not be negative", ErrInvalidValue)
+	}
+}
+
+func (e *ValidationError) checkName(name string) {
+	if name == "" {
+		e.Add("name", CodeRequired, "must not be empty", ErrEmptyName)
+	}
+}
+
+// checkItem проверяет поля, которые задаёт клиент: имя, атрибуты и ссылку на родителя.
+// Существование родителя проверяет хранилище.
+func (e *ValidationError) checkItem(item Item) {
+	e.checkName(item.Name)
+	e.checkAttributes("attributes", item.Attributes)
+	if item.ParentID < 0 {
+		e.Add("parent_id", CodeOutOfRange, "must be a positive integer", ErrInvalidValue)
+	}
+}
@@ -0,0 +1,176 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"Goworkspace/Project/domain"
+)
+
+// DefaultBuffer - размер очереди асинхронного подписчика по умолчанию.
+const DefaultBuffer = 256
+
+type Handler func(ctx context.Context, event domain.Event) error
+
+// Bus - внутрипроцессная шина событий, реализует domain.EventPublisher.
+// Синхронные подписчики вызываются в горутине публикации, асинхронные - в своей,
+// через ограниченную очередь. Ошибки и паники подписчиков только логируются.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[int]*subscription
+	next   int
+	closed bool
+
+	wg      sync.WaitGroup
+	dropped atomic.Int64
+}
+
+type subscription struct {
+	name    string
+	handler Handler
+	queue   chan delivery // nil у синхронного подписчика
+}
+
+type delivery struct {
+	ctx   context.Context
+	event domain.Event
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[int]*subscription)}
+}
+
+// Subscribe регистрирует синхронного подписчика. Возвращает функцию отписки.
+func (b *Bus) Subscribe(name string, h Handler) (unsubscribe func()) {
+	return b.add(&subscription{name: name, handler: h})
+}
+
+// SubscribeAsync регистрирует подписчика со своей очередью размера buffer.
+// Если очередь заполнена, событие для этого подписчика отбрасывается:
+// медленный подписчик не должен задерживать запросы.
+func (b *Bus) SubscribeAsync(name string, buffer int, h Handler) (unsubscribe func()) {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	return b.add(&subscription{name: name, handler: h, queue: make(chan delivery, buffer)})
+}
+
+func (b *Bus) add(sub *subscription) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return func() {}
+	}
+	id := b.next
+	b.next++
+	b.subs[id] = sub
+	if sub.queue != nil {
+		b.wg.Add(1)
+		go b.run(sub)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { b.remove(id) })
+	}
+}
+
+func (b *Bus) remove(id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub, ok := b.subs[id]
+	if !ok {
+		return
+	}
+	delete(b.subs, id)
+	if sub.queue != nil {
+		close(sub.queue)
+	}
+}
+
+// Publish раздаёт событие всем подписчикам. После Close события игнорируются.
+func (b *Bus) Publish(ctx context.Context, event domain.Event) {
+	var direct []*subscription
+
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return
+	}
+	// Асинхронная доставка переживает запрос, поэтому отмена его контекста не передаётся;
+	// значения (арендатор, автор) остаются
+	detached := context.WithoutCancel(ctx)
+	for _, sub := range b.subs {
+		if sub.queue == nil {
+			direct = append(direct, sub)
+			continue
+		}
+		select {
+		case sub.queue <- delivery{ctx: detached, event: event}:
+		default:
+			b.dropped.Add(1)
+			log.Printf("[WARN]: event %s id=%d dropped: subscriber %q queue is full", event.EventType(), event.ItemID(), sub.name)
+		}
+	}
+	b.mu.RUnlock()
+
+	// Синхронные подписчики вызываются без блокировки: им можно подписываться и отписываться
+	for _, sub := range direct {
+		sub.deliver(ctx, event)
+	}
+}
+
+// Dropped - число событий, отброшенных из-за переполненных очередей.
+func (b *Bus) Dropped() int64 {
+	return b.dropped.Load()
+}
+
+// Close прекращает приём событий и ждёт, пока асинхронные подписчики
+// обработают уже поставленные в очередь, но не дольше, чем живёт ctx.
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for id, sub := range b.subs {
+			delete(b.subs, id)
+			if sub.queue != nil {
+				close(sub.queue)
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Bus) run(sub *subscription) {
+	defer b.wg.Done()
+	for d := range sub.queue {
+		sub.deliver(d.ctx, d.event)
+	}
+}
+
+func (sub *subscription) deliver(ctx context.Context, event domain.Event) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("[ERROR]: event %s id=%d: subscriber %q panicked: %v", event.EventType(), event.ItemID(), sub.name, rec)
+		}
+	}()
+	if err := sub.handler(ctx, event); err != nil {
+		log.Printf("[ERROR]: event %s id=%d: subscriber %q failed: %v", event.EventType(), event.ItemID(), sub.name, err)
+	}
+}
@@ -0,0 +1,176 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"Goworkspace/Project/domain"
+	"Goworkspace/Project/events"
+)
+
+func created(id int) domain.Event {
+	return domain.ItemCreated{Item: domain.Item{ID: id}, At: time.Now()}
+}
+
+func TestBus_Sync(t *testing.T) {
+	t.Run("Delivers in publisher goroutine", func(t *testing.T) {
+		bus := events.NewBus()
+		var got []int
+		bus.Subscribe("test", func(ctx context.Context, e domain.Event) error {
+			got = append(got, e.ItemID())
+			return nil
+		})
+
+		bus.Publish(context.Background(), created(1))
+		bus.Publish(context.Background(), created(2))
+
+		if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+			t.Fatalf("expected [1 2], got %v", got)
+		}
+	})
+
+	t.Run("Failing subscriber does not affect others", func(t *testing.T) {
+		bus := events.NewBus()
+		calls := 0
+		bus.Subscribe("error", func(ctx context.Context, e domain.Event) error {
+			return errors.New("boom")
+		})
+		bus.Subscribe("panic", func(ctx context.Context, e domain.Event) error {
+			panic("boom")
+		})
+		bus.Subscribe("ok", func(ctx context.Context, e domain.Event) error {
+			calls++
+			return nil
+		})
+
+		bus.Publish(context.Background(), created(1))
+
+		if calls != 1 {
+			t.Fatalf("expected healthy subscriber to be called once, got %d", calls)
+		}
+	})
+
+	t.Run("Unsubscribe from handler", func(t *testing.T) {
+		bus := events.NewBus()
+		calls := 0
+		var unsubscribe func()
+		unsubscribe = bus.Subscribe("once", func(ctx context.Context, e domain.Event) error {
+			calls++
+			unsubscribe()
+			return nil
+		})
+
+		bus.Publish(context.Background(), created(1))
+		bus.Publish(context.Background(), created(2))
+
+		if calls != 1 {
+			t.Fatalf("expected 1 call, got %d", calls)
+		}
+	})
+}
+
+func TestBus_Async(t *testing.T) {
+	t.Run("Close drains queued events", func(t *testing.T) {
+		bus := events.NewBus()
+		var (
+			mu  sync.Mutex
+			got []int
+		)
+		bus.SubscribeAsync("test", 10, func(ctx context.Context, e domain.Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, e.ItemID())
+			return nil
+		})
+
+		for id := 1; id <= 5; id++ {
+			bus.Publish(context.Background(), created(id))
+		}
+		if err := bus.Close(context.Background()); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(got) != 5 {
+			t.Fatalf("expected 5 events in order, got %v", got)
+		}
+		for i, id := range got {
+			if id != i+1 {
+				t.Fatalf("expected events in order, got %v", got)
+			}
+		}
+	})
+
+	t.Run("Slow subscriber does not block publisher", func(t *testing.T) {
+		bus := events.NewBus()
+		release := make(chan struct{})
+		bus.SubscribeAsync("slow", 1, func(ctx context.Context, e domain.Event) error {
+			<-release
+			return nil
+		})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for id := 1; id <= 10; id++ {
+				bus.Publish(context.Background(), created(id))
+			}
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("publish blocked on slow subscriber")
+		}
+		if bus.Dropped() == 0 {
+			t.Fatal("expected overflowing events to be dropped")
+		}
+
+		close(release)
+		if err := bus.Close(context.Background()); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+	})
+
+	t.Run("Handler outlives request context", func(t *testing.T) {
+		bus := events.NewBus()
+		ctxErr := make(chan error, 1)
+		bus.SubscribeAsync("test", 1, func(ctx context.Context, e domain.Event) error {
+			ctxErr <- ctx.Err()
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		bus.Publish(ctx, created(1))
+		cancel()
+		bus.Close(context.Background())
+
+		if err := <-ctxErr; err != nil {
+			t.Fatalf("expected detached context, got: %v", err)
+		}
+	})
+
+	t.Run("Close respects context deadline", func(t *testing.T) {
+		bus := events.NewBus()
+		release := make(chan struct{})
+		defer close(release)
+		bus.SubscribeAsync("stuck", 1, func(ctx context.Context, e domain.Event) error {
+			<-release
+			return nil
+		})
+		bus.Publish(context.Background(), created(1))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := bus.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected DeadlineExceeded, got: %v", err)
+		}
+
+		// После закрытия события игнорируются, а не приводят к панике
+		bus.Publish(context.Background(), created(2))
+	})
+}
@@ -1,10 +1,14 @@
 package middleware
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"Goworkspace/Project/problem"
 )
 
 func TestRecovery_middleware(t *testing.T) {
@@ -43,3 +47,70 @@ func TestTimeout_middleware(t *testing.T) {
 	}
 
 }
+
+func TestRecovery_problemDetails(t *testing.T) {
+	handlerPanic := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { panic("Boom") })
+
+	t.Run("Problem details by default", func(t *testing.T) {
+		handler := RequestIDMiddleware(RecoveryMiddleware(handlerPanic))
+
+		req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+		req.Header.Set(RequestIDHeader, "abc-1")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+			t.Fatalf("Expected %s, got: %s", problem.ContentType, ct)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Unexpected error json: %v", err)
+		}
+		if body["status"] != float64(500) || body["instance"] != "/panic" || body["request_id"] != "abc-1" {
+			t.Fatalf("Unexpected body: %v", body)
+		}
+	})
+
+	t.Run("Legacy shape keeps the original body", func(t *testing.T) {
+		handler := LegacyErrorsMiddleware(RecoveryMiddleware(handlerPanic))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+		if rec.Code != http.StatusInternalServerError || rec.Body.String() != `{"Error":"internal server error"}`+"\n" {
+			t.Fatalf("Unexpected response: %d %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestRequestID_middleware(t *testing.T) {
+	var seen string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+	}))
+
+	cases := map[string]bool{
+		"client-id_1.2:3":        true,
+		"":                       false,
+		"with space":             false,
+		strings.Repeat("a", 129): false,
+	}
+
+	for incoming, kept := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if incoming != "" {
+			req.Header.Set(RequestIDHeader, incoming)
+		}
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if seen == "" || rec.Header().Get(RequestIDHeader) != seen {
+			t.Fatalf("%q: request id must be set and echoed, got: %q / %q", incoming, seen, rec.Header().Get(RequestIDHeader))
+		}
+		if (seen == incoming) != kept {
+			t.Fatalf("%q: expected kept=%v, got: %q", incoming, kept, seen)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"Goworkspace/Project/domain"
+)
+
+const (
+	ActorHeader    = "X-Actor"
+	AnonymousActor = "anonymous"
+)
+
+// ActorMiddleware кладёт в контекст автора запроса для журнала аудита.
+// Аутентификации пока нет, поэтому имя - то, что клиент заявил в X-Actor;
+// адрес - адрес соединения: X-Forwarded-For без доверенного прокси подделывается.
+// Должен стоять после RequestIDMiddleware.
+func ActorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.Header.Get(ActorHeader)
+		if !validRequestID(name) { // те же правила, что для ID запроса: значение попадает в логи
+			name = AnonymousActor
+		}
+
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		actor := domain.Actor{Name: name, RequestID: RequestID(r.Context()), ClientIP: ip}
+		next.ServeHTTP(w, r.WithContext(domain.ContextWithActor(r.Context(), actor)))
+	})
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+type legacyErrorsKey struct{}
+
+// LegacyErrorsMiddleware включает прежний формат ошибок ({"error": ...}) вместо
+// application/problem+json для клиентов, которые ещё не перешли на RFC 7807.
+func LegacyErrorsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), legacyErrorsKey{}, true)))
+	})
+}
+
+// LegacyErrors сообщает, нужно ли отвечать об ошибках в прежнем формате.
+func LegacyErrors(ctx context.Context) bool {
+	legacy, _ := ctx.Value(legacyErrorsKey{}).(bool)
+	return legacy
+}
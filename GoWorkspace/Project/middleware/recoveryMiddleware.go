@@ -4,16 +4,16 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+
+	"Goworkspace/Project/problem"
 )
 
 func RecoveryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if rec := recover(); rec != nil {
-				log.Printf("[RECOVERY]: %s %s: panic recovered: %v", r.Method, r.URL.Path, rec)
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusInternalServerError)
-				if err := json.NewEncoder(w).Encode(map[string]string{"Error": "internal server error"}); err != nil {
+				log.Printf("[RECOVERY]: %s %s request_id=%s: panic recovered: %v", r.Method, r.URL.Path, RequestID(r.Context()), rec)
+				if err := writeInternalError(w, r); err != nil {
 					log.Printf("[ERROR]: %s %s: %v", r.Method, r.URL.Path, err)
 				}
 			}
@@ -21,3 +21,17 @@ func RecoveryMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+func writeInternalError(w http.ResponseWriter, r *http.Request) error {
+	// Старые клиенты получают прежнее тело без изменений, включая ключ "Error"
+	if LegacyErrors(r.Context()) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		return json.NewEncoder(w).Encode(map[string]string{"Error": "internal server error"})
+	}
+
+	details := problem.New(http.StatusInternalServerError, "internal server error")
+	details.Instance = r.URL.Path
+	details.RequestID = RequestID(r.Context())
+	return problem.Write(w, details)
+}
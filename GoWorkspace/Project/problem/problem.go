@@ -0,0 +1,65 @@
+// Package problem формирует ответы об ошибках в формате RFC 7807 (application/problem+json).
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	ContentType = "application/problem+json"
+	TypeBlank   = "about:blank" // тип без дополнительной семантики: смысл ошибки - сам HTTP-статус
+)
+
+// Details - тело ответа RFC 7807. Extensions добавляются в объект верхнего уровня
+// рядом со стандартными полями и не могут их перекрыть.
+type Details struct {
+	Type      string
+	Title     string
+	Status    int
+	Detail    string
+	Instance  string
+	RequestID string
+
+	Extensions map[string]any
+}
+
+// New создаёт Details с типом about:blank и стандартным заголовком статуса.
+func New(status int, detail string) Details {
+	return Details{Type: TypeBlank, Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+func (d Details) MarshalJSON() ([]byte, error) {
+	doc := make(map[string]any, len(d.Extensions)+6)
+	for key, value := range d.Extensions {
+		doc[key] = value
+	}
+
+	doc["type"] = d.Type
+	doc["title"] = d.Title
+	doc["status"] = d.Status
+	if d.Detail != "" {
+		doc["detail"] = d.Detail
+	}
+	if d.Instance != "" {
+		doc["instance"] = d.Instance
+	}
+	if d.RequestID != "" {
+		doc["request_id"] = d.RequestID
+	}
+
+	return json.Marshal(doc)
+}
+
+// Write отправляет d с Content-Type application/problem+json и статусом d.Status.
+func Write(w http.ResponseWriter, d Details) error {
+	body, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(d.Status)
+	_, err = w.Write(append(body, '\n'))
+	return err
+}
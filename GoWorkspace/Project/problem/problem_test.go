@@ -0,0 +1,65 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	t.Run("Writes standard members and extensions", func(t *testing.T) {
+		details := New(http.StatusNotFound, "item 42")
+		details.Instance = "/item/42"
+		details.RequestID = "req-1"
+		details.Extensions = map[string]any{"violations": []string{"a"}}
+
+		rec := httptest.NewRecorder()
+		if err := Write(rec, details); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("Expected code 404, got: %d", rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+			t.Fatalf("Expected %s, got: %s", ContentType, ct)
+		}
+
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Unexpected error json: %v", err)
+		}
+		expected := map[string]any{
+			"type": TypeBlank, "title": "Not Found", "status": float64(404),
+			"detail": "item 42", "instance": "/item/42", "request_id": "req-1",
+		}
+		for key, value := range expected {
+			if body[key] != value {
+				t.Fatalf("%s: expected %v, got: %v", key, value, body[key])
+			}
+		}
+		if _, ok := body["violations"]; !ok {
+			t.Fatalf("Expected extension member, got: %v", body)
+		}
+	})
+
+	t.Run("Extensions cannot override standard members", func(t *testing.T) {
+		details := New(http.StatusBadRequest, "")
+		details.Extensions = map[string]any{"status": 200, "title": "OK"}
+
+		data, err := json.Marshal(details)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var body map[string]any
+		json.Unmarshal(data, &body)
+		if body["status"] != float64(400) || body["title"] != "Bad Request" {
+			t.Fatalf("Standard members must win, got: %v", body)
+		}
+		if _, ok := body["detail"]; ok {
+			t.Fatalf("Empty detail must be omitted, got: %v", body)
+		}
+	})
+}
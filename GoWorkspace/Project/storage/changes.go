@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"Goworkspace/Project/domain"
+)
+
+func (s *MemoryStorage) ListChanges(ctx context.Context, since int64, limit int) ([]domain.Change, int64, error) {
+	select {
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	default:
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		return s.read(ctx).listChanges(since, limit)
+	}
+}
+
+func (s *MemoryStorage) CompactChanges(ctx context.Context, before time.Time) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		return s.write(ctx).compactChanges(before), nil
+	}
+}
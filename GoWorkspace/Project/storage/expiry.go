@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"container/heap"
+	"context"
+	"time"
+
+	"Goworkspace/Project/domain"
+)
+
+// Срок жизни элементов. Чтение скрывает истёкший элемент сразу, запись сначала удаляет
+// истёкшие элементы окончательно (expire), ReapExpired отдаёт удалённые для публикации событий.
+
+func (s *MemoryStorage) ReapExpired(ctx context.Context) ([]domain.Item, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		return s.write(ctx).reapExpired(), nil
+	}
+}
+
+// expired сообщает, истёк ли срок жизни элемента к now.
+func expired(item domain.Item, now time.Time) bool {
+	return !item.ExpiresAt.IsZero() && !now.Before(item.ExpiresAt)
+}
+
+type expiryEntry struct {
+	at time.Time
+	id int
+}
+
+// expiryHeap - min-куча сроков жизни для container/heap. Записи удалённых элементов
+// не вычищаются сразу: expire пропускает их, когда они доходят до вершины.
+type expiryHeap []expiryEntry
+
+func (h expiryHeap) Len() int { return len(h) }
+
+// Less при равных сроках ставит первым больший ID: дети обычно создаются позже родителей.
+func (h expiryHeap) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].id > h[j].id
+	}
+	return h[i].at.Before(h[j].at)
+}
+
+func (h expiryHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *expiryHeap) Push(x any) { *h = append(*h, x.(expiryEntry)) }
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
+}
+
+func (st *state) scheduleExpiry(item domain.Item) {
+	if !item.ExpiresAt.IsZero() {
+		heap.Push(&st.expiry, expiryEntry{at: item.ExpiresAt, id: item.ID})
+	}
+}
+
+// expire окончательно удаляет живые элементы, истёкшие к now, минуя корзину,
+// и копит их до reapExpired.
+func (st *state) expire(now time.Time) {
+	for len(st.expiry) > 0 && !now.Before(st.expiry[0].at) {
+		entry := heap.Pop(&st.expiry).(expiryEntry)
+		item, ok := st.data[entry.id]
+		if !ok || !item.ExpiresAt.Equal(entry.at) {
+			continue // элемент уже удалён
+		}
+
+		// Дети истекают не позже родителя и удаляются в этом же цикле
+		delete(st.data, item.ID)
+		st.unlinkParent(item.ID, item.ParentID)
+		st.unindexName(item.Name)
+		st.unindexOrder(item.ID)
+		st.unindexTags(item.Tags, item.ID)
+		st.record(domain.EventItemDeleted, domain.Item{ID: item.ID}, now)
+		st.expired = append(st.expired, item)
+	}
+}
+
+// reapExpired возвращает элементы, удалённые expire с прошлого вызова, и забывает их.
+func (st *state) reapExpired() []domain.Item {
+	reaped := make([]domain.Item, 0, len(st.expired))
+	for _, item := range st.expired {
+		reaped = append(reaped, detach(item))
+	}
+	st.expired = nil
+	return reaped
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+)
+
+// Индексы state. Поддерживаются при каждой записи.
+
+func (st *state) indexName(name string, id int) {
+	st.names[name] = id
+
+	pos := sort.SearchStrings(st.sortedNames, name)
+	st.sortedNames = append(st.sortedNames, "")
+	copy(st.sortedNames[pos+1:], st.sortedNames[pos:])
+	st.sortedNames[pos] = name
+}
+
+func (st *state) unindexName(name string) {
+	delete(st.names, name)
+
+	if pos := sort.SearchStrings(st.sortedNames, name); pos < len(st.sortedNames) && st.sortedNames[pos] == name {
+		st.sortedNames = append(st.sortedNames[:pos], st.sortedNames[pos+1:]...)
+	}
+}
+
+// idsByPrefix возвращает ID элементов, имя которых начинается с prefix, по возрастанию ID.
+func (st *state) idsByPrefix(prefix string) []int {
+	var ids []int
+	for pos := sort.SearchStrings(st.sortedNames, prefix); pos < len(st.sortedNames); pos++ {
+		name := st.sortedNames[pos]
+		if !strings.HasPrefix(name, prefix) {
+			break
+		}
+		ids = append(ids, st.names[name])
+	}
+
+	sort.Ints(ids)
+	return ids
+}
+
+func (st *state) indexTags(tags []string, id int) {
+	for _, tag := range tags {
+		ids, ok := st.tags[tag]
+		if !ok {
+			ids = make(map[int]struct{})
+			st.tags[tag] = ids
+		}
+		ids[id] = struct{}{}
+	}
+}
+
+func (st *state) unindexTags(tags []string, id int) {
+	for _, tag := range tags {
+		delete(st.tags[tag], id)
+		if len(st.tags[tag]) == 0 {
+			delete(st.tags, tag) // тег без элементов не попадает в ListTags
+		}
+	}
+}
+
+// idsByTag возвращает ID элементов самого редкого из tags по возрастанию ID.
+// Остальные теги проверяет вызывающий.
+func (st *state) idsByTag(tags []string) []int {
+	rarest := st.tags[tags[0]]
+	for _, tag := range tags[1:] {
+		if len(st.tags[tag]) < len(rarest) {
+			rarest = st.tags[tag]
+		}
+	}
+
+	ids := make([]int, 0, len(rarest))
+	for id := range rarest {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+	return ids
+}
+
+func (st *state) indexOrder(id int) {
+	pos := sort.SearchInts(st.order, id)
+	st.order = append(st.order, 0)
+	copy(st.order[pos+1:], st.order[pos:])
+	st.order[pos] = id
+}
+
+func (st *state) unindexOrder(id int) {
+	if pos := sort.SearchInts(st.order, id); pos < len(st.order) && st.order[pos] == id {
+		st.order = append(st.order[:pos], st.order[pos+1:]...)
+	}
+}
@@ -0,0 +1,512 @@
+package storage
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"Goworkspace/Project/domain"
+)
+
+// state - данные хранилища и их индексы без синхронизации.
+// MemoryStorage защищает его мьютексом, транзакция работает с копией.
+type state struct {
+	data  map[int]domain.Item
+	names map[string]int // индекс уникальности: имя -> ID
+	order []int          // ID по возрастанию, для постраничной выборки
+	next  int
+
+	sortedNames []string // имена по возрастанию, для поиска по префиксу
+
+	tags map[string]map[int]struct{} // инвертированный индекс: тег -> ID живых элементов
+
+	children map[int]map[int]struct{} // иерархия: ID родителя -> ID живых детей
+
+	trash map[int]domain.DeletedItem // удалённые элементы до окончательной очистки
+
+	expiry  expiryHeap    // сроки жизни элементов, ближайший - первый
+	expired []domain.Item // истёкшие и уже удалённые элементы до ReapExpired
+
+	revision  int64           // ревизия последней записи
+	changes   []domain.Change // журнал: ревизии compacted+1..revision подряд
+	compacted int64           // последняя ревизия, удалённая из журнала
+}
+
+func newState() *state {
+	return &state{
+		data:  make(map[int]domain.Item),
+		names: make(map[string]int),
+		trash: make(map[int]domain.DeletedItem),
+		tags:  make(map[string]map[int]struct{}),
+		next:  1,
+
+		children: make(map[int]map[int]struct{}),
+	}
+}
+
+// clone делает независимую копию для транзакции.
+// Элементы хранятся по значению и не изменяются на месте (карты атрибутов и срезы тегов тоже),
+// поэтому достаточно копий карт верхнего уровня и множеств индексов тегов и детей.
+func (st *state) clone() *state {
+	cp := &state{
+		data:        make(map[int]domain.Item, len(st.data)),
+		names:       make(map[string]int, len(st.names)),
+		order:       append([]int(nil), st.order...),
+		next:        st.next,
+		sortedNames: append([]string(nil), st.sortedNames...),
+		trash:       make(map[int]domain.DeletedItem, len(st.trash)),
+		tags:        make(map[string]map[int]struct{}, len(st.tags)),
+		children:    make(map[int]map[int]struct{}, len(st.children)),
+		expiry:      slices.Clone(st.expiry),
+		expired:     st.expired[:len(st.expired):len(st.expired)],
+		revision:    st.revision,
+		changes:     st.changes[:len(st.changes):len(st.changes)], // append в копии не затронет оригинал
+		compacted:   st.compacted,
+	}
+	for id, item := range st.data {
+		cp.data[id] = item
+	}
+	for name, id := range st.names {
+		cp.names[name] = id
+	}
+	for id, deleted := range st.trash {
+		cp.trash[id] = deleted
+	}
+	for tag, ids := range st.tags {
+		cp.tags[tag] = maps.Clone(ids) // множества изменяются на месте, их копируем
+	}
+	for parent, ids := range st.children {
+		cp.children[parent] = maps.Clone(ids)
+	}
+	return cp
+}
+
+func (st *state) createItem(item domain.Item) (domain.Item, error) {
+	if id, ok := st.names[item.Name]; ok {
+		return domain.Item{}, &domain.AlreadyExistsError{ID: id}
+	}
+	if err := st.checkParent(item); err != nil {
+		return domain.Item{}, err
+	}
+
+	st.insert(&item)
+
+	return detach(item), nil
+}
+
+// detach отвязывает атрибуты и теги элемента от хранилища: снаружи их можно менять
+// без последствий для сохранённых данных.
+func detach(item domain.Item) domain.Item {
+	item.Attributes = maps.Clone(item.Attributes)
+	item.Tags = slices.Clone(item.Tags)
+	return item
+}
+
+// insert выдаёт элементу новый ID и добавляет его во все индексы.
+func (st *state) insert(item *domain.Item) {
+	item.ID = st.next
+	item.Version = 1
+	item.Attributes = maps.Clone(item.Attributes)
+	item.Tags = slices.Clone(item.Tags)
+	st.next++
+	st.data[item.ID] = *item
+	st.indexName(item.Name, item.ID)
+	st.indexTags(item.Tags, item.ID)
+	st.linkParent(item.ID, item.ParentID)
+	st.scheduleExpiry(*item)
+	st.order = append(st.order, item.ID) // next растёт монотонно, порядок сохраняется
+	st.record(domain.EventItemCreated, *item, item.CreatedAt)
+}
+
+// record выдаёт записи следующую ревизию и добавляет её в журнал.
+// item - сохранённое состояние; у удаления - только ID.
+func (st *state) record(typ domain.EventType, item domain.Item, at time.Time) {
+	st.revision++
+	st.changes = append(st.changes, domain.Change{Revision: st.revision, Type: typ, ItemID: item.ID, Item: item, At: at})
+}
+
+func (st *state) getItem(id int, now time.Time) (domain.Item, error) {
+	item, ok := st.data[id]
+
+	if !ok || expired(item, now) {
+		return domain.Item{}, domain.ErrNotFound
+	}
+
+	return detach(item), nil
+}
+
+// updateItem возвращает новое и прежнее состояние элемента.
+func (st *state) updateItem(item domain.Item) (domain.Item, domain.Item, error) {
+	old, ok := st.data[item.ID]
+	if !ok {
+		return domain.Item{}, domain.Item{}, domain.ErrNotFound
+	}
+
+	if item.Version != 0 && item.Version != old.Version {
+		return domain.Item{}, domain.Item{}, domain.ErrVersionConflict
+	}
+
+	if id, ok := st.names[item.Name]; ok && id != item.ID {
+		return domain.Item{}, domain.Item{}, &domain.AlreadyExistsError{ID: id}
+	}
+
+	item.ExpiresAt = old.ExpiresAt // срок жизни задаётся только при создании
+	if item.ParentID != old.ParentID {
+		if err := st.checkParent(item); err != nil {
+			return domain.Item{}, domain.Item{}, err
+		}
+		st.unlinkParent(item.ID, old.ParentID)
+		st.linkParent(item.ID, item.ParentID)
+	}
+
+	item.Version = old.Version + 1
+	item.CreatedAt = old.CreatedAt
+	item.Attributes = maps.Clone(item.Attributes)
+	item.Tags = old.Tags // теги меняются только через addTag/removeTag
+
+	st.unindexName(old.Name)
+	st.data[item.ID] = item
+	st.indexName(item.Name, item.ID)
+	st.record(domain.EventItemUpdated, item, item.UpdatedAt)
+
+	return detach(item), detach(old), nil
+}
+
+// deleteItem возвращает удалённый элемент.
+func (st *state) deleteItem(id, version int, at time.Time) (domain.Item, error) {
+	item, ok := st.data[id]
+	if !ok {
+		return domain.Item{}, domain.ErrNotFound
+	}
+
+	if version != 0 && version != item.Version {
+		return domain.Item{}, domain.ErrVersionConflict
+	}
+	if len(st.children[id]) > 0 {
+		return domain.Item{}, domain.ErrHasChildren
+	}
+
+	st.softDelete(item, at)
+
+	return detach(item), nil
+}
+
+// softDelete переносит элемент в корзину и освобождает его имя. Детей у элемента быть не должно.
+func (st *state) softDelete(item domain.Item, at time.Time) {
+	delete(st.data, item.ID)
+	st.unlinkParent(item.ID, item.ParentID)
+	st.unindexName(item.Name)
+	st.unindexOrder(item.ID)
+	st.unindexTags(item.Tags, item.ID)
+	st.trash[item.ID] = domain.DeletedItem{Item: item, DeletedAt: at}
+	st.record(domain.EventItemDeleted, domain.Item{ID: item.ID}, at)
+}
+
+func (st *state) createItems(items []domain.Item) ([]domain.Item, error) {
+	// Сначала проверяем весь пакет, потом пишем: ошибка не должна оставить часть элементов
+	var batchErr domain.BatchError
+	seen := make(map[string]int, len(items))
+	for i, item := range items {
+		if id, ok := st.names[item.Name]; ok {
+			batchErr.Errors = append(batchErr.Errors, domain.IndexError{Index: i, Err: &domain.AlreadyExistsError{ID: id}})
+			continue
+		}
+		if first, ok := seen[item.Name]; ok {
+			batchErr.Errors = append(batchErr.Errors, domain.IndexError{Index: i, Err: fmt.Errorf("%w: same as index %d", domain.ErrDuplicateInBatch, first)})
+			continue
+		}
+		if err := st.checkParent(item); err != nil {
+			batchErr.Errors = append(batchErr.Errors, domain.IndexError{Index: i, Err: err})
+			continue
+		}
+		seen[item.Name] = i
+	}
+	if len(batchErr.Errors) > 0 {
+		return nil, &batchErr
+	}
+
+	created := make([]domain.Item, 0, len(items))
+	for _, item := range items {
+		st.insert(&item)
+		created = append(created, detach(item))
+	}
+
+	return created, nil
+}
+
+// deleteItems удаляет элементы по списку или фильтру. Элемент с детьми, которые не удаляются
+// вместе с ним, остаётся со статусом has_children. Результаты - в порядке запроса или по возрастанию ID.
+func (st *state) deleteItems(req domain.BulkDelete, at time.Time) []domain.DeleteResult {
+	results, order := st.planDelete(req, at)
+	if !req.DryRun {
+		for _, id := range order {
+			st.softDelete(st.data[id], at)
+		}
+	}
+	return results
+}
+
+// planDelete ничего не меняет: возвращает итоги удаления и порядок, в котором элементы
+// нужно удалять. Истёкшие к now элементы считаются уже удалёнными.
+func (st *state) planDelete(req domain.BulkDelete, now time.Time) ([]domain.DeleteResult, []int) {
+	ids := req.IDs
+	if len(ids) == 0 {
+		for _, item := range st.findItems(0, 0, domain.ListOptions{Name: req.Name}, now) {
+			ids = append(ids, item.ID)
+		}
+	}
+
+	order := st.leavesFirst(ids, now)
+	deleted := make(map[int]bool, len(order))
+	for _, id := range order {
+		deleted[id] = true
+	}
+
+	results := make([]domain.DeleteResult, 0, len(ids))
+	for _, id := range ids {
+		status := domain.DeleteStatusNotFound
+		if deleted[id] {
+			status = domain.DeleteStatusDeleted
+		} else if item, ok := st.data[id]; ok && !expired(item, now) {
+			status = domain.DeleteStatusHasChildren
+		}
+		results = append(results, domain.DeleteResult{ID: id, Status: status})
+	}
+
+	return results, order
+}
+
+// findItems отбирает элементы с ID > afterID по фильтрам opts, по возрастанию ID,
+// пропуская истёкшие к now. limit <= 0 - без ограничения.
+func (st *state) findItems(afterID, limit int, opts domain.ListOptions, now time.Time) []domain.Item {
+	filter := opts.Name
+
+	// Кандидаты по возрастанию ID: из индекса имён или тегов, если фильтр позволяет, иначе все
+	candidates := st.order
+	switch {
+	case filter.Exact != "":
+		candidates = nil
+		if id, ok := st.names[filter.Exact]; ok {
+			candidates = []int{id}
+		}
+	case filter.Prefix != "":
+		candidates = st.idsByPrefix(filter.Prefix)
+	case len(opts.Tags) > 0:
+		candidates = st.idsByTag(opts.Tags)
+	case opts.Parent != 0:
+		candidates = st.childIDs(opts.Parent)
+	}
+
+	contains := strings.ToLower(filter.Contains)
+
+	items := make([]domain.Item, 0, max(limit, 0))
+	for pos := sort.SearchInts(candidates, afterID+1); pos < len(candidates) && (limit <= 0 || len(items) < limit); pos++ {
+		item := st.data[candidates[pos]]
+		if expired(item, now) {
+			continue
+		}
+		if contains != "" && !strings.Contains(strings.ToLower(item.Name), contains) {
+			continue
+		}
+		if opts.Parent != 0 && item.ParentID != opts.Parent {
+			continue
+		}
+		if !hasAttributes(item, opts.Attributes) || !hasTags(item, opts.Tags) {
+			continue
+		}
+		items = append(items, detach(item))
+	}
+
+	return items
+}
+
+// hasAttributes сообщает, содержит ли элемент все пары из attrs.
+func hasAttributes(item domain.Item, attrs map[string]string) bool {
+	for key, value := range attrs {
+		if got, ok := item.Attributes[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTags сообщает, есть ли у элемента все теги из tags.
+func hasTags(item domain.Item, tags []string) bool {
+	for _, tag := range tags {
+		if _, ok := slices.BinarySearch(item.Tags, tag); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// addTag и removeTag возвращают новое и прежнее состояние элемента.
+func (st *state) addTag(id, version int, tag string, at time.Time) (domain.Item, domain.Item, error) {
+	item, ok := st.data[id]
+	if !ok {
+		return domain.Item{}, domain.Item{}, domain.ErrNotFound
+	}
+	if version != 0 && version != item.Version {
+		return domain.Item{}, domain.Item{}, domain.ErrVersionConflict
+	}
+	old := item
+
+	pos, found := slices.BinarySearch(item.Tags, tag)
+	if found {
+		return detach(item), detach(old), nil
+	}
+	if len(item.Tags) >= domain.MaxTagsPerItem {
+		return domain.Item{}, domain.Item{}, domain.NewValidationError("tag", domain.CodeTooMany, fmt.Sprintf("item already has %d tags", len(item.Tags)), domain.ErrInvalidValue)
+	}
+
+	item.Tags = slices.Insert(slices.Clone(item.Tags), pos, tag)
+	item.Version++
+	item.UpdatedAt = at
+
+	st.data[id] = item
+	st.indexTags([]string{tag}, id)
+	st.record(domain.EventItemUpdated, item, at)
+
+	return detach(item), detach(old), nil
+}
+
+func (st *state) removeTag(id, version int, tag string, at time.Time) (domain.Item, domain.Item, error) {
+	item, ok := st.data[id]
+	if !ok {
+		return domain.Item{}, domain.Item{}, domain.ErrNotFound
+	}
+	if version != 0 && version != item.Version {
+		return domain.Item{}, domain.Item{}, domain.ErrVersionConflict
+	}
+	old := item
+
+	pos, found := slices.BinarySearch(item.Tags, tag)
+	if !found {
+		return domain.Item{}, domain.Item{}, domain.ErrNotFound
+	}
+
+	item.Tags = slices.Delete(slices.Clone(item.Tags), pos, pos+1)
+	if len(item.Tags) == 0 {
+		item.Tags = nil
+	}
+	item.Version++
+	item.UpdatedAt = at
+
+	st.data[id] = item
+	st.unindexTags([]string{tag}, id)
+	st.record(domain.EventItemUpdated, item, at)
+
+	return detach(item), detach(old), nil
+}
+
+func (st *state) listTags(now time.Time) []domain.TagCount {
+	tags := make([]domain.TagCount, 0, len(st.tags))
+	for tag, ids := range st.tags {
+		count := 0
+		for id := range ids {
+			if !expired(st.data[id], now) {
+				count++
+			}
+		}
+		if count > 0 {
+			tags = append(tags, domain.TagCount{Tag: tag, Count: count})
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+
+	return tags
+}
+
+func (st *state) listDeleted() []domain.DeletedItem {
+	items := make([]domain.DeletedItem, 0, len(st.trash))
+	for _, deleted := range st.trash {
+		deleted.Item = detach(deleted.Item)
+		items = append(items, deleted)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+
+	return items
+}
+
+func (st *state) restoreItem(id int, at time.Time) (domain.Item, error) {
+	deleted, ok := st.trash[id]
+	if !ok || expired(deleted.Item, at) {
+		return domain.Item{}, domain.ErrNotFound // истёкший элемент восстановить нельзя
+	}
+
+	// Пока элемент лежал в корзине, его имя мог занять другой элемент
+	if existingID, ok := st.names[deleted.Name]; ok {
+		return domain.Item{}, &domain.AlreadyExistsError{ID: existingID}
+	}
+	if _, ok := st.data[deleted.ParentID]; deleted.ParentID != 0 && !ok {
+		return domain.Item{}, domain.ErrParentDeleted
+	}
+
+	item := deleted.Item
+	item.Version++
+	item.UpdatedAt = at
+
+	delete(st.trash, id)
+	st.data[id] = item
+	st.indexName(item.Name, id)
+	st.indexOrder(id)
+	st.indexTags(item.Tags, id)
+	st.linkParent(id, item.ParentID)
+	st.scheduleExpiry(item) // запись в куче могла уйти, пока элемент лежал в корзине
+	st.record(domain.EventItemCreated, item, at)
+
+	return detach(item), nil
+}
+
+func (st *state) purgeDeleted(before time.Time) int {
+	purged := 0
+	for id, deleted := range st.trash {
+		if deleted.DeletedAt.Before(before) {
+			delete(st.trash, id)
+			purged++
+		}
+	}
+
+	return purged
+}
+
+// listChanges возвращает до limit изменений после since (limit <= 0 - все) и текущую ревизию.
+func (st *state) listChanges(since int64, limit int) ([]domain.Change, int64, error) {
+	if since < st.compacted {
+		return nil, st.revision, domain.ErrRevisionCompacted
+	}
+	if since > st.revision {
+		return nil, st.revision, domain.NewValidationError("since", domain.CodeOutOfRange, fmt.Sprintf("must not be greater than the current revision %d", st.revision), domain.ErrInvalidValue)
+	}
+
+	pending := st.changes[since-st.compacted:]
+	if limit > 0 && limit < len(pending) {
+		pending = pending[:limit]
+	}
+	changes := make([]domain.Change, 0, len(pending))
+	for _, change := range pending {
+		change.Item = detach(change.Item)
+		changes = append(changes, change)
+	}
+
+	return changes, st.revision, nil
+}
+
+// compactChanges удаляет начало журнала до первого изменения не раньше before.
+func (st *state) compactChanges(before time.Time) int {
+	n := 0
+	for n < len(st.changes) && st.changes[n].At.Before(before) {
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+
+	st.compacted = st.changes[n-1].Revision
+	st.changes = slices.Clone(st.changes[n:]) // освобождаем память удалённых записей
+
+	return n
+}
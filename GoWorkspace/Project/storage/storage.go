@@ -2,21 +2,68 @@ package storage
 
 import (
 	"context"
+	"slices"
 	"sync"
+	"time"
 
 	"Goworkspace/Project/domain"
 )
 
+// MemoryStorage хранит данные каждого арендатора в отдельном state:
+// ID, имена, индексы, корзина и журнал изменений не пересекаются.
 type MemoryStorage struct {
-	mu   sync.RWMutex
-	data map[int]domain.Item
-	next int
+	mu      sync.RWMutex
+	tenants map[string]*state
+	clock   domain.Clock
 }
 
-func NewMemoryStorage() *MemoryStorage {
-	return &MemoryStorage{
-		data: make(map[int]domain.Item),
-		next: 1,
+// NewMemoryStorage создаёт хранилище. По clock истекает срок жизни элементов;
+// это должны быть те же часы, что и у сервиса, поэтому clock обязателен: nil - паника.
+func NewMemoryStorage(clock domain.Clock) *MemoryStorage {
+	if clock == nil {
+		panic("storage: nil clock")
+	}
+	return &MemoryStorage{tenants: make(map[string]*state), clock: clock}
+}
+
+// emptyState отвечает на чтение у арендатора без данных. Не изменяется.
+var emptyState = newState()
+
+// read возвращает данные арендатора из ctx. Вызывается под s.mu.RLock.
+func (s *MemoryStorage) read(ctx context.Context) *state {
+	if st, ok := s.tenants[domain.TenantFromContext(ctx)]; ok {
+		return st
+	}
+	return emptyState
+}
+
+// write возвращает данные арендатора из ctx, создавая их при первой записи.
+// Истёкшие элементы удаляются до записи, чтобы не мешать ей. Вызывается под s.mu.Lock.
+func (s *MemoryStorage) write(ctx context.Context) *state {
+	tenant := domain.TenantFromContext(ctx)
+	st, ok := s.tenants[tenant]
+	if !ok {
+		st = newState()
+		s.tenants[tenant] = st
+	}
+	st.expire(s.clock.Now())
+	return st
+}
+
+func (s *MemoryStorage) Tenants(ctx context.Context) ([]string, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		tenants := make([]string, 0, len(s.tenants))
+		for tenant := range s.tenants {
+			tenants = append(tenants, tenant)
+		}
+		slices.Sort(tenants)
+		return tenants, nil
 	}
 }
 
@@ -28,11 +75,7 @@ func (s *MemoryStorage) CreateItem(ctx context.Context, item domain.Item) (domai
 		s.mu.Lock()
 		defer s.mu.Unlock()
 
-		item.ID = s.next
-		s.next++
-		s.data[item.ID] = item
-
-		return item, nil
+		return s.write(ctx).createItem(item)
 	}
 
 }
@@ -45,31 +88,77 @@ func (s *MemoryStorage) GetItem(ctx context.Context, id int) (domain.Item, error
 		s.mu.RLock()
 		defer s.mu.RUnlock()
 
-		item, ok := s.data[id]
+		return s.read(ctx).getItem(id, s.clock.Now())
+	}
+}
 
-		if !ok {
-			return domain.Item{}, domain.ErrNotFound
-		}
+func (s *MemoryStorage) UpdateItem(ctx context.Context, item domain.Item) (domain.Item, domain.Item, error) {
+	select {
+	case <-ctx.Done():
+		return domain.Item{}, domain.Item{}, ctx.Err()
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
 
-		return item, nil
+		return s.write(ctx).updateItem(item)
 	}
 }
 
-func (s *MemoryStorage) DeleteItem(ctx context.Context, id int) error {
+func (s *MemoryStorage) DeleteItem(ctx context.Context, id, version int, at time.Time) (domain.Item, error) {
 	select {
 	case <-ctx.Done():
-		return ctx.Err()
+		return domain.Item{}, ctx.Err()
 	default:
 		s.mu.Lock()
 		defer s.mu.Unlock()
 
-		if _, ok := s.data[id]; !ok {
-			return domain.ErrNotFound
+		// Мягкое удаление: элемент уходит в корзину, имя освобождается
+		return s.write(ctx).deleteItem(id, version, at)
+	}
+
+}
+
+func (s *MemoryStorage) DeleteItems(ctx context.Context, req domain.BulkDelete, at time.Time) ([]domain.DeleteResult, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		// Пробный запуск только читает: не создаёт данных арендатора и не удаляет истёкшие элементы
+		if req.DryRun {
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+
+			results, _ := s.read(ctx).planDelete(req, s.clock.Now())
+			return results, nil
 		}
 
-		delete(s.data, id)
+		s.mu.Lock()
+		defer s.mu.Unlock()
 
-		return nil
+		return s.write(ctx).deleteItems(req, at), nil
 	}
+}
 
+func (s *MemoryStorage) ListItems(ctx context.Context, opts domain.ListOptions) ([]domain.Item, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		return s.read(ctx).findItems(opts.AfterID, opts.Limit, opts, s.clock.Now()), nil
+	}
+}
+
+func (s *MemoryStorage) CreateItems(ctx context.Context, items []domain.Item) ([]domain.Item, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		return s.write(ctx).createItems(items)
+	}
 }
@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -29,7 +30,7 @@ func TimeoutContext() context.Context {
 func TestStorage_Create(t *testing.T) {
 
 	t.Run("Success return item", func(t *testing.T) {
-		st := storage.NewMemoryStorage()
+		st := storage.NewMemoryStorage(domain.SystemClock{})
 		item := domain.Item{Name: "Alex"}
 
 		resItem, err := st.CreateItem(context.Background(), item)
@@ -42,7 +43,7 @@ func TestStorage_Create(t *testing.T) {
 	})
 
 	t.Run("Increment ID by 1", func(t *testing.T) {
-		st := storage.NewMemoryStorage()
+		st := storage.NewMemoryStorage(domain.SystemClock{})
 		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
 
 		resItem, err := st.CreateItem(context.Background(), domain.Item{Name: "Alice"})
@@ -55,7 +56,7 @@ func TestStorage_Create(t *testing.T) {
 	})
 
 	t.Run("Data save in Memory Storage", func(t *testing.T) {
-		st := storage.NewMemoryStorage()
+		st := storage.NewMemoryStorage(domain.SystemClock{})
 		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
 		st.CreateItem(context.Background(), domain.Item{Name: "Alice"})
 
@@ -69,8 +70,37 @@ func TestStorage_Create(t *testing.T) {
 		}
 	})
 
+	t.Run("Duplicate name returns ErrAlreadyExists", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+
+		_, err := st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+		if !errors.Is(err, domain.ErrAlreadyExists) {
+			t.Fatalf("expected ErrAlreadyExists, got %v", err)
+		}
+
+		var existsErr *domain.AlreadyExistsError
+		if !errors.As(err, &existsErr) || existsErr.ID != 1 {
+			t.Fatalf("expected existing id=1, got %v", err)
+		}
+	})
+
+	t.Run("Name is free again after delete", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+		st.DeleteItem(context.Background(), 1, 0, time.Now())
+
+		resItem, err := st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if resItem.ID != 2 {
+			t.Fatalf("expected item id=2, got: %v", resItem.ID)
+		}
+	})
+
 	t.Run("Context Canceled returns context.Canceled", func(t *testing.T) {
-		st := storage.NewMemoryStorage()
+		st := storage.NewMemoryStorage(domain.SystemClock{})
 		item := domain.Item{Name: "Deril"}
 
 		_, err := st.CreateItem(CanceledContext(), item)
@@ -81,7 +111,7 @@ func TestStorage_Create(t *testing.T) {
 	})
 
 	t.Run("Context timeout returns context.DeadlineExceeded", func(t *testing.T) {
-		st := storage.NewMemoryStorage()
+		st := storage.NewMemoryStorage(domain.SystemClock{})
 		item := domain.Item{Name: "Deril"}
 
 		_, err := st.CreateItem(TimeoutContext(), item)
@@ -94,7 +124,7 @@ func TestStorage_Create(t *testing.T) {
 
 func TestStorage_Get(t *testing.T) {
 	t.Run("Data save in memory storage", func(t *testing.T) {
-		st := storage.NewMemoryStorage()
+		st := storage.NewMemoryStorage(domain.SystemClock{})
 		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
 
 		item, err := st.GetItem(context.Background(), 1)
@@ -107,7 +137,7 @@ func TestStorage_Get(t *testing.T) {
 	})
 
 	t.Run("Returns error ErrNotFound", func(t *testing.T) {
-		st := storage.NewMemoryStorage()
+		st := storage.NewMemoryStorage(domain.SystemClock{})
 
 		_, err := st.GetItem(context.Background(), 1)
 		if !errors.Is(err, domain.ErrNotFound) {
@@ -116,7 +146,7 @@ func TestStorage_Get(t *testing.T) {
 	})
 
 	t.Run("Context Canceled returns context.Canceled", func(t *testing.T) {
-		st := storage.NewMemoryStorage()
+		st := storage.NewMemoryStorage(domain.SystemClock{})
 		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
 
 		_, err := st.GetItem(CanceledContext(), 1)
@@ -127,7 +157,7 @@ func TestStorage_Get(t *testing.T) {
 	})
 
 	t.Run("Context timeout returns context.DeadlineExceeded", func(t *testing.T) {
-		st := storage.NewMemoryStorage()
+		st := storage.NewMemoryStorage(domain.SystemClock{})
 		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
 		_, err := st.GetItem(TimeoutContext(), 1)
 
@@ -137,31 +167,125 @@ func TestStorage_Get(t *testing.T) {
 	})
 }
 
+func TestStorage_Update(t *testing.T) {
+	t.Run("Success update keeps ID", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+
+		resItem, _, err := st.UpdateItem(context.Background(), domain.Item{ID: 1, Name: "Alice"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if resItem.ID != 1 || resItem.Name != "Alice" {
+			t.Fatalf("expected item id= 1, name: Alice; got: id: %v, name: %v", resItem.ID, resItem.Name)
+		}
+
+		item, _ := st.GetItem(context.Background(), 1)
+		if item.Name != "Alice" {
+			t.Fatalf("expected stored name Alice, got: %v", item.Name)
+		}
+	})
+
+	t.Run("Returns previous state", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex", Attributes: map[string]string{"k": "v"}})
+
+		_, before, err := st.UpdateItem(context.Background(), domain.Item{ID: 1, Name: "Alice", Attributes: map[string]string{"k": "w"}})
+		if err != nil || before.Name != "Alex" || before.Version != 1 || before.Attributes["k"] != "v" {
+			t.Fatalf("expected previous state, got: %+v, %v", before, err)
+		}
+	})
+
+	t.Run("CreatedAt is preserved", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex", CreatedAt: created, UpdatedAt: created})
+
+		updatedAt := created.Add(time.Hour)
+		resItem, _, _ := st.UpdateItem(context.Background(), domain.Item{ID: 1, Name: "Alice", UpdatedAt: updatedAt})
+		if !resItem.CreatedAt.Equal(created) || !resItem.UpdatedAt.Equal(updatedAt) {
+			t.Fatalf("unexpected timestamps: %+v", resItem)
+		}
+	})
+
+	t.Run("Old name is released", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+		st.UpdateItem(context.Background(), domain.Item{ID: 1, Name: "Alice"})
+
+		if _, err := st.CreateItem(context.Background(), domain.Item{Name: "Alex"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Same name is allowed for the same item", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+
+		if _, _, err := st.UpdateItem(context.Background(), domain.Item{ID: 1, Name: "Alex"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Name of another item returns ErrAlreadyExists", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alice"})
+
+		_, _, err := st.UpdateItem(context.Background(), domain.Item{ID: 2, Name: "Alex"})
+		if !errors.Is(err, domain.ErrAlreadyExists) {
+			t.Fatalf("expected ErrAlreadyExists, got: %v", err)
+		}
+	})
+
+	t.Run("Returns error ErrNotFound", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+
+		_, _, err := st.UpdateItem(context.Background(), domain.Item{ID: 1, Name: "Alex"})
+		if !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected error ErrNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("Context Canceled returns context.Canceled", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+
+		_, _, err := st.UpdateItem(CanceledContext(), domain.Item{ID: 1, Name: "Alice"})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
 func TestStorage_Delete(t *testing.T) {
 	t.Run("Success delete", func(t *testing.T) {
-		st := storage.NewMemoryStorage()
+		st := storage.NewMemoryStorage(domain.SystemClock{})
 		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
 
-		st.DeleteItem(context.Background(), 1)
-		_, err := st.GetItem(context.Background(), 1)
+		deleted, err := st.DeleteItem(context.Background(), 1, 0, time.Now())
+		if err != nil || deleted.ID != 1 || deleted.Name != "Alex" {
+			t.Fatalf("expected deleted item, got: %+v, %v", deleted, err)
+		}
+		_, err = st.GetItem(context.Background(), 1)
 		if !errors.Is(err, domain.ErrNotFound) {
 			t.Fatalf("expected ErrNotFound, got error: %v", err)
 		}
 	})
 
 	t.Run("Returns error ErrNotFound", func(t *testing.T) {
-		st := storage.NewMemoryStorage()
+		st := storage.NewMemoryStorage(domain.SystemClock{})
 
-		err := st.DeleteItem(context.Background(), 1)
+		_, err := st.DeleteItem(context.Background(), 1, 0, time.Now())
 		if !errors.Is(err, domain.ErrNotFound) {
 			t.Fatalf("expected error ErrNotFound, got: %v", err)
 		}
 	})
 
 	t.Run("Context Canceled returns context.Canceled", func(t *testing.T) {
-		st := storage.NewMemoryStorage()
+		st := storage.NewMemoryStorage(domain.SystemClock{})
 
-		err := st.DeleteItem(CanceledContext(), 1)
+		_, err := st.DeleteItem(CanceledContext(), 1, 0, time.Now())
 
 		if !errors.Is(err, context.Canceled) {
 			t.Fatalf("expected context.Canceled, got %v", err)
@@ -169,9 +293,9 @@ func TestStorage_Delete(t *testing.T) {
 	})
 
 	t.Run("Context timeout returns context.DeadlineExceeded", func(t *testing.T) {
-		st := storage.NewMemoryStorage()
+		st := storage.NewMemoryStorage(domain.SystemClock{})
 
-		err := st.DeleteItem(TimeoutContext(), 1)
+		_, err := st.DeleteItem(TimeoutContext(), 1, 0, time.Now())
 
 		if !errors.Is(err, context.DeadlineExceeded) {
 			t.Fatalf("expected context.Canceled, got %v", err)
@@ -179,8 +303,94 @@ func TestStorage_Delete(t *testing.T) {
 	})
 }
 
+func TestStorage_Trash(t *testing.T) {
+	t.Run("Deleted item goes to trash", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+		st.DeleteItem(context.Background(), 1, 0, time.Now())
+
+		items, err := st.ListDeleted(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(items) != 1 || items[0].ID != 1 || items[0].Name != "Alex" || items[0].DeletedAt.IsZero() {
+			t.Fatalf("expected Alex in trash, got: %+v", items)
+		}
+
+		list, _ := st.ListItems(context.Background(), domain.ListOptions{Limit: 10})
+		if len(list) != 0 {
+			t.Fatalf("deleted item must be hidden from list, got: %+v", list)
+		}
+	})
+
+	t.Run("Restore returns item with its ID", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alice"})
+		st.DeleteItem(context.Background(), 1, 0, time.Now())
+
+		item, err := st.RestoreItem(context.Background(), 1, time.Now())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if item.ID != 1 || item.Name != "Alex" || item.Version != 2 {
+			t.Fatalf("unexpected restored item: %+v", item)
+		}
+
+		list, _ := st.ListItems(context.Background(), domain.ListOptions{Limit: 10})
+		if len(list) != 2 || list[0].ID != 1 || list[1].ID != 2 {
+			t.Fatalf("restored item must be back in order, got: %+v", list)
+		}
+		if trash, _ := st.ListDeleted(context.Background()); len(trash) != 0 {
+			t.Fatalf("trash must be empty, got: %+v", trash)
+		}
+	})
+
+	t.Run("Restore with taken name returns ErrAlreadyExists", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+		st.DeleteItem(context.Background(), 1, 0, time.Now())
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+
+		_, err := st.RestoreItem(context.Background(), 1, time.Now())
+		if !errors.Is(err, domain.ErrAlreadyExists) {
+			t.Fatalf("expected ErrAlreadyExists, got: %v", err)
+		}
+	})
+
+	t.Run("Restore unknown item returns ErrNotFound", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+
+		_, err := st.RestoreItem(context.Background(), 1, time.Now())
+		if !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("Purge removes only old tombstones", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+		st.DeleteItem(context.Background(), 1, 0, time.Now())
+
+		purged, err := st.PurgeDeleted(context.Background(), time.Now().Add(-time.Hour))
+		if err != nil || purged != 0 {
+			t.Fatalf("expected nothing purged, got: %d, %v", purged, err)
+		}
+
+		purged, err = st.PurgeDeleted(context.Background(), time.Now().Add(time.Second))
+		if err != nil || purged != 1 {
+			t.Fatalf("expected 1 purged, got: %d, %v", purged, err)
+		}
+
+		if _, err := st.RestoreItem(context.Background(), 1, time.Now()); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("purged item must not be restorable, got: %v", err)
+		}
+	})
+}
+
 func TestStorage_ConcurrentCRUD(t *testing.T) {
-	st := storage.NewMemoryStorage()
+	st := storage.NewMemoryStorage(domain.SystemClock{})
 	const n = 1000
 
 	// Этап 1: CREATE
@@ -220,17 +430,1159 @@ func TestStorage_ConcurrentCRUD(t *testing.T) {
 		}(i)
 	}
 
+	wg.Wait() // GET должен увидеть все элементы до начала удаления
+
 	// Этап 3: DELETE
 	wg.Add(n)
 	for i := 0; i < n; i++ {
 		go func(i int) {
 			defer wg.Done()
-			err := st.DeleteItem(context.Background(), ids[i])
+			_, err := st.DeleteItem(context.Background(), ids[i], 0, time.Now())
 			if err != nil {
 				t.Errorf("Delete error for id=%d: %v", ids[i], err)
 			}
 		}(i)
 	}
 
-	wg.Wait() // ждём завершения GET и DELETE
+	wg.Wait() // ждём завершения DELETE
+}
+
+func TestStorage_ConcurrentDuplicateCreate(t *testing.T) {
+	st := storage.NewMemoryStorage(domain.SystemClock{})
+	const n = 100
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+		exists  int
+	)
+	wg.Add(n)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			_, err := st.CreateItem(context.Background(), domain.Item{Name: "same"})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				created++
+			case errors.Is(err, domain.ErrAlreadyExists):
+				exists++
+			default:
+				t.Errorf("Create error: %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if created != 1 || exists != n-1 {
+		t.Fatalf("expected 1 created and %d duplicates, got: %d created, %d duplicates", n-1, created, exists)
+	}
+}
+
+func TestStorage_List(t *testing.T) {
+	t.Run("Returns items ordered by ID", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		for i := 1; i <= 5; i++ {
+			st.CreateItem(context.Background(), domain.Item{Name: fmt.Sprintf("item-%d", i)})
+		}
+		st.DeleteItem(context.Background(), 3, 0, time.Now())
+
+		items, err := st.ListItems(context.Background(), domain.ListOptions{AfterID: 1, Limit: 10})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := []int{2, 4, 5}
+		if len(items) != len(expected) {
+			t.Fatalf("expected %d items, got: %+v", len(expected), items)
+		}
+		for i, id := range expected {
+			if items[i].ID != id {
+				t.Fatalf("expected id=%d at position %d, got: %d", id, i, items[i].ID)
+			}
+		}
+	})
+
+	t.Run("Respects limit", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		for i := 1; i <= 5; i++ {
+			st.CreateItem(context.Background(), domain.Item{Name: fmt.Sprintf("item-%d", i)})
+		}
+
+		items, _ := st.ListItems(context.Background(), domain.ListOptions{Limit: 2})
+		if len(items) != 2 || items[0].ID != 1 || items[1].ID != 2 {
+			t.Fatalf("expected ids 1,2; got: %+v", items)
+		}
+	})
+
+	t.Run("Empty storage returns empty slice", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+
+		items, err := st.ListItems(context.Background(), domain.ListOptions{Limit: 10})
+		if err != nil || items == nil || len(items) != 0 {
+			t.Fatalf("expected empty slice, got: %v, %v", items, err)
+		}
+	})
+
+	t.Run("Context Canceled returns context.Canceled", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+
+		_, err := st.ListItems(CanceledContext(), domain.ListOptions{Limit: 10})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestStorage_ListFilter(t *testing.T) {
+	newStorage := func() *storage.MemoryStorage {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		for _, name := range []string{"Alex", "alice", "Bob", "Alexander", "ALEXA", "Al"} {
+			st.CreateItem(context.Background(), domain.Item{Name: name})
+		}
+		return st
+	}
+
+	names := func(items []domain.Item) []string {
+		res := make([]string, 0, len(items))
+		for _, item := range items {
+			res = append(res, item.Name)
+		}
+		return res
+	}
+
+	cases := []struct {
+		title    string
+		opts     domain.ListOptions
+		expected []string
+	}{
+		{"Exact match", domain.ListOptions{Name: domain.NameFilter{Exact: "Alex"}}, []string{"Alex"}},
+		{"Exact miss", domain.ListOptions{Name: domain.NameFilter{Exact: "alex"}}, []string{}},
+		{"Prefix is case sensitive", domain.ListOptions{Name: domain.NameFilter{Prefix: "Al"}}, []string{"Alex", "Alexander", "Al"}},
+		{"Contains is case insensitive", domain.ListOptions{Name: domain.NameFilter{Contains: "lex"}}, []string{"Alex", "Alexander", "ALEXA"}},
+		{"Prefix and contains", domain.ListOptions{Name: domain.NameFilter{Prefix: "Al", Contains: "and"}}, []string{"Alexander"}},
+		{"Prefix after cursor", domain.ListOptions{AfterID: 1, Name: domain.NameFilter{Prefix: "Al"}}, []string{"Alexander", "Al"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			st := newStorage()
+			tc.opts.Limit = 10
+
+			items, err := st.ListItems(context.Background(), tc.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := names(items); fmt.Sprint(got) != fmt.Sprint(tc.expected) {
+				t.Fatalf("expected %v, got: %v", tc.expected, got)
+			}
+		})
+	}
+
+	t.Run("Prefix index follows updates and deletes", func(t *testing.T) {
+		st := newStorage()
+		st.UpdateItem(context.Background(), domain.Item{ID: 3, Name: "Alfred"})
+		st.DeleteItem(context.Background(), 4, 0, time.Now())
+
+		items, _ := st.ListItems(context.Background(), domain.ListOptions{Limit: 10, Name: domain.NameFilter{Prefix: "Al"}})
+		if got := names(items); fmt.Sprint(got) != fmt.Sprint([]string{"Alex", "Alfred", "Al"}) {
+			t.Fatalf("unexpected result: %v", got)
+		}
+	})
+}
+
+func TestStorage_Version(t *testing.T) {
+	t.Run("Version starts at 1 and grows on update", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+
+		item, _ := st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+		if item.Version != 1 {
+			t.Fatalf("expected version 1, got: %d", item.Version)
+		}
+
+		item, _, _ = st.UpdateItem(context.Background(), domain.Item{ID: 1, Name: "Alice"})
+		item, _, _ = st.UpdateItem(context.Background(), domain.Item{ID: 1, Name: "Alice"})
+		if item.Version != 3 {
+			t.Fatalf("expected version 3, got: %d", item.Version)
+		}
+	})
+
+	t.Run("Update with stale version returns ErrVersionConflict", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+		st.UpdateItem(context.Background(), domain.Item{ID: 1, Name: "Alice", Version: 1})
+
+		_, _, err := st.UpdateItem(context.Background(), domain.Item{ID: 1, Name: "Bob", Version: 1})
+		if !errors.Is(err, domain.ErrVersionConflict) {
+			t.Fatalf("expected ErrVersionConflict, got: %v", err)
+		}
+
+		item, _ := st.GetItem(context.Background(), 1)
+		if item.Name != "Alice" {
+			t.Fatalf("item must not change on conflict, got: %v", item.Name)
+		}
+	})
+
+	t.Run("Delete with stale version returns ErrVersionConflict", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+		st.UpdateItem(context.Background(), domain.Item{ID: 1, Name: "Alice"})
+
+		if _, err := st.DeleteItem(context.Background(), 1, 1, time.Now()); !errors.Is(err, domain.ErrVersionConflict) {
+			t.Fatalf("expected ErrVersionConflict, got: %v", err)
+		}
+		if _, err := st.DeleteItem(context.Background(), 1, 2, time.Now()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Concurrent updates with same version: exactly one wins", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "Alex"})
+		const n = 50
+
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			succeeded int
+		)
+		wg.Add(n)
+		for i := 0; i < n; i++ {
+			go func(i int) {
+				defer wg.Done()
+				_, _, err := st.UpdateItem(context.Background(), domain.Item{ID: 1, Name: fmt.Sprintf("name-%d", i), Version: 1})
+				if err == nil {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				} else if !errors.Is(err, domain.ErrVersionConflict) {
+					t.Errorf("Update error: %v", err)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		if succeeded != 1 {
+			t.Fatalf("expected exactly one successful update, got: %d", succeeded)
+		}
+	})
+}
+
+func TestStorage_CreateItems(t *testing.T) {
+	t.Run("Creates all items with sequential IDs", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "first"})
+
+		items, err := st.CreateItems(context.Background(), []domain.Item{{Name: "a"}, {Name: "b"}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(items) != 2 || items[0].ID != 2 || items[1].ID != 3 || items[1].Version != 1 {
+			t.Fatalf("unexpected items: %+v", items)
+		}
+
+		list, _ := st.ListItems(context.Background(), domain.ListOptions{Limit: 10})
+		if len(list) != 3 {
+			t.Fatalf("expected 3 stored items, got: %+v", list)
+		}
+	})
+
+	t.Run("Conflict rejects the whole batch", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "taken"})
+
+		_, err := st.CreateItems(context.Background(), []domain.Item{{Name: "a"}, {Name: "taken"}, {Name: "b"}, {Name: "a"}})
+
+		var batchErr *domain.BatchError
+		if !errors.As(err, &batchErr) || len(batchErr.Errors) != 2 {
+			t.Fatalf("expected BatchError with 2 entries, got: %v", err)
+		}
+		if batchErr.Errors[0].Index != 1 || !errors.Is(batchErr.Errors[0].Err, domain.ErrAlreadyExists) {
+			t.Fatalf("unexpected first error: %+v", batchErr.Errors[0])
+		}
+		if batchErr.Errors[1].Index != 3 || !errors.Is(batchErr.Errors[1].Err, domain.ErrDuplicateInBatch) {
+			t.Fatalf("unexpected second error: %+v", batchErr.Errors[1])
+		}
+
+		list, _ := st.ListItems(context.Background(), domain.ListOptions{Limit: 10})
+		if len(list) != 1 {
+			t.Fatalf("nothing must be created on conflict, got: %+v", list)
+		}
+	})
+
+	t.Run("Context Canceled returns context.Canceled", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+
+		_, err := st.CreateItems(CanceledContext(), []domain.Item{{Name: "a"}})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestStorage_DeleteItems(t *testing.T) {
+	newStorage := func() *storage.MemoryStorage {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		for _, name := range []string{"test-1", "keep", "test-2", "TEST-3"} {
+			st.CreateItem(context.Background(), domain.Item{Name: name})
+		}
+		return st
+	}
+
+	t.Run("By IDs reports deleted and not found", func(t *testing.T) {
+		st := newStorage()
+
+		results, err := st.DeleteItems(context.Background(), domain.BulkDelete{IDs: []int{3, 42, 1}}, time.Now())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := []domain.DeleteResult{
+			{ID: 3, Status: domain.DeleteStatusDeleted},
+			{ID: 42, Status: domain.DeleteStatusNotFound},
+			{ID: 1, Status: domain.DeleteStatusDeleted},
+		}
+		if fmt.Sprint(results) != fmt.Sprint(expected) {
+			t.Fatalf("expected %v, got: %v", expected, results)
+		}
+
+		trash, _ := st.ListDeleted(context.Background())
+		if len(trash) != 2 {
+			t.Fatalf("deleted items must go to trash, got: %+v", trash)
+		}
+	})
+
+	t.Run("By filter deletes matching items", func(t *testing.T) {
+		st := newStorage()
+
+		results, err := st.DeleteItems(context.Background(), domain.BulkDelete{Name: domain.NameFilter{Contains: "test"}}, time.Now())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(results) != 3 || results[0].ID != 1 || results[1].ID != 3 || results[2].ID != 4 {
+			t.Fatalf("unexpected results: %v", results)
+		}
+
+		list, _ := st.ListItems(context.Background(), domain.ListOptions{Limit: 10})
+		if len(list) != 1 || list[0].Name != "keep" {
+			t.Fatalf("only keep must remain, got: %+v", list)
+		}
+	})
+
+	t.Run("Dry run changes nothing", func(t *testing.T) {
+		st := newStorage()
+
+		results, _ := st.DeleteItems(context.Background(), domain.BulkDelete{Name: domain.NameFilter{Prefix: "test"}, DryRun: true}, time.Now())
+		if len(results) != 2 {
+			t.Fatalf("expected 2 matches, got: %v", results)
+		}
+
+		list, _ := st.ListItems(context.Background(), domain.ListOptions{Limit: 10})
+		if len(list) != 4 {
+			t.Fatalf("dry run must not delete, got: %+v", list)
+		}
+	})
+
+	t.Run("Dry run does not create tenant data", func(t *testing.T) {
+		st := newStorage()
+		fresh := domain.ContextWithTenant(context.Background(), "fresh")
+
+		results, err := st.DeleteItems(fresh, domain.BulkDelete{IDs: []int{1}, DryRun: true}, time.Now())
+		if err != nil || len(results) != 1 || results[0].Status != domain.DeleteStatusNotFound {
+			t.Fatalf("expected not_found, got: %v, %v", results, err)
+		}
+
+		tenants, _ := st.Tenants(context.Background())
+		if len(tenants) != 1 || tenants[0] != domain.DefaultTenant {
+			t.Fatalf("dry run must not create a tenant, got: %v", tenants)
+		}
+	})
+
+	t.Run("Context Canceled returns context.Canceled", func(t *testing.T) {
+		st := newStorage()
+
+		_, err := st.DeleteItems(CanceledContext(), domain.BulkDelete{IDs: []int{1}}, time.Now())
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestStorage_WithTx(t *testing.T) {
+	errAbort := errors.New("abort")
+
+	t.Run("Commit applies all changes", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+
+		err := st.WithTx(context.Background(), func(tx domain.Storage) error {
+			if _, err := tx.CreateItem(context.Background(), domain.Item{Name: "a"}); err != nil {
+				return err
+			}
+			_, err := tx.CreateItem(context.Background(), domain.Item{Name: "b"})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		list, _ := st.ListItems(context.Background(), domain.ListOptions{Limit: 10})
+		if len(list) != 2 {
+			t.Fatalf("expected 2 items, got: %+v", list)
+		}
+	})
+
+	t.Run("Error rolls back all changes", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "keep"})
+
+		err := st.WithTx(context.Background(), func(tx domain.Storage) error {
+			tx.CreateItem(context.Background(), domain.Item{Name: "new"})
+			tx.UpdateItem(context.Background(), domain.Item{ID: 1, Name: "changed"})
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("expected errAbort, got: %v", err)
+		}
+
+		list, _ := st.ListItems(context.Background(), domain.ListOptions{Limit: 10})
+		if len(list) != 1 || list[0].Name != "keep" || list[0].Version != 1 {
+			t.Fatalf("storage must be untouched, got: %+v", list)
+		}
+
+		// Имя из отменённой транзакции снова свободно
+		if _, err := st.CreateItem(context.Background(), domain.Item{Name: "new"}); err != nil {
+			t.Fatalf("name from rolled back tx must be free, got: %v", err)
+		}
+	})
+
+	t.Run("Panic rolls back and propagates", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected panic to propagate")
+				}
+			}()
+			st.WithTx(context.Background(), func(tx domain.Storage) error {
+				tx.CreateItem(context.Background(), domain.Item{Name: "a"})
+				panic("boom")
+			})
+		}()
+
+		list, _ := st.ListItems(context.Background(), domain.ListOptions{Limit: 10})
+		if len(list) != 0 {
+			t.Fatalf("storage must be untouched, got: %+v", list)
+		}
+
+		// Блокировка должна быть освобождена
+		if _, err := st.CreateItem(context.Background(), domain.Item{Name: "a"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Nested tx acts as savepoint", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+
+		err := st.WithTx(context.Background(), func(tx domain.Storage) error {
+			tx.CreateItem(context.Background(), domain.Item{Name: "outer"})
+
+			nested := tx.WithTx(context.Background(), func(inner domain.Storage) error {
+				inner.CreateItem(context.Background(), domain.Item{Name: "inner"})
+				return errAbort
+			})
+			if !errors.Is(nested, errAbort) {
+				t.Fatalf("expected errAbort from nested tx, got: %v", nested)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		list, _ := st.ListItems(context.Background(), domain.ListOptions{Limit: 10})
+		if len(list) != 1 || list[0].Name != "outer" {
+			t.Fatalf("only outer must be committed, got: %+v", list)
+		}
+	})
+
+	t.Run("Using tx after close returns ErrTxClosed", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+
+		var leaked domain.Storage
+		st.WithTx(context.Background(), func(tx domain.Storage) error {
+			leaked = tx
+			return nil
+		})
+
+		_, err := leaked.CreateItem(context.Background(), domain.Item{Name: "late"})
+		if !errors.Is(err, storage.ErrTxClosed) {
+			t.Fatalf("expected ErrTxClosed, got: %v", err)
+		}
+	})
+
+	t.Run("Concurrent read-modify-write loses no updates", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "counter"})
+
+		const workers = 50
+		var wg sync.WaitGroup
+
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				st.WithTx(context.Background(), func(tx domain.Storage) error {
+					item, err := tx.GetItem(context.Background(), 1)
+					if err != nil {
+						return err
+					}
+					_, _, err = tx.UpdateItem(context.Background(), item)
+					return err
+				})
+			}()
+		}
+		wg.Wait()
+
+		item, _ := st.GetItem(context.Background(), 1)
+		if item.Version != workers+1 {
+			t.Fatalf("expected version %d, got: %d", workers+1, item.Version)
+		}
+	})
+
+	t.Run("Context Canceled returns context.Canceled", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+
+		err := st.WithTx(CanceledContext(), func(tx domain.Storage) error { return nil })
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestStorage_Attributes(t *testing.T) {
+	t.Run("Stored attributes are isolated from callers", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+
+		attrs := map[string]string{"owner": "alice"}
+		created, _ := st.CreateItem(context.Background(), domain.Item{Name: "a", Attributes: attrs})
+		attrs["owner"] = "mallory"
+		created.Attributes["owner"] = "mallory"
+
+		item, _ := st.GetItem(context.Background(), 1)
+		if item.Attributes["owner"] != "alice" {
+			t.Fatalf("stored attributes must not change, got: %+v", item.Attributes)
+		}
+
+		item.Attributes["owner"] = "mallory"
+		again, _ := st.GetItem(context.Background(), 1)
+		if again.Attributes["owner"] != "alice" {
+			t.Fatalf("stored attributes must not change, got: %+v", again.Attributes)
+		}
+	})
+
+	t.Run("List filters by all attributes", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "a", Attributes: map[string]string{"env": "prod", "owner": "alice"}})
+		st.CreateItem(context.Background(), domain.Item{Name: "b", Attributes: map[string]string{"env": "prod"}})
+		st.CreateItem(context.Background(), domain.Item{Name: "c"})
+
+		list, _ := st.ListItems(context.Background(), domain.ListOptions{Limit: 10, Attributes: map[string]string{"env": "prod"}})
+		if len(list) != 2 {
+			t.Fatalf("expected 2 items, got: %+v", list)
+		}
+
+		list, _ = st.ListItems(context.Background(), domain.ListOptions{Limit: 10, Attributes: map[string]string{"env": "prod", "owner": "alice"}})
+		if len(list) != 1 || list[0].Name != "a" {
+			t.Fatalf("expected only a, got: %+v", list)
+		}
+	})
+}
+
+func TestStorage_Tags(t *testing.T) {
+	newStorage := func() *storage.MemoryStorage {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		for _, name := range []string{"a", "b", "c"} {
+			st.CreateItem(context.Background(), domain.Item{Name: name})
+		}
+		st.AddTag(context.Background(), 1, 0, "red", time.Time{})
+		st.AddTag(context.Background(), 1, 0, "big", time.Time{})
+		st.AddTag(context.Background(), 2, 0, "red", time.Time{})
+		return st
+	}
+
+	listTagged := func(st *storage.MemoryStorage, tags ...string) []domain.Item {
+		list, err := st.ListItems(context.Background(), domain.ListOptions{Limit: 10, Tags: tags})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return list
+	}
+
+	t.Run("Add keeps tags sorted and bumps version", func(t *testing.T) {
+		st := newStorage()
+
+		item, _ := st.GetItem(context.Background(), 1)
+		if fmt.Sprint(item.Tags) != "[big red]" || item.Version != 3 {
+			t.Fatalf("unexpected item: %+v", item)
+		}
+
+		again, before, err := st.AddTag(context.Background(), 1, 0, "red", time.Time{})
+		if err != nil || again.Version != 3 || before.Version != 3 {
+			t.Fatalf("adding existing tag must be a no-op, got: %+v, %+v, %v", again, before, err)
+		}
+	})
+
+	t.Run("List by tags uses AND semantics", func(t *testing.T) {
+		st := newStorage()
+
+		if list := listTagged(st, "red"); len(list) != 2 {
+			t.Fatalf("expected 2 items, got: %+v", list)
+		}
+		if list := listTagged(st, "big", "red"); len(list) != 1 || list[0].ID != 1 {
+			t.Fatalf("expected only item 1, got: %+v", list)
+		}
+		if list := listTagged(st, "missing"); len(list) != 0 {
+			t.Fatalf("expected no items, got: %+v", list)
+		}
+	})
+
+	t.Run("Remove updates index and counts", func(t *testing.T) {
+		st := newStorage()
+
+		if _, before, err := st.RemoveTag(context.Background(), 2, 0, "red", time.Time{}); err != nil || fmt.Sprint(before.Tags) != "[red]" {
+			t.Fatalf("expected previous tags [red], got: %+v, %v", before, err)
+		}
+		if _, _, err := st.RemoveTag(context.Background(), 2, 0, "red", time.Time{}); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got: %v", err)
+		}
+
+		tags, _ := st.ListTags(context.Background())
+		expected := []domain.TagCount{{Tag: "big", Count: 1}, {Tag: "red", Count: 1}}
+		if fmt.Sprint(tags) != fmt.Sprint(expected) {
+			t.Fatalf("expected %v, got: %v", expected, tags)
+		}
+	})
+
+	t.Run("Update preserves tags", func(t *testing.T) {
+		st := newStorage()
+
+		item, _, _ := st.UpdateItem(context.Background(), domain.Item{ID: 1, Name: "renamed"})
+		if fmt.Sprint(item.Tags) != "[big red]" {
+			t.Fatalf("update must not drop tags, got: %+v", item)
+		}
+	})
+
+	t.Run("Delete and restore maintain index", func(t *testing.T) {
+		st := newStorage()
+
+		st.DeleteItem(context.Background(), 1, 0, time.Now())
+		if list := listTagged(st, "big"); len(list) != 0 {
+			t.Fatalf("deleted item must leave the index, got: %+v", list)
+		}
+		tags, _ := st.ListTags(context.Background())
+		if len(tags) != 1 || tags[0] != (domain.TagCount{Tag: "red", Count: 1}) {
+			t.Fatalf("unexpected tags: %v", tags)
+		}
+
+		st.RestoreItem(context.Background(), 1, time.Time{})
+		if list := listTagged(st, "big"); len(list) != 1 {
+			t.Fatalf("restored item must return to the index, got: %+v", list)
+		}
+	})
+
+	t.Run("Version mismatch returns ErrVersionConflict", func(t *testing.T) {
+		st := newStorage()
+
+		_, _, err := st.AddTag(context.Background(), 1, 1, "new", time.Time{})
+		if !errors.Is(err, domain.ErrVersionConflict) {
+			t.Fatalf("expected ErrVersionConflict, got: %v", err)
+		}
+	})
+
+	t.Run("Too many tags returns ErrInvalidValue", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(context.Background(), domain.Item{Name: "a"})
+		for i := 0; i < domain.MaxTagsPerItem; i++ {
+			if _, _, err := st.AddTag(context.Background(), 1, 0, fmt.Sprintf("t%d", i), time.Time{}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+
+		_, _, err := st.AddTag(context.Background(), 1, 0, "extra", time.Time{})
+		if !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("expected ErrInvalidValue, got: %v", err)
+		}
+	})
+
+	t.Run("Rolled back tx leaves index untouched", func(t *testing.T) {
+		st := newStorage()
+
+		st.WithTx(context.Background(), func(tx domain.Storage) error {
+			tx.AddTag(context.Background(), 3, 0, "red", time.Time{})
+			return errors.New("abort")
+		})
+
+		if list := listTagged(st, "red"); len(list) != 2 {
+			t.Fatalf("expected 2 items, got: %+v", list)
+		}
+	})
+
+	t.Run("Context Canceled returns context.Canceled", func(t *testing.T) {
+		st := newStorage()
+
+		_, _, err := st.AddTag(CanceledContext(), 1, 0, "x", time.Time{})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestStorage_Changes(t *testing.T) {
+	ctx := context.Background()
+	at := func(minute int) time.Time { return time.Date(2024, 1, 1, 0, minute, 0, 0, time.UTC) }
+
+	changeTypes := func(changes []domain.Change) string {
+		var parts []string
+		for _, c := range changes {
+			parts = append(parts, fmt.Sprintf("%d:%s:%d", c.Revision, c.Type, c.ItemID))
+		}
+		return strings.Join(parts, " ")
+	}
+
+	t.Run("Every write gets the next revision", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+
+		item, _ := st.CreateItem(ctx, domain.Item{Name: "a", CreatedAt: at(1), UpdatedAt: at(1)})
+		st.CreateItems(ctx, []domain.Item{{Name: "b"}, {Name: "c"}})
+		st.UpdateItem(ctx, domain.Item{ID: item.ID, Name: "a2", UpdatedAt: at(2)})
+		st.AddTag(ctx, item.ID, 0, "red", at(3))
+		st.AddTag(ctx, item.ID, 0, "red", at(3)) // повтор ничего не меняет
+		st.RemoveTag(ctx, item.ID, 0, "red", at(4))
+		st.DeleteItem(ctx, item.ID, 0, time.Now())
+		st.DeleteItems(ctx, domain.BulkDelete{IDs: []int{2}, DryRun: true}, time.Now())
+		st.RestoreItem(ctx, item.ID, at(5))
+		st.UpdateItem(ctx, domain.Item{ID: 99, Name: "x"}) // ошибка ничего не пишет
+
+		changes, revision, err := st.ListChanges(ctx, 0, 0)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := "1:item.created:1 2:item.created:2 3:item.created:3 4:item.updated:1 5:item.updated:1 6:item.updated:1 7:item.deleted:1 8:item.created:1"
+		if got := changeTypes(changes); got != expected || revision != 8 {
+			t.Fatalf("expected %q at revision 8, got %q at %d", expected, got, revision)
+		}
+		if changes[3].Item.Name != "a2" || !changes[3].At.Equal(at(2)) || changes[6].Item.Name != "" {
+			t.Fatalf("unexpected change contents: %+v, %+v", changes[3], changes[6])
+		}
+
+		page, _, _ := st.ListChanges(ctx, 6, 1)
+		if got := changeTypes(page); got != "7:item.deleted:1" {
+			t.Fatalf("expected one change after 6, got %q", got)
+		}
+	})
+
+	t.Run("Rolled back transaction keeps revision", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		st.CreateItem(ctx, domain.Item{Name: "a"})
+
+		st.WithTx(ctx, func(tx domain.Storage) error {
+			tx.CreateItem(ctx, domain.Item{Name: "b"})
+			return errors.New("rollback")
+		})
+		st.WithTx(ctx, func(tx domain.Storage) error {
+			_, err := tx.CreateItem(ctx, domain.Item{Name: "c"})
+			return err
+		})
+
+		changes, revision, _ := st.ListChanges(ctx, 0, 0)
+		if got := changeTypes(changes); got != "1:item.created:1 2:item.created:2" || revision != 2 {
+			t.Fatalf("unexpected log %q at revision %d", got, revision)
+		}
+	})
+
+	t.Run("Compaction", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		for i, name := range []string{"a", "b", "c"} {
+			st.CreateItem(ctx, domain.Item{Name: name, CreatedAt: at(i)})
+		}
+
+		compacted, err := st.CompactChanges(ctx, at(2))
+		if err != nil || compacted != 2 {
+			t.Fatalf("expected 2 compacted changes, got %d, %v", compacted, err)
+		}
+
+		for _, since := range []int64{0, 1} {
+			if _, _, err := st.ListChanges(ctx, since, 0); !errors.Is(err, domain.ErrRevisionCompacted) {
+				t.Errorf("since %d: expected ErrRevisionCompacted, got: %v", since, err)
+			}
+		}
+		if _, _, err := st.ListChanges(ctx, 4, 0); !errors.Is(err, domain.ErrInvalidValue) || errors.Is(err, domain.ErrRevisionCompacted) {
+			t.Errorf("since beyond revision: expected ErrInvalidValue, got: %v", err)
+		}
+		changes, _, err := st.ListChanges(ctx, 2, 0)
+		if got := changeTypes(changes); err != nil || got != "3:item.created:3" {
+			t.Fatalf("expected change 3, got %q, %v", got, err)
+		}
+		if changes, _, err := st.ListChanges(ctx, 3, 0); err != nil || len(changes) != 0 {
+			t.Fatalf("expected empty tail, got %v, %v", changes, err)
+		}
+	})
+}
+
+func TestStorage_Tenants(t *testing.T) {
+	acme := domain.ContextWithTenant(context.Background(), "acme")
+	globex := domain.ContextWithTenant(context.Background(), "globex")
+
+	t.Run("Tenants have separate IDs, names and trash", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+
+		a, err := st.CreateItem(acme, domain.Item{Name: "Alex"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		g, err := st.CreateItem(globex, domain.Item{Name: "Alex"}) // то же имя у другого арендатора
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if a.ID != 1 || g.ID != 1 {
+			t.Fatalf("expected both tenants to start from id 1, got %d and %d", a.ID, g.ID)
+		}
+
+		if _, err := st.DeleteItem(acme, 1, 0, time.Now()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := st.GetItem(acme, 1); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got: %v", err)
+		}
+		if item, err := st.GetItem(globex, 1); err != nil || item.Name != "Alex" {
+			t.Fatalf("globex item must survive acme delete, got %+v, %v", item, err)
+		}
+		if deleted, _ := st.ListDeleted(globex); len(deleted) != 0 {
+			t.Fatalf("expected empty globex trash, got %+v", deleted)
+		}
+		if _, err := st.GetItem(context.Background(), 1); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("default tenant must not see other tenants, got: %v", err)
+		}
+
+		tenants, err := st.Tenants(context.Background())
+		if err != nil || fmt.Sprint(tenants) != "[acme globex]" {
+			t.Fatalf("unexpected tenants: %v, %v", tenants, err)
+		}
+	})
+
+	t.Run("Transaction is bound to its tenant", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+
+		err := st.WithTx(acme, func(tx domain.Storage) error {
+			if _, err := tx.CreateItem(acme, domain.Item{Name: "a"}); err != nil {
+				return err
+			}
+			_, err := tx.CreateItem(globex, domain.Item{Name: "g"})
+			return err
+		})
+		if !errors.Is(err, storage.ErrCrossTenant) {
+			t.Fatalf("expected ErrCrossTenant, got: %v", err)
+		}
+		if items, _ := st.ListItems(acme, domain.ListOptions{Limit: 10}); len(items) != 0 {
+			t.Fatalf("expected rollback, got %+v", items)
+		}
+		if items, _ := st.ListItems(globex, domain.ListOptions{Limit: 10}); len(items) != 0 {
+			t.Fatalf("expected no globex items, got %+v", items)
+		}
+	})
+
+	t.Run("Concurrent writes stay within tenant", func(t *testing.T) {
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		const tenants, perTenant = 4, 50
+
+		var wg sync.WaitGroup
+		for i := 0; i < tenants; i++ {
+			ctx := domain.ContextWithTenant(context.Background(), fmt.Sprintf("t%d", i))
+			for j := 0; j < perTenant; j++ {
+				wg.Add(1)
+				go func(j int) {
+					defer wg.Done()
+					item, err := st.CreateItem(ctx, domain.Item{Name: fmt.Sprintf("item-%d", j)})
+					if err != nil {
+						t.Errorf("unexpected error: %v", err)
+						return
+					}
+					if j%2 == 0 {
+						st.DeleteItem(ctx, item.ID, 0, time.Now())
+					}
+				}(j)
+			}
+		}
+		wg.Wait()
+
+		for i := 0; i < tenants; i++ {
+			ctx := domain.ContextWithTenant(context.Background(), fmt.Sprintf("t%d", i))
+			items, _ := st.ListItems(ctx, domain.ListOptions{Limit: 2 * perTenant})
+			deleted, _ := st.ListDeleted(ctx)
+			if len(items) != perTenant/2 || len(deleted) != perTenant/2 {
+				t.Fatalf("t%d: expected %d live and %d deleted, got %d and %d", i, perTenant/2, perTenant/2, len(items), len(deleted))
+			}
+			if _, revision, _ := st.ListChanges(ctx, 0, 1); revision != perTenant*3/2 {
+				t.Fatalf("t%d: expected revision %d, got %d", i, perTenant*3/2, revision)
+			}
+		}
+	})
+}
+
+func TestStorage_Tree(t *testing.T) {
+	ctx := context.Background()
+
+	// 1 root -> 2 a -> 4 c; 1 root -> 3 b
+	newTree := func(t *testing.T) *storage.MemoryStorage {
+		t.Helper()
+		st := storage.NewMemoryStorage(domain.SystemClock{})
+		for _, item := range []domain.Item{{Name: "root"}, {Name: "a", ParentID: 1}, {Name: "b", ParentID: 1}, {Name: "c", ParentID: 2}} {
+			if _, err := st.CreateItem(ctx, item); err != nil {
+				t.Fatalf("create %s: %v", item.Name, err)
+			}
+		}
+		return st
+	}
+	ids := func(items []domain.Item) string {
+		res := make([]int, 0, len(items))
+		for _, item := range items {
+			res = append(res, item.ID)
+		}
+		return fmt.Sprint(res)
+	}
+
+	t.Run("Parent must exist", func(t *testing.T) {
+		st := newTree(t)
+
+		_, err := st.CreateItem(ctx, domain.Item{Name: "orphan", ParentID: 99})
+		var validationErr *domain.ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Violations[0].Code != domain.CodeNotFound {
+			t.Fatalf("expected parent_id not_found, got: %v", err)
+		}
+
+		_, err = st.CreateItems(ctx, []domain.Item{{Name: "ok", ParentID: 1}, {Name: "orphan", ParentID: 99}})
+		var batchErr *domain.BatchError
+		if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[0].Index != 1 {
+			t.Fatalf("expected batch error at index 1, got: %v", err)
+		}
+	})
+
+	t.Run("Moves that create a cycle are rejected", func(t *testing.T) {
+		st := newTree(t)
+
+		for _, parent := range []int{1, 2, 4} { // сам элемент и его потомки
+			item, _ := st.GetItem(ctx, 1)
+			item.ParentID = parent
+			if _, _, err := st.UpdateItem(ctx, item); !errors.Is(err, domain.ErrInvalidValue) {
+				t.Fatalf("parent %d: expected ErrInvalidValue, got: %v", parent, err)
+			}
+		}
+
+		// Перенос c под b меняет индекс детей
+		item, _ := st.GetItem(ctx, 4)
+		item.ParentID = 3
+		if _, _, err := st.UpdateItem(ctx, item); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if items, _ := st.ListItems(ctx, domain.ListOptions{Parent: 3}); ids(items) != "[4]" {
+			t.Fatalf("expected b to have child 4, got %s", ids(items))
+		}
+		if items, _ := st.ListItems(ctx, domain.ListOptions{Parent: 2}); len(items) != 0 {
+			t.Fatalf("expected a to have no children, got %s", ids(items))
+		}
+	})
+
+	t.Run("Subtree respects depth", func(t *testing.T) {
+		st := newTree(t)
+
+		all, _ := st.ListSubtree(ctx, 1, 0)
+		if ids(all) != "[1 2 3 4]" {
+			t.Fatalf("unexpected subtree: %s", ids(all))
+		}
+		top, _ := st.ListSubtree(ctx, 1, 1)
+		if ids(top) != "[1 2 3]" {
+			t.Fatalf("unexpected depth 1 subtree: %s", ids(top))
+		}
+		if _, err := st.ListSubtree(ctx, 99, 0); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("Delete refuses parents, cascade removes descendants first", func(t *testing.T) {
+		st := newTree(t)
+
+		if _, err := st.DeleteItem(ctx, 2, 0, time.Now()); !errors.Is(err, domain.ErrHasChildren) {
+			t.Fatalf("expected ErrHasChildren, got: %v", err)
+		}
+
+		deleted, err := st.DeleteTree(ctx, 1, 0, time.Now())
+		if err != nil || ids(deleted) != "[4 3 2 1]" {
+			t.Fatalf("unexpected cascade: %s, %v", ids(deleted), err)
+		}
+		if trash, _ := st.ListDeleted(ctx); len(trash) != 4 {
+			t.Fatalf("expected 4 items in trash, got %d", len(trash))
+		}
+
+		// Восстанавливать можно только сверху вниз
+		if _, err := st.RestoreItem(ctx, 4, time.Now()); !errors.Is(err, domain.ErrParentDeleted) {
+			t.Fatalf("expected ErrParentDeleted, got: %v", err)
+		}
+		for _, id := range []int{1, 2, 4} {
+			if _, err := st.RestoreItem(ctx, id, time.Now()); err != nil {
+				t.Fatalf("restore %d: %v", id, err)
+			}
+		}
+		if _, err := st.DeleteItem(ctx, 2, 0, time.Now()); !errors.Is(err, domain.ErrHasChildren) {
+			t.Fatalf("restored child must be linked again, got: %v", err)
+		}
+	})
+
+	t.Run("Bulk delete keeps parents whose children stay", func(t *testing.T) {
+		st := newTree(t)
+
+		results, _ := st.DeleteItems(ctx, domain.BulkDelete{IDs: []int{2, 1, 4}}, time.Now())
+		got := fmt.Sprint(results)
+		if got != "[{2 deleted} {1 has_children} {4 deleted}]" {
+			t.Fatalf("unexpected results: %s", got)
+		}
+		if _, err := st.GetItem(ctx, 1); err != nil {
+			t.Fatalf("root must stay: %v", err)
+		}
+	})
+}
+
+type stepClock struct {
+	now time.Time
+}
+
+func (c *stepClock) Now() time.Time { return c.now }
+
+func TestStorage_Expiry(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	newStorage := func(t *testing.T) (*storage.MemoryStorage, *stepClock) {
+		t.Helper()
+		clock := &stepClock{now: start}
+		st := storage.NewMemoryStorage(clock)
+		// Сроки не по порядку ID: куча должна отдать их по времени
+		for _, item := range []domain.Item{
+			{Name: "late", ExpiresAt: start.Add(3 * time.Minute), Tags: []string{"tmp"}},
+			{Name: "early", ExpiresAt: start.Add(time.Minute), Tags: []string{"tmp"}},
+			{Name: "child", ParentID: 1, ExpiresAt: start.Add(2 * time.Minute)},
+			{Name: "forever"},
+		} {
+			if _, err := st.CreateItem(ctx, item); err != nil {
+				t.Fatalf("create %s: %v", item.Name, err)
+			}
+		}
+		return st, clock
+	}
+
+	t.Run("Reads hide expired items before reaping", func(t *testing.T) {
+		st, clock := newStorage(t)
+		clock.now = start.Add(2 * time.Minute)
+
+		for _, id := range []int{2, 3} {
+			if _, err := st.GetItem(ctx, id); !errors.Is(err, domain.ErrNotFound) {
+				t.Fatalf("id %d: expected ErrNotFound, got: %v", id, err)
+			}
+		}
+		items, _ := st.ListItems(ctx, domain.ListOptions{})
+		if len(items) != 2 || items[0].ID != 1 || items[1].ID != 4 {
+			t.Fatalf("expected live items 1 and 4, got: %+v", items)
+		}
+		if tree, err := st.ListSubtree(ctx, 1, 0); err != nil || len(tree) != 1 {
+			t.Fatalf("expected subtree without expired child, got: %+v, %v", tree, err)
+		}
+		if tags, _ := st.ListTags(ctx); len(tags) != 1 || tags[0].Count != 1 {
+			t.Fatalf("expected tmp to count only the live item, got: %+v", tags)
+		}
+	})
+
+	t.Run("Reap returns items in expiry order once", func(t *testing.T) {
+		st, clock := newStorage(t)
+
+		clock.now = start.Add(2 * time.Minute)
+		reaped, err := st.ReapExpired(ctx)
+		if err != nil || len(reaped) != 2 || reaped[0].Name != "early" || reaped[1].Name != "child" {
+			t.Fatalf("unexpected reap: %+v, %v", reaped, err)
+		}
+		if reaped, _ := st.ReapExpired(ctx); len(reaped) != 0 {
+			t.Fatalf("expected nothing left to reap, got: %+v", reaped)
+		}
+
+		changes, _, _ := st.ListChanges(ctx, 4, 0)
+		if len(changes) != 2 || changes[0].Type != domain.EventItemDeleted || changes[0].ItemID != 2 {
+			t.Fatalf("expected deletions in change log, got: %+v", changes)
+		}
+	})
+
+	t.Run("Expired item cannot be restored", func(t *testing.T) {
+		st, clock := newStorage(t)
+		if _, err := st.DeleteItem(ctx, 2, 0, time.Now()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		clock.now = start.Add(time.Hour)
+		if _, err := st.RestoreItem(ctx, 2, clock.now); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("Nil clock panics", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic for nil clock")
+			}
+		}()
+		storage.NewMemoryStorage(nil)
+	})
+
+	t.Run("Dry run skips expired items without removing them", func(t *testing.T) {
+		st, clock := newStorage(t)
+		clock.now = start.Add(2 * time.Minute)
+
+		results, err := st.DeleteItems(ctx, domain.BulkDelete{IDs: []int{1, 2}, DryRun: true}, clock.now)
+		expected := []domain.DeleteResult{
+			{ID: 1, Status: domain.DeleteStatusDeleted}, // единственный ребёнок уже истёк
+			{ID: 2, Status: domain.DeleteStatusNotFound},
+		}
+		if err != nil || fmt.Sprint(results) != fmt.Sprint(expected) {
+			t.Fatalf("expected %v, got: %v, %v", expected, results, err)
+		}
+		if changes, _, _ := st.ListChanges(ctx, 4, 0); len(changes) != 0 {
+			t.Fatalf("dry run must not record expiry, got: %+v", changes)
+		}
+	})
+
+	t.Run("Transaction sees and reaps expired items", func(t *testing.T) {
+		st, clock := newStorage(t)
+
+		err := st.WithTx(ctx, func(tx domain.Storage) error {
+			clock.now = start.Add(time.Minute)
+			if _, err := tx.GetItem(ctx, 2); !errors.Is(err, domain.ErrNotFound) {
+				t.Fatalf("expected ErrNotFound inside tx, got: %v", err)
+			}
+			reaped, err := tx.ReapExpired(ctx)
+			if err != nil || len(reaped) != 1 || reaped[0].ID != 2 {
+				t.Fatalf("unexpected reap inside tx: %+v, %v", reaped, err)
+			}
+			return errors.New("rollback")
+		})
+		if err == nil {
+			t.Fatal("expected rollback error")
+		}
+
+		// Откат возвращает элемент в кучу: его отдаёт следующий вызов
+		if reaped, _ := st.ReapExpired(ctx); len(reaped) != 1 || reaped[0].ID != 2 {
+			t.Fatalf("expected item 2 after rollback, got: %+v", reaped)
+		}
+	})
 }
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"Goworkspace/Project/domain"
+)
+
+func (s *MemoryStorage) AddTag(ctx context.Context, id, version int, tag string, at time.Time) (domain.Item, domain.Item, error) {
+	select {
+	case <-ctx.Done():
+		return domain.Item{}, domain.Item{}, ctx.Err()
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		return s.write(ctx).addTag(id, version, tag, at)
+	}
+}
+
+func (s *MemoryStorage) RemoveTag(ctx context.Context, id, version int, tag string, at time.Time) (domain.Item, domain.Item, error) {
+	select {
+	case <-ctx.Done():
+		return domain.Item{}, domain.Item{}, ctx.Err()
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		return s.write(ctx).removeTag(id, version, tag, at)
+	}
+}
+
+func (s *MemoryStorage) ListTags(ctx context.Context) ([]domain.TagCount, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		return s.read(ctx).listTags(s.clock.Now()), nil
+	}
+}
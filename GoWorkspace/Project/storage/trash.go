@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"Goworkspace/Project/domain"
+)
+
+func (s *MemoryStorage) ListDeleted(ctx context.Context) ([]domain.DeletedItem, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		return s.read(ctx).listDeleted(), nil
+	}
+}
+
+func (s *MemoryStorage) RestoreItem(ctx context.Context, id int, at time.Time) (domain.Item, error) {
+	select {
+	case <-ctx.Done():
+		return domain.Item{}, ctx.Err()
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		return s.write(ctx).restoreItem(id, at)
+	}
+}
+
+func (s *MemoryStorage) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		return s.write(ctx).purgeDeleted(before), nil
+	}
+}
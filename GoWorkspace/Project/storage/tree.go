@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"Goworkspace/Project/domain"
+)
+
+// Иерархия элементов. Инвариант: родитель живого элемента тоже жив,
+// поэтому удаление родителя с детьми возможно только каскадом.
+
+func (s *MemoryStorage) DeleteTree(ctx context.Context, id, version int, at time.Time) ([]domain.Item, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		return s.write(ctx).deleteTree(id, version, at)
+	}
+}
+
+func (s *MemoryStorage) ListSubtree(ctx context.Context, id, depth int) ([]domain.Item, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		return s.read(ctx).listSubtree(id, depth, s.clock.Now())
+	}
+}
+
+func (st *state) linkParent(id, parent int) {
+	if parent == 0 {
+		return
+	}
+	ids, ok := st.children[parent]
+	if !ok {
+		ids = make(map[int]struct{})
+		st.children[parent] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func (st *state) unlinkParent(id, parent int) {
+	if parent == 0 {
+		return
+	}
+	delete(st.children[parent], id)
+	if len(st.children[parent]) == 0 {
+		delete(st.children, parent)
+	}
+}
+
+// childIDs возвращает ID детей parent по возрастанию.
+func (st *state) childIDs(parent int) []int {
+	ids := make([]int, 0, len(st.children[parent]))
+	for id := range st.children[parent] {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// checkParent проверяет, что элемент можно поместить под родителя: родитель существует,
+// не является самим элементом или его потомком и живёт не меньше элемента.
+// ID == 0 - новый элемент.
+func (st *state) checkParent(item domain.Item) error {
+	id, parent := item.ID, item.ParentID
+	if parent == 0 {
+		return nil
+	}
+	p, ok := st.data[parent]
+	if !ok {
+		return domain.NewValidationError("parent_id", domain.CodeNotFound, "parent does not exist", domain.ErrInvalidValue)
+	}
+	// Дети истекают не позже родителя, поэтому у живого элемента всегда живой родитель
+	if !p.ExpiresAt.IsZero() && (item.ExpiresAt.IsZero() || item.ExpiresAt.After(p.ExpiresAt)) {
+		return domain.NewValidationError("expires_at", domain.CodeOutOfRange, "must not be later than the parent's expires_at", domain.ErrInvalidValue)
+	}
+	for p := parent; p != 0; p = st.data[p].ParentID {
+		if p == id {
+			return domain.NewValidationError("parent_id", domain.CodeInvalid, "would make the item its own ancestor", domain.ErrInvalidValue)
+		}
+	}
+	return nil
+}
+
+// subtree возвращает id и его потомков до глубины depth (0 - все): предки раньше потомков,
+// дети одного родителя - по возрастанию ID.
+func (st *state) subtree(id, depth int) []int {
+	ids := []int{id}
+	level := ids
+	for d := 1; (depth <= 0 || d <= depth) && len(level) > 0; d++ {
+		var next []int
+		for _, parent := range level {
+			next = append(next, st.childIDs(parent)...)
+		}
+		ids = append(ids, next...)
+		level = next
+	}
+	return ids
+}
+
+func (st *state) listSubtree(id, depth int, now time.Time) ([]domain.Item, error) {
+	if item, ok := st.data[id]; !ok || expired(item, now) {
+		return nil, domain.ErrNotFound
+	}
+
+	ids := st.subtree(id, depth)
+	items := make([]domain.Item, 0, len(ids))
+	for _, id := range ids {
+		// Потомки истёкшего элемента тоже истекли, пропуск не рвёт дерево
+		if item := st.data[id]; !expired(item, now) {
+			items = append(items, detach(item))
+		}
+	}
+	return items, nil
+}
+
+// deleteTree удаляет элемент вместе с потомками, потомков - раньше предков.
+// Возвращает удалённые элементы в порядке удаления.
+func (st *state) deleteTree(id, version int, at time.Time) ([]domain.Item, error) {
+	root, ok := st.data[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	if version != 0 && version != root.Version {
+		return nil, domain.ErrVersionConflict
+	}
+
+	ids := st.subtree(id, 0)
+	slices.Reverse(ids)
+	deleted := make([]domain.Item, 0, len(ids))
+	for _, id := range ids {
+		item := st.data[id]
+		st.softDelete(item, at)
+		deleted = append(deleted, detach(item))
+	}
+	return deleted, nil
+}
+
+// leavesFirst отбирает из ids живые элементы, которые можно удалить без каскада:
+// все их дети тоже среди ids. Возвращает их в порядке удаления - дети раньше родителей,
+// остальное в порядке ids. Истёкшие к now элементы и дети не учитываются.
+func (st *state) leavesFirst(ids []int, now time.Time) []int {
+	pending := make(map[int]int, len(ids)) // ID -> сколько детей ещё не удалено; -1 - уже в очереди
+	for _, id := range ids {
+		if item, ok := st.data[id]; ok && !expired(item, now) {
+			n := 0
+			for child := range st.children[id] {
+				if !expired(st.data[child], now) {
+					n++
+				}
+			}
+			pending[id] = n
+		}
+	}
+
+	order := make([]int, 0, len(pending))
+	for _, id := range ids {
+		if n, ok := pending[id]; ok && n == 0 {
+			pending[id] = -1
+			order = append(order, id)
+		}
+	}
+	for i := 0; i < len(order); i++ {
+		parent := st.data[order[i]].ParentID
+		if n, ok := pending[parent]; ok && n > 0 {
+			pending[parent] = n - 1
+			if n == 1 {
+				pending[parent] = -1
+				order = append(order, parent)
+			}
+		}
+	}
+	return order
+}
@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"Goworkspace/Project/domain"
+)
+
+var (
+	ErrTxClosed    = errors.New("transaction is closed")                 // Транзакция уже завершена
+	ErrCrossTenant = errors.New("transaction belongs to another tenant") // Контекст операции указывает на другого арендатора
+)
+
+// WithTx выполняет fn атомарно. fn работает с копией данных (copy-on-write),
+// которая подменяет основное состояние только при успешном завершении.
+// Ошибка или паника в fn оставляет хранилище нетронутым; паника пробрасывается дальше.
+//
+// На время fn хранилище заблокировано на запись: внутри fn нужно использовать только tx,
+// обращение к самому MemoryStorage приведёт к взаимоблокировке.
+// Транзакция видит только арендатора из ctx; операции с контекстом другого арендатора
+// возвращают ErrCrossTenant.
+func (s *MemoryStorage) WithTx(ctx context.Context, fn func(tx domain.Storage) error) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		tenant := domain.TenantFromContext(ctx)
+		st, err := runTx(s.write(ctx), tenant, s.clock.Now, fn)
+		if err != nil {
+			return err
+		}
+		s.tenants[tenant] = st
+
+		return nil
+	}
+}
+
+// runTx запускает fn на копии base и возвращает изменённую копию.
+func runTx(base *state, tenant string, now func() time.Time, fn func(tx domain.Storage) error) (*state, error) {
+	tx := &txStorage{st: base.clone(), tenant: tenant, now: now}
+	defer func() { tx.closed = true }() // в том числе при панике
+
+	if err := fn(tx); err != nil {
+		return nil, err
+	}
+
+	return tx.st, nil
+}
+
+// txStorage - представление хранилища внутри транзакции. Не потокобезопасно:
+// им пользуется только функция, переданная в WithTx.
+type txStorage struct {
+	st     *state
+	tenant string
+	now    func() time.Time
+	closed bool
+}
+
+func (tx *txStorage) check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if tx.closed {
+		return ErrTxClosed
+	}
+	if domain.TenantFromContext(ctx) != tx.tenant {
+		return ErrCrossTenant
+	}
+	return nil
+}
+
+func (tx *txStorage) CreateItem(ctx context.Context, item domain.Item) (domain.Item, error) {
+	if err := tx.check(ctx); err != nil {
+		return domain.Item{}, err
+	}
+	return tx.st.createItem(item)
+}
+
+func (tx *txStorage) GetItem(ctx context.Context, id int) (domain.Item, error) {
+	if err := tx.check(ctx); err != nil {
+		return domain.Item{}, err
+	}
+	return tx.st.getItem(id, tx.now())
+}
+
+func (tx *txStorage) UpdateItem(ctx context.Context, item domain.Item) (domain.Item, domain.Item, error) {
+	if err := tx.check(ctx); err != nil {
+		return domain.Item{}, domain.Item{}, err
+	}
+	return tx.st.updateItem(item)
+}
+
+func (tx *txStorage) DeleteItem(ctx context.Context, id, version int, at time.Time) (domain.Item, error) {
+	if err := tx.check(ctx); err != nil {
+		return domain.Item{}, err
+	}
+	return tx.st.deleteItem(id, version, at)
+}
+
+func (tx *txStorage) CreateItems(ctx context.Context, items []domain.Item) ([]domain.Item, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	return tx.st.createItems(items)
+}
+
+func (tx *txStorage) DeleteItems(ctx context.Context, req domain.BulkDelete, at time.Time) ([]domain.DeleteResult, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	return tx.st.deleteItems(req, at), nil
+}
+
+func (tx *txStorage) ListItems(ctx context.Context, opts domain.ListOptions) ([]domain.Item, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	return tx.st.findItems(opts.AfterID, opts.Limit, opts, tx.now()), nil
+}
+
+func (tx *txStorage) ListDeleted(ctx context.Context) ([]domain.DeletedItem, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	return tx.st.listDeleted(), nil
+}
+
+func (tx *txStorage) RestoreItem(ctx context.Context, id int, at time.Time) (domain.Item, error) {
+	if err := tx.check(ctx); err != nil {
+		return domain.Item{}, err
+	}
+	return tx.st.restoreItem(id, at)
+}
+
+func (tx *txStorage) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	if err := tx.check(ctx); err != nil {
+		return 0, err
+	}
+	return tx.st.purgeDeleted(before), nil
+}
+
+func (tx *txStorage) AddTag(ctx context.Context, id, version int, tag string, at time.Time) (domain.Item, domain.Item, error) {
+	if err := tx.check(ctx); err != nil {
+		return domain.Item{}, domain.Item{}, err
+	}
+	return tx.st.addTag(id, version, tag, at)
+}
+
+func (tx *txStorage) RemoveTag(ctx context.Context, id, version int, tag string, at time.Time) (domain.Item, domain.Item, error) {
+	if err := tx.check(ctx); err != nil {
+		return domain.Item{}, domain.Item{}, err
+	}
+	return tx.st.removeTag(id, version, tag, at)
+}
+
+func (tx *txStorage) ListTags(ctx context.Context) ([]domain.TagCount, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	return tx.st.listTags(tx.now()), nil
+}
+
+func (tx *txStorage) ListChanges(ctx context.Context, since int64, limit int) ([]domain.Change, int64, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, 0, err
+	}
+	return tx.st.listChanges(since, limit)
+}
+
+// Tenants внутри транзакции видит только её арендатора.
+func (tx *txStorage) Tenants(ctx context.Context) ([]string, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	return []string{tx.tenant}, nil
+}
+
+func (tx *txStorage) CompactChanges(ctx context.Context, before time.Time) (int, error) {
+	if err := tx.check(ctx); err != nil {
+		return 0, err
+	}
+	return tx.st.compactChanges(before), nil
+}
+
+func (tx *txStorage) DeleteTree(ctx context.Context, id, version int, at time.Time) ([]domain.Item, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	return tx.st.deleteTree(id, version, at)
+}
+
+func (tx *txStorage) ListSubtree(ctx context.Context, id, depth int) ([]domain.Item, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	return tx.st.listSubtree(id, depth, tx.now())
+}
+
+func (tx *txStorage) ReapExpired(ctx context.Context) ([]domain.Item, error) {
+	if err := tx.check(ctx); err != nil {
+		return nil, err
+	}
+	tx.st.expire(tx.now())
+	return tx.st.reapExpired(), nil
+}
+
+// WithTx внутри транзакции работает как точка сохранения:
+// ошибка откатывает только изменения вложенного fn.
+func (tx *txStorage) WithTx(ctx context.Context, fn func(tx domain.Storage) error) error {
+	if err := tx.check(ctx); err != nil {
+		return err
+	}
+
+	st, err := runTx(tx.st, tx.tenant, tx.now, fn)
+	if err != nil {
+		return err
+	}
+	tx.st = st
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package stream
+
+import (
+	"context"
+	"sync"
+
+	"Goworkspace/Project/domain"
+)
+
+const (
+	DefaultHistory      = 1024 // событий хранится для возобновления по Last-Event-ID
+	DefaultClientBuffer = 64   // событий ждёт отправки одному клиенту
+)
+
+// Message - событие с порядковым номером потока. Номера идут подряд с 1 общие для всех арендаторов,
+// поэтому клиент одного арендатора видит их с пропусками.
+type Message struct {
+	ID     uint64
+	Tenant string
+	Event  domain.Event
+}
+
+// Broker нумерует события шины, хранит последние из них и раздаёт подключённым клиентам
+// того же арендатора. Handle подходит как синхронный подписчик events.Bus: он не блокируется.
+type Broker struct {
+	mu      sync.Mutex
+	history []Message // кольцевой буфер
+	head    int       // индекс самого старого сообщения
+	count   int
+	last    uint64
+	clients map[*Client]struct{}
+	buffer  int
+	closed  bool
+}
+
+// Client - подписка одного потока. Done закрывается, когда брокер отключает клиента:
+// тот не успевал читать или сервер останавливается.
+type Client struct {
+	tenant   string
+	messages chan Message
+	done     chan struct{}
+	once     sync.Once
+	slow     bool
+}
+
+func (c *Client) Messages() <-chan Message { return c.messages }
+func (c *Client) Done() <-chan struct{}    { return c.done }
+
+// Slow сообщает, что клиент отключён из-за переполненной очереди.
+func (c *Client) Slow() bool {
+	select {
+	case <-c.done:
+		return c.slow
+	default:
+		return false
+	}
+}
+
+func (c *Client) disconnect(slow bool) {
+	c.once.Do(func() {
+		c.slow = slow
+		close(c.done)
+	})
+}
+
+func NewBroker(history, clientBuffer int) *Broker {
+	if history <= 0 {
+		history = DefaultHistory
+	}
+	if clientBuffer <= 0 {
+		clientBuffer = DefaultClientBuffer
+	}
+	return &Broker{history: make([]Message, history), clients: make(map[*Client]struct{}), buffer: clientBuffer}
+}
+
+func (b *Broker) Handle(ctx context.Context, event domain.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.last++
+	msg := Message{ID: b.last, Tenant: domain.TenantFromContext(ctx), Event: event}
+	if b.count < len(b.history) {
+		b.history[(b.head+b.count)%len(b.history)] = msg
+		b.count++
+	} else {
+		b.history[b.head] = msg
+		b.head = (b.head + 1) % len(b.history)
+	}
+
+	for c := range b.clients {
+		if c.tenant != msg.Tenant {
+			continue
+		}
+		select {
+		case c.messages <- msg:
+		default:
+			// Медленного клиента отключаем, а не пропускаем событие:
+			// при переподключении он продолжит с Last-Event-ID без потерь
+			delete(b.clients, c)
+			c.disconnect(true)
+		}
+	}
+	return nil
+}
+
+// Replay - что клиент пропустил до подключения.
+type Replay struct {
+	Messages []Message // события после запрошенного номера, по порядку
+	LastID   uint64    // номер, после которого идут события из Client.Messages
+	Complete bool      // false - пропущенное уже вытеснено из истории или номер из другого запуска
+}
+
+// Subscribe подключает клиента арендатора tenant. resume == false - клиент подключается впервые
+// и получает только новые события; иначе - ещё и события после lastID из истории.
+func (b *Broker) Subscribe(tenant string, lastID uint64, resume bool) (*Client, Replay) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	client := &Client{tenant: tenant, messages: make(chan Message, b.buffer), done: make(chan struct{})}
+	replay := Replay{LastID: b.last, Complete: true}
+	if b.closed {
+		client.disconnect(false)
+		return client, replay
+	}
+	b.clients[client] = struct{}{}
+
+	if !resume {
+		return client, replay
+	}
+	oldest := b.last - uint64(b.count) + 1
+	if lastID > b.last || lastID+1 < oldest {
+		replay.Complete = false
+		return client, replay
+	}
+	for i := lastID + 1 - oldest; i < uint64(b.count); i++ {
+		if msg := b.history[(b.head+int(i))%len(b.history)]; msg.Tenant == tenant {
+			replay.Messages = append(replay.Messages, msg)
+		}
+	}
+	return client, replay
+}
+
+func (b *Broker) Unsubscribe(c *Client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.clients, c)
+	c.disconnect(false)
+}
+
+// LastID - номер последнего события, 0 - событий ещё не было.
+func (b *Broker) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.last
+}
+
+// Close отключает всех клиентов. Без этого открытые потоки не дадут
+// http.Server.Shutdown завершиться: подходит для RegisterOnShutdown.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for c := range b.clients {
+		delete(b.clients, c)
+		c.disconnect(false)
+	}
+}
@@ -0,0 +1,133 @@
+package stream_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"Goworkspace/Project/domain"
+	"Goworkspace/Project/stream"
+)
+
+func publish(b *stream.Broker, ids ...int) {
+	for _, id := range ids {
+		b.Handle(context.Background(), domain.ItemDeleted{ID: id, At: time.Now()})
+	}
+}
+
+func itemIDs(msgs []stream.Message) []int {
+	res := make([]int, 0, len(msgs))
+	for _, msg := range msgs {
+		res = append(res, msg.Event.ItemID())
+	}
+	return res
+}
+
+func TestBroker_Replay(t *testing.T) {
+	t.Run("New client gets only live events", func(t *testing.T) {
+		b := stream.NewBroker(4, 4)
+		publish(b, 1, 2)
+
+		client, replay := b.Subscribe(domain.DefaultTenant, 0, false)
+		defer b.Unsubscribe(client)
+		if len(replay.Messages) != 0 || !replay.Complete || replay.LastID != 2 {
+			t.Fatalf("unexpected replay: %+v", replay)
+		}
+
+		publish(b, 3)
+		if msg := <-client.Messages(); msg.ID != 3 || msg.Event.ItemID() != 3 {
+			t.Fatalf("unexpected message: %+v", msg)
+		}
+	})
+
+	t.Run("Resume returns missed events", func(t *testing.T) {
+		b := stream.NewBroker(4, 4)
+		publish(b, 1, 2, 3, 4, 5, 6)
+
+		cases := map[uint64][]int{6: {}, 5: {6}, 2: {3, 4, 5, 6}}
+		for lastID, expected := range cases {
+			client, replay := b.Subscribe(domain.DefaultTenant, lastID, true)
+			b.Unsubscribe(client)
+			if got := itemIDs(replay.Messages); !replay.Complete || len(got) != len(expected) || (len(got) > 0 && got[0] != expected[0]) {
+				t.Errorf("last id %d: expected %v, got %v (complete=%t)", lastID, expected, got, replay.Complete)
+			}
+		}
+	})
+
+	t.Run("Evicted or unknown ID is incomplete", func(t *testing.T) {
+		b := stream.NewBroker(4, 4)
+		publish(b, 1, 2, 3, 4, 5, 6)
+
+		for _, lastID := range []uint64{1, 7} {
+			client, replay := b.Subscribe(domain.DefaultTenant, lastID, true)
+			b.Unsubscribe(client)
+			if replay.Complete || len(replay.Messages) != 0 || replay.LastID != 6 {
+				t.Errorf("last id %d: expected incomplete replay, got %+v", lastID, replay)
+			}
+		}
+	})
+}
+
+func TestBroker_Clients(t *testing.T) {
+	t.Run("Slow client is disconnected", func(t *testing.T) {
+		b := stream.NewBroker(16, 2)
+		slow, _ := b.Subscribe(domain.DefaultTenant, 0, false)
+		fast, _ := b.Subscribe(domain.DefaultTenant, 0, false)
+		defer b.Unsubscribe(fast)
+
+		for id := 1; id <= 3; id++ {
+			publish(b, id)
+			<-fast.Messages()
+		}
+
+		select {
+		case <-slow.Done():
+		default:
+			t.Fatal("expected slow client to be disconnected")
+		}
+		if !slow.Slow() || fast.Slow() {
+			t.Fatalf("unexpected slow flags: slow=%t fast=%t", slow.Slow(), fast.Slow())
+		}
+	})
+
+	t.Run("Close disconnects clients", func(t *testing.T) {
+		b := stream.NewBroker(4, 4)
+		client, _ := b.Subscribe(domain.DefaultTenant, 0, false)
+
+		b.Close()
+		<-client.Done()
+		if client.Slow() {
+			t.Fatal("closed client is not slow")
+		}
+
+		late, _ := b.Subscribe(domain.DefaultTenant, 0, false)
+		select {
+		case <-late.Done():
+		default:
+			t.Fatal("expected subscription after Close to be disconnected")
+		}
+	})
+}
+
+func TestBroker_Tenants(t *testing.T) {
+	b := stream.NewBroker(8, 8)
+	acme := domain.ContextWithTenant(context.Background(), "acme")
+	b.Handle(acme, domain.ItemDeleted{ID: 1, At: time.Now()})
+	publish(b, 2)
+
+	client, replay := b.Subscribe("acme", 0, true)
+	if got := itemIDs(replay.Messages); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("expected only acme history, got %v", got)
+	}
+
+	publish(b, 3)
+	b.Handle(acme, domain.ItemDeleted{ID: 4, At: time.Now()})
+	select {
+	case msg := <-client.Messages():
+		if msg.ID != 4 || msg.Tenant != "acme" {
+			t.Fatalf("expected acme event 4, got %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("acme event not delivered")
+	}
+}
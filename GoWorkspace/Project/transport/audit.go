@@ -0,0 +1,73 @@
+package transport
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"Goworkspace/Project/domain"
+)
+
+// AuditHandler ищет записи журнала аудита. Фильтры: actor, action, item_id, request_id,
+// from и to (RFC 3339, to не включается); страницы - after и limit.
+func AuditHandler(src *domain.Service) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		query := domain.AuditQuery{
+			Actor:     params.Get("actor"),
+			Action:    domain.AuditAction(params.Get("action")),
+			RequestID: params.Get("request_id"),
+		}
+
+		var v domain.ValidationError
+		parseInt := func(field string, dst *int) bool {
+			value := params.Get(field)
+			if value == "" {
+				return false
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				v.Add(field, domain.CodeInvalid, "must be an integer", domain.ErrInvalidValue)
+				return false
+			}
+			*dst = n
+			return true
+		}
+		parseTime := func(field string, dst *time.Time) {
+			if value := params.Get(field); value != "" {
+				t, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					v.Add(field, domain.CodeInvalid, "must be an RFC 3339 timestamp", domain.ErrInvalidValue)
+				}
+				*dst = t
+			}
+		}
+
+		var after int
+		parseInt("item_id", &query.ItemID)
+		parseInt("after", &after)
+		if parseInt("limit", &query.Limit) && query.Limit < 1 {
+			v.Add("limit", domain.CodeOutOfRange, fmt.Sprintf("must be between 1 and %d", domain.MaxListLimit), domain.ErrInvalidValue)
+		}
+		parseTime("from", &query.From)
+		parseTime("to", &query.To)
+		query.AfterID = int64(after)
+
+		if err := v.Err(); err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		page, err := src.Audit(r.Context(), query)
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		WriteJSON(w, r, http.StatusOK, NewAuditResponse(page))
+
+		log.Printf("[INFO]: %s %s: successful: count=%d", r.Method, r.URL.Path, len(page.Records))
+	})
+}
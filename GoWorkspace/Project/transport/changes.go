@@ -0,0 +1,51 @@
+package transport
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"Goworkspace/Project/domain"
+)
+
+// ChangesHandler отдаёт журнал изменений после ревизии since (по умолчанию - с начала).
+// 410 Gone - журнал уже сжат за эту ревизию, клиенту нужна полная синхронизация.
+func ChangesHandler(src *domain.Service) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v domain.ValidationError
+
+		var since int64
+		if strSince := r.URL.Query().Get("since"); strSince != "" {
+			value, err := strconv.ParseInt(strSince, 10, 64)
+			if err != nil || value < 0 {
+				v.Add("since", domain.CodeOutOfRange, "must be a non-negative integer", domain.ErrInvalidValue)
+			}
+			since = value
+		}
+
+		var limit int
+		if strLimit := r.URL.Query().Get("limit"); strLimit != "" {
+			value, err := strconv.Atoi(strLimit)
+			if err != nil || value < 1 {
+				v.Add("limit", domain.CodeOutOfRange, fmt.Sprintf("must be between 1 and %d", domain.MaxListLimit), domain.ErrInvalidValue)
+			}
+			limit = value
+		}
+
+		if err := v.Err(); err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		page, err := src.Changes(r.Context(), since, limit)
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		WriteJSON(w, r, http.StatusOK, NewChangesResponse(page, since))
+
+		log.Printf("[INFO]: %s %s: successful: since=%d count=%d", r.Method, r.URL.Path, since, len(page.Changes))
+	})
+}
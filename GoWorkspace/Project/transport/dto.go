@@ -2,17 +2,441 @@ package transport
 
 import (
 	"Goworkspace/Project/domain"
+	"Goworkspace/Project/webhook"
+	"encoding/json"
+	"errors"
+	"time"
 )
 
+// CreateRequest - новый элемент. Срок жизни задаётся одним из полей: ttl - длительность
+// в формате Go ("90s", "24h"), expires_at - момент в RFC 3339.
 type CreateRequest struct {
-	Name string `json:"name"`
+	Name       string            `json:"name"`
+	ParentID   int               `json:"parent_id,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	TTL        string            `json:"ttl,omitempty"`
+	ExpiresAt  string            `json:"expires_at,omitempty"`
+}
+
+// Item разбирает запрос во входные данные domain.Service.Create.
+func (req CreateRequest) Item() (domain.Item, error) {
+	item := domain.Item{Name: req.Name, ParentID: req.ParentID, Attributes: req.Attributes}
+
+	var v domain.ValidationError
+	if req.TTL != "" {
+		ttl, err := time.ParseDuration(req.TTL)
+		switch {
+		case err != nil:
+			v.Add("ttl", domain.CodeInvalid, "must be a duration such as 90s or 24h", domain.ErrInvalidValue)
+		case ttl <= 0:
+			v.Add("ttl", domain.CodeOutOfRange, "must be positive", domain.ErrInvalidValue)
+		}
+		item.TTL = ttl
+	}
+	if req.ExpiresAt != "" {
+		at, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			v.Add("expires_at", domain.CodeInvalid, "must be an RFC 3339 timestamp", domain.ErrInvalidValue)
+		}
+		item.ExpiresAt = at
+	}
+
+	return item, v.Err()
+}
+
+// UpdateRequest - полное состояние элемента для PUT: отсутствующие атрибуты удаляются,
+// без parent_id элемент становится корневым.
+type UpdateRequest struct {
+	Name       string            `json:"name"`
+	ParentID   int               `json:"parent_id,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// ItemResponse - представление элемента в ответах API, время в формате RFC 3339 (UTC).
+type ItemResponse struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Version   int    `json:"version"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	ParentID  int    `json:"parent_id,omitempty"`  // нет у корневых элементов
+	ExpiresAt string `json:"expires_at,omitempty"` // нет у бессрочных элементов
+
+	Attributes map[string]string `json:"attributes"` // всегда объект, без атрибутов - {}
+	Tags       []string          `json:"tags"`       // всегда массив, без тегов - []
+}
+
+type DeletedItemResponse struct {
+	ItemResponse
+	DeletedAt string `json:"deleted_at"`
 }
 
 type ResponseResult struct {
-	Item   *domain.Item `json:"item,omitempty"`
-	Status string       `json:"status"`
+	Item   *ItemResponse `json:"item,omitempty"`
+	Status string        `json:"status"`
+}
+
+// CascadeDeleteResponse - ответ DELETE с cascade=true: ID удалённых, потомки раньше предков.
+type CascadeDeleteResponse struct {
+	Deleted []int  `json:"deleted"`
+	Status  string `json:"status"`
+}
+
+// TreeNodeResponse - элемент поддерева с детьми по возрастанию ID.
+type TreeNodeResponse struct {
+	ItemResponse
+	Children []TreeNodeResponse `json:"children"` // всегда массив, у листьев - []
+}
+
+type TreeResponse struct {
+	Tree   TreeNodeResponse `json:"tree"`
+	Status string           `json:"status"`
+}
+
+type ListResponse struct {
+	Items      []ItemResponse `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	Status     string         `json:"status"`
+}
+
+type TrashResponse struct {
+	Items  []DeletedItemResponse `json:"items"`
+	Status string                `json:"status"`
+}
+
+type BatchCreateResponse struct {
+	Items  []ItemResponse `json:"items"`
+	Status string         `json:"status"`
+}
+
+type NameFilterRequest struct {
+	Name         string `json:"name"`
+	NamePrefix   string `json:"name_prefix"`
+	NameContains string `json:"name_contains"`
+}
+
+type BatchDeleteRequest struct {
+	IDs    []int              `json:"ids"`
+	Filter *NameFilterRequest `json:"filter"`
+	DryRun bool               `json:"dry_run"`
+}
+
+type DeleteResultResponse struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+}
+
+type BatchDeleteResponse struct {
+	Results []DeleteResultResponse `json:"results"`
+	Deleted int                    `json:"deleted"`
+	DryRun  bool                   `json:"dry_run"`
+	Status  string                 `json:"status"`
+}
+
+type TagCountResponse struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+type TagsResponse struct {
+	Tags   []TagCountResponse `json:"tags"`
+	Status string             `json:"status"`
+}
+
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"` // без него генерируется и возвращается один раз при создании
+	Events []string `json:"events,omitempty"` // пусто - все события
+}
+
+type WebhookResponse struct {
+	ID        int      `json:"id"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"secret,omitempty"`
+	Events    []string `json:"events"`
+	CreatedAt string   `json:"created_at"`
+}
+
+type WebhookResult struct {
+	Webhook *WebhookResponse `json:"webhook,omitempty"`
+	Status  string           `json:"status"`
+}
+
+type WebhooksResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+	Status   string            `json:"status"`
+}
+
+type DeadLetterResponse struct {
+	DeliveryID string          `json:"delivery_id"`
+	WebhookID  int             `json:"webhook_id"`
+	URL        string          `json:"url"`
+	Event      string          `json:"event"`
+	ItemID     int             `json:"item_id"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempts   int             `json:"attempts"`
+	LastError  string          `json:"last_error"`
+	FailedAt   string          `json:"failed_at"`
+}
+
+type DeadLettersResponse struct {
+	DeadLetters []DeadLetterResponse `json:"dead_letters"`
+	Status      string               `json:"status"`
+}
+
+// EventResponse - данные события потока /items/events.
+type EventResponse struct {
+	Type       string        `json:"type"`
+	OccurredAt string        `json:"occurred_at"`
+	ItemID     int           `json:"item_id"`
+	Item       *ItemResponse `json:"item,omitempty"` // нет у item.deleted
+}
+
+type ChangeResponse struct {
+	Revision int64         `json:"revision"`
+	Type     string        `json:"type"`
+	ItemID   int           `json:"item_id"`
+	Item     *ItemResponse `json:"item,omitempty"` // нет у item.deleted
+	At       string        `json:"at"`
+}
+
+// ChangesResponse - страница журнала. Следующую запрашивают с since=next_since.
+type ChangesResponse struct {
+	Changes   []ChangeResponse `json:"changes"`
+	Revision  int64            `json:"revision"`
+	NextSince int64            `json:"next_since"`
+	HasMore   bool             `json:"has_more"`
+	Status    string           `json:"status"`
+}
+
+type AuditRecordResponse struct {
+	ID        int64         `json:"id"`
+	At        string        `json:"at"`
+	Actor     string        `json:"actor"`
+	Action    string        `json:"action"`
+	ItemID    int           `json:"item_id"`
+	Before    *ItemResponse `json:"before"` // null - элемента не было
+	After     *ItemResponse `json:"after"`  // null - элемента не стало
+	RequestID string        `json:"request_id,omitempty"`
+	ClientIP  string        `json:"client_ip,omitempty"`
+}
+
+// AuditResponse - страница журнала аудита. Следующую запрашивают с after=next_after.
+type AuditResponse struct {
+	Records   []AuditRecordResponse `json:"records"`
+	NextAfter int64                 `json:"next_after,omitempty"`
+	Status    string                `json:"status"`
+}
+
+type ViolationResponse struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error      string              `json:"error"`
+	Violations []ViolationResponse `json:"violations,omitempty"`
+	ExistingID int                 `json:"existing_id,omitempty"` // при конфликте имён
+}
+
+type BatchItemError struct {
+	Index      int                 `json:"index"`
+	Error      string              `json:"error"`
+	Violations []ViolationResponse `json:"violations,omitempty"`
+	ExistingID int                 `json:"existing_id,omitempty"`
+}
+
+type BatchErrorResponse struct {
+	Error  string           `json:"error"`
+	Errors []BatchItemError `json:"errors"`
+}
+
+func NewItemResponse(item domain.Item) *ItemResponse {
+	return &ItemResponse{
+		ID:        item.ID,
+		Name:      item.Name,
+		Version:   item.Version,
+		CreatedAt: formatTime(item.CreatedAt),
+		UpdatedAt: formatTime(item.UpdatedAt),
+		ParentID:  item.ParentID,
+		ExpiresAt: formatTime(item.ExpiresAt),
+
+		Attributes: attributesOrEmpty(item.Attributes),
+		Tags:       tagsOrEmpty(item.Tags),
+	}
+}
+
+func NewTreeNodeResponse(node domain.TreeNode) TreeNodeResponse {
+	res := TreeNodeResponse{ItemResponse: *NewItemResponse(node.Item), Children: make([]TreeNodeResponse, 0, len(node.Children))}
+	for _, child := range node.Children {
+		res.Children = append(res.Children, NewTreeNodeResponse(child))
+	}
+	return res
+}
+
+func NewItemsResponse(items []domain.Item) []ItemResponse {
+	res := make([]ItemResponse, 0, len(items))
+	for _, item := range items {
+		res = append(res, *NewItemResponse(item))
+	}
+	return res
+}
+
+func NewDeletedItemsResponse(items []domain.DeletedItem) []DeletedItemResponse {
+	res := make([]DeletedItemResponse, 0, len(items))
+	for _, item := range items {
+		res = append(res, DeletedItemResponse{ItemResponse: *NewItemResponse(item.Item), DeletedAt: formatTime(item.DeletedAt)})
+	}
+	return res
+}
+
+func NewBatchErrorResponse(msg string, batchErr *domain.BatchError) BatchErrorResponse {
+	res := BatchErrorResponse{Error: msg, Errors: make([]BatchItemError, 0, len(batchErr.Errors))}
+	for _, itemErr := range batchErr.Errors {
+		itemRes := BatchItemError{Index: itemErr.Index, Error: itemErr.Err.Error()}
+		var (
+			validationErr *domain.ValidationError
+			existsErr     *domain.AlreadyExistsError
+		)
+		if errors.As(itemErr.Err, &validationErr) {
+			itemRes.Error = "validation failed"
+			itemRes.Violations = NewViolationsResponse(validationErr)
+		}
+		if errors.As(itemErr.Err, &existsErr) {
+			itemRes.ExistingID = existsErr.ID
+		}
+		res.Errors = append(res.Errors, itemRes)
+	}
+	return res
+}
+
+func NewViolationsResponse(validationErr *domain.ValidationError) []ViolationResponse {
+	res := make([]ViolationResponse, 0, len(validationErr.Violations))
+	for _, violation := range validationErr.Violations {
+		res = append(res, ViolationResponse{Field: violation.Field, Code: violation.Code, Message: violation.Message})
+	}
+	return res
+}
+
+func NewBatchDeleteResponse(results []domain.DeleteResult, dryRun bool) BatchDeleteResponse {
+	res := BatchDeleteResponse{Results: make([]DeleteResultResponse, 0, len(results)), DryRun: dryRun, Status: "Batch delete OK"}
+	for _, result := range results {
+		if result.Status == domain.DeleteStatusDeleted {
+			res.Deleted++
+		}
+		res.Results = append(res.Results, DeleteResultResponse{ID: result.ID, Status: string(result.Status)})
+	}
+	return res
+}
+
+func attributesOrEmpty(attrs map[string]string) map[string]string {
+	if attrs == nil {
+		return map[string]string{}
+	}
+	return attrs
+}
+
+func NewTagsResponse(tags []domain.TagCount) TagsResponse {
+	res := TagsResponse{Tags: make([]TagCountResponse, 0, len(tags)), Status: "Tags OK"}
+	for _, tag := range tags {
+		res.Tags = append(res.Tags, TagCountResponse{Tag: tag.Tag, Count: tag.Count})
+	}
+	return res
+}
+
+// NewWebhookResponse не раскрывает секрет: его показывают только при создании.
+func NewWebhookResponse(sub webhook.Subscription) *WebhookResponse {
+	events := make([]string, 0, len(sub.Events))
+	for _, t := range sub.Events {
+		events = append(events, string(t))
+	}
+	return &WebhookResponse{ID: sub.ID, URL: sub.URL, Events: events, CreatedAt: formatTime(sub.CreatedAt)}
+}
+
+func NewWebhooksResponse(subs []webhook.Subscription) WebhooksResponse {
+	res := WebhooksResponse{Webhooks: make([]WebhookResponse, 0, len(subs)), Status: "Webhooks OK"}
+	for _, sub := range subs {
+		res.Webhooks = append(res.Webhooks, *NewWebhookResponse(sub))
+	}
+	return res
+}
+
+func NewDeadLettersResponse(letters []webhook.DeadLetter) DeadLettersResponse {
+	res := DeadLettersResponse{DeadLetters: make([]DeadLetterResponse, 0, len(letters)), Status: "Dead letters OK"}
+	for _, l := range letters {
+		res.DeadLetters = append(res.DeadLetters, DeadLetterResponse{
+			DeliveryID: l.DeliveryID,
+			WebhookID:  l.SubscriptionID,
+			URL:        l.URL,
+			Event:      string(l.Event),
+			ItemID:     l.ItemID,
+			Payload:    l.Payload,
+			Attempts:   l.Attempts,
+			LastError:  l.LastError,
+			FailedAt:   formatTime(l.FailedAt),
+		})
+	}
+	return res
+}
+
+func NewEventResponse(event domain.Event) EventResponse {
+	res := EventResponse{Type: string(event.EventType()), OccurredAt: formatTime(event.OccurredAt()), ItemID: event.ItemID()}
+	switch e := event.(type) {
+	case domain.ItemCreated:
+		res.Item = NewItemResponse(e.Item)
+	case domain.ItemUpdated:
+		res.Item = NewItemResponse(e.Item)
+	}
+	return res
+}
+
+func NewChangesResponse(page domain.ChangePage, since int64) ChangesResponse {
+	res := ChangesResponse{Changes: make([]ChangeResponse, 0, len(page.Changes)), Revision: page.Revision, NextSince: since, HasMore: page.HasMore, Status: "Changes OK"}
+	for _, change := range page.Changes {
+		changeRes := ChangeResponse{Revision: change.Revision, Type: string(change.Type), ItemID: change.ItemID, At: formatTime(change.At)}
+		if change.Type != domain.EventItemDeleted {
+			changeRes.Item = NewItemResponse(change.Item)
+		}
+		res.Changes = append(res.Changes, changeRes)
+		res.NextSince = change.Revision
+	}
+	return res
+}
+
+func NewAuditResponse(page domain.AuditPage) AuditResponse {
+	res := AuditResponse{Records: make([]AuditRecordResponse, 0, len(page.Records)), NextAfter: page.NextAfter, Status: "Audit OK"}
+	for _, rec := range page.Records {
+		recRes := AuditRecordResponse{
+			ID:        rec.ID,
+			At:        formatTime(rec.At),
+			Actor:     rec.Actor,
+			Action:    string(rec.Action),
+			ItemID:    rec.ItemID,
+			RequestID: rec.RequestID,
+			ClientIP:  rec.ClientIP,
+		}
+		if rec.Before != nil {
+			recRes.Before = NewItemResponse(*rec.Before)
+		}
+		if rec.After != nil {
+			recRes.After = NewItemResponse(*rec.After)
+		}
+		res.Records = append(res.Records, recRes)
+	}
+	return res
+}
+
+func tagsOrEmpty(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
 }
@@ -0,0 +1,124 @@
+package transport
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"Goworkspace/Project/domain"
+)
+
+// FormatETag строит сильный ETag из версии элемента.
+func FormatETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func SetETag(w http.ResponseWriter, item domain.Item) {
+	w.Header().Set("ETag", FormatETag(item.Version))
+}
+
+// IfMatchVersion разбирает заголовок If-Match элемента id и возвращает версию для условной записи.
+// Возвращает 0, если заголовка нет или указан "*" - тогда запись безусловная.
+// Слабый ETag по RFC 9110 никогда не совпадает. Для списка ETag читается текущая версия:
+// запись условна на неё, если она есть в списке, иначе - ErrVersionConflict.
+func IfMatchVersion(r *http.Request, src *domain.Service, id int) (int, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	var versions []int
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		if version, err := parseETag(tag); err == nil {
+			versions = append(versions, version)
+		}
+	}
+
+	switch len(versions) {
+	case 0:
+		return 0, domain.ErrVersionConflict
+	case 1:
+		return versions[0], nil
+	}
+
+	current, err := src.Get(r.Context(), id)
+	if err != nil {
+		return 0, err
+	}
+	if !slices.Contains(versions, current.Version) {
+		return 0, domain.ErrVersionConflict
+	}
+	return current.Version, nil
+}
+
+func parseETag(value string) (int, error) {
+	unquoted, ok := strings.CutPrefix(value, `"`)
+	if !ok {
+		return 0, domain.ErrInvalidValue
+	}
+	unquoted, ok = strings.CutSuffix(unquoted, `"`)
+	if !ok {
+		return 0, domain.ErrInvalidValue
+	}
+
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version < 1 {
+		return 0, domain.ErrInvalidValue
+	}
+
+	return version, nil
+}
+
+// MatchesIfNoneMatch сообщает, совпадает ли заголовок If-None-Match с версией элемента.
+// Для If-None-Match используется слабое сравнение, поэтому префикс W/ игнорируется.
+func MatchesIfNoneMatch(r *http.Request, item domain.Item) bool {
+	value := strings.TrimSpace(r.Header.Get("If-None-Match"))
+	if value == "" {
+		return false
+	}
+	if value == "*" {
+		return true
+	}
+
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if version, err := parseETag(tag); err == nil && version == item.Version {
+			return true
+		}
+	}
+
+	return false
+}
+
+func SetLastModified(w http.ResponseWriter, item domain.Item) {
+	if !item.UpdatedAt.IsZero() {
+		w.Header().Set("Last-Modified", item.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// NotModified проверяет условный GET. If-None-Match имеет приоритет,
+// If-Modified-Since учитывается только при его отсутствии (RFC 9110, 13.1.3).
+func NotModified(r *http.Request, item domain.Item) bool {
+	if r.Header.Get("If-None-Match") != "" {
+		return MatchesIfNoneMatch(r, item)
+	}
+
+	value := r.Header.Get("If-Modified-Since")
+	if value == "" || item.UpdatedAt.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(value)
+	if err != nil {
+		return false
+	}
+
+	// Last-Modified передаётся с точностью до секунды
+	return !item.UpdatedAt.Truncate(time.Second).After(since)
+}
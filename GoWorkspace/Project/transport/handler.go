@@ -2,6 +2,10 @@ package transport
 
 import (
 	"Goworkspace/Project/domain"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -18,13 +22,20 @@ func PostHandler(src *domain.Service) http.HandlerFunc {
 			return
 		}
 
-		item, err := src.Create(r.Context(), req.Name)
+		input, err := req.Item()
 		if err != nil {
 			HelperError(w, r, err)
 			return
 		}
 
-		res := &ResponseResult{Item: &item, Status: "Create OK"}
+		item, err := src.Create(r.Context(), input)
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		res := &ResponseResult{Item: NewItemResponse(item), Status: "Create OK"}
+		SetETag(w, item)
 		WriteJSON(w, r, http.StatusCreated, res)
 
 		log.Printf("[INFO]: %s %s: successful: id=%d", r.Method, r.URL.Path, item.ID)
@@ -33,10 +44,9 @@ func PostHandler(src *domain.Service) http.HandlerFunc {
 
 func GetHandler(src *domain.Service) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		strID := chi.URLParam(r, "id")
-		reqID, err := strconv.Atoi(strID)
-		if err != nil || reqID < 1 {
-			HelperError(w, r, domain.ErrInvalidValue)
+		reqID, err := ParseID(r)
+		if err != nil {
+			HelperError(w, r, err)
 			return
 		}
 
@@ -46,23 +56,203 @@ func GetHandler(src *domain.Service) http.HandlerFunc {
 			return
 		}
 
-		res := ResponseResult{Item: &item, Status: "Get OK"}
+		SetETag(w, item)
+		SetLastModified(w, item)
+		if NotModified(r, item) {
+			w.WriteHeader(http.StatusNotModified)
+			log.Printf("[INFO]: %s %s: not modified: id=%d", r.Method, r.URL.Path, reqID)
+			return
+		}
+
+		res := ResponseResult{Item: NewItemResponse(item), Status: "Get OK"}
 		WriteJSON(w, r, http.StatusOK, res)
 
 		log.Printf("[INFO]: %s %s: successful: id=%d", r.Method, r.URL.Path, reqID)
 	})
 }
 
+func PutHandler(src *domain.Service) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqID, err := ParseID(r)
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		version, err := IfMatchVersion(r, src, reqID)
+		if err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		var req UpdateRequest
+
+		if err := DecodeJSONBody(r, &req); err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		item, err := src.Update(r.Context(), domain.Item{ID: reqID, Name: req.Name, ParentID: req.ParentID, Attributes: req.Attributes, Version: version})
+		if err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		res := ResponseResult{Item: NewItemResponse(item), Status: "Update OK"}
+		SetETag(w, item)
+		WriteJSON(w, r, http.StatusOK, res)
+
+		log.Printf("[INFO]: %s %s: successful: id=%d", r.Method, r.URL.Path, reqID)
+	})
+}
+
+func PatchHandler(src *domain.Service) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqID, err := ParseID(r)
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		if err := RequireContentType(r, MergePatchContentType); err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		version, err := IfMatchVersion(r, src, reqID)
+		if err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		patch, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		// Чтение, наложение патча и запись выполняются атомарно
+		item, err := src.Modify(r.Context(), reqID, version, func(current domain.Item) (domain.Item, error) {
+			doc, err := json.Marshal(UpdateRequest{Name: current.Name, ParentID: current.ParentID, Attributes: current.Attributes})
+			if err != nil {
+				return domain.Item{}, err
+			}
+
+			merged, err := ApplyMergePatch(doc, patch)
+			if err != nil {
+				return domain.Item{}, err
+			}
+
+			var req UpdateRequest
+
+			if err := DecodeJSONBytes(merged, &req); err != nil {
+				return domain.Item{}, err
+			}
+
+			current.Name = req.Name
+			current.ParentID = req.ParentID // null в патче делает элемент корневым
+			current.Attributes = req.Attributes
+			return current, nil
+		})
+		if err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		res := ResponseResult{Item: NewItemResponse(item), Status: "Patch OK"}
+		SetETag(w, item)
+		WriteJSON(w, r, http.StatusOK, res)
+
+		log.Printf("[INFO]: %s %s: successful: id=%d", r.Method, r.URL.Path, reqID)
+	})
+}
+
+// ParseListQuery читает параметры списка: cursor, limit, фильтры имени, tag и attr.*.
+func ParseListQuery(r *http.Request) (domain.ListQuery, error) {
+	query := domain.ListQuery{
+		Cursor: r.URL.Query().Get("cursor"),
+		Name: domain.NameFilter{
+			Exact:    r.URL.Query().Get("name"),
+			Prefix:   r.URL.Query().Get("name_prefix"),
+			Contains: r.URL.Query().Get("name_contains"),
+		},
+	}
+
+	query.Tags = r.URL.Query()["tag"] // несколько tag объединяются по И
+
+	attrs, err := AttributeFilter(r.URL.Query())
+	if err != nil {
+		return domain.ListQuery{}, err
+	}
+	query.Attributes = attrs
+
+	if strLimit := r.URL.Query().Get("limit"); strLimit != "" {
+		limit, err := strconv.Atoi(strLimit)
+		if err != nil || limit < 1 {
+			return domain.ListQuery{}, domain.NewValidationError("limit", domain.CodeOutOfRange, fmt.Sprintf("must be between 1 and %d", domain.MaxListLimit), domain.ErrInvalidValue)
+		}
+		query.Limit = limit
+	}
+
+	return query, nil
+}
+
+func ListHandler(src *domain.Service) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, err := ParseListQuery(r)
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		page, err := src.List(r.Context(), query)
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		res := ListResponse{Items: NewItemsResponse(page.Items), NextCursor: page.NextCursor, Status: "List OK"}
+		WriteJSON(w, r, http.StatusOK, res)
+
+		log.Printf("[INFO]: %s %s: successful: count=%d", r.Method, r.URL.Path, len(page.Items))
+	})
+}
+
 func DeleteHandler(src *domain.Service) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		strID := chi.URLParam(r, "id")
-		reqID, err := strconv.Atoi(strID)
-		if err != nil || reqID < 1 {
-			HelperError(w, r, domain.ErrInvalidValue)
+		reqID, err := ParseID(r)
+		if err != nil {
+			HelperError(w, r, err)
 			return
 		}
 
-		if err := src.Delete(r.Context(), reqID); err != nil {
+		version, err := IfMatchVersion(r, src, reqID)
+		if err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		cascade, err := parseCascade(r)
+		if err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		if cascade {
+			deleted, err := src.DeleteTree(r.Context(), reqID, version)
+			if err != nil {
+				HelperError(w, r, err, reqID)
+				return
+			}
+
+			WriteJSON(w, r, http.StatusOK, CascadeDeleteResponse{Deleted: deleted, Status: "Delete OK"})
+
+			log.Printf("[INFO]: %s %s: successful: id=%d deleted=%d", r.Method, r.URL.Path, reqID, len(deleted))
+			return
+		}
+
+		if err := src.Delete(r.Context(), reqID, version); err != nil {
 			HelperError(w, r, err, reqID)
 			return
 		}
@@ -73,3 +263,160 @@ func DeleteHandler(src *domain.Service) http.HandlerFunc {
 		log.Printf("[INFO]: %s %s: successful: id=%d", r.Method, r.URL.Path, reqID)
 	})
 }
+
+func TrashHandler(src *domain.Service) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		items, err := src.ListTrash(r.Context())
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		res := TrashResponse{Items: NewDeletedItemsResponse(items), Status: "Trash OK"}
+		WriteJSON(w, r, http.StatusOK, res)
+
+		log.Printf("[INFO]: %s %s: successful: count=%d", r.Method, r.URL.Path, len(items))
+	})
+}
+
+func RestoreHandler(src *domain.Service) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqID, err := ParseID(r)
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		item, err := src.Restore(r.Context(), reqID)
+		if err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		res := ResponseResult{Item: NewItemResponse(item), Status: "Restore OK"}
+		SetETag(w, item)
+		WriteJSON(w, r, http.StatusOK, res)
+
+		log.Printf("[INFO]: %s %s: successful: id=%d", r.Method, r.URL.Path, reqID)
+	})
+}
+
+func BatchCreateHandler(src *domain.Service) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req []CreateRequest
+
+		if err := DecodeJSONBody(r, &req); err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		var batchErr domain.BatchError
+		inputs := make([]domain.Item, 0, len(req))
+		for i, entry := range req {
+			input, err := entry.Item()
+			if err != nil {
+				batchErr.Errors = append(batchErr.Errors, domain.IndexError{Index: i, Err: err})
+				continue
+			}
+			inputs = append(inputs, input)
+		}
+		if len(batchErr.Errors) > 0 {
+			HelperError(w, r, &batchErr)
+			return
+		}
+
+		items, err := src.CreateBatch(r.Context(), inputs)
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		res := BatchCreateResponse{Items: NewItemsResponse(items), Status: "Batch create OK"}
+		WriteJSON(w, r, http.StatusCreated, res)
+
+		log.Printf("[INFO]: %s %s: successful: count=%d", r.Method, r.URL.Path, len(items))
+	})
+}
+
+func BatchDeleteHandler(src *domain.Service) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req BatchDeleteRequest
+
+		if err := DecodeJSONBody(r, &req); err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		bulk := domain.BulkDelete{IDs: req.IDs, DryRun: req.DryRun}
+		if req.Filter != nil {
+			bulk.Name = domain.NameFilter{
+				Exact:    req.Filter.Name,
+				Prefix:   req.Filter.NamePrefix,
+				Contains: req.Filter.NameContains,
+			}
+		}
+
+		results, err := src.DeleteBatch(r.Context(), bulk)
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		res := NewBatchDeleteResponse(results, req.DryRun)
+		WriteJSON(w, r, http.StatusOK, res)
+
+		log.Printf("[INFO]: %s %s: successful: deleted=%d dry_run=%t", r.Method, r.URL.Path, res.Deleted, req.DryRun)
+	})
+}
+
+func AddTagHandler(src *domain.Service) http.HandlerFunc {
+	return tagHandler(src, src.AddTag, "Add tag OK")
+}
+
+func RemoveTagHandler(src *domain.Service) http.HandlerFunc {
+	return tagHandler(src, src.RemoveTag, "Remove tag OK")
+}
+
+// tagHandler - общий обработчик /item/{id}/tags/{tag}: добавление и снятие тега
+// отличаются только операцией сервиса.
+func tagHandler(src *domain.Service, op func(ctx context.Context, id, version int, tag string) (domain.Item, error), status string) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqID, err := ParseID(r)
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		version, err := IfMatchVersion(r, src, reqID)
+		if err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		item, err := op(r.Context(), reqID, version, chi.URLParam(r, "tag"))
+		if err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		res := ResponseResult{Item: NewItemResponse(item), Status: status}
+		SetETag(w, item)
+		WriteJSON(w, r, http.StatusOK, res)
+
+		log.Printf("[INFO]: %s %s: successful: id=%d", r.Method, r.URL.Path, reqID)
+	})
+}
+
+func TagsHandler(src *domain.Service) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tags, err := src.ListTags(r.Context())
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		WriteJSON(w, r, http.StatusOK, NewTagsResponse(tags))
+
+		log.Printf("[INFO]: %s %s: successful: count=%d", r.Method, r.URL.Path, len(tags))
+	})
+}
@@ -1,20 +1,42 @@
 package transport
 
 import (
+	"Goworkspace/Project/audit"
 	"Goworkspace/Project/domain"
+	"Goworkspace/Project/events"
 	"Goworkspace/Project/storage"
+	"Goworkspace/Project/stream"
+	"Goworkspace/Project/webhook"
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
+// problemResponse - тело application/problem+json вместе с расширениями API.
+type problemResponse struct {
+	Type       string              `json:"type"`
+	Title      string              `json:"title"`
+	Status     int                 `json:"status"`
+	Detail     string              `json:"detail"`
+	Instance   string              `json:"instance"`
+	RequestID  string              `json:"request_id"`
+	Violations []ViolationResponse `json:"violations"`
+	Errors     []BatchItemError    `json:"errors"`
+	ExistingID int                 `json:"existing_id"`
+}
+
 func SetupTestRout() http.Handler {
-	st := storage.NewMemoryStorage()
-	svc := domain.NewService(st)
+	clock := domain.SystemClock{}
+	st := storage.NewMemoryStorage(clock)
+	svc := domain.NewService(st, clock)
 	return NewRouter(svc)
 }
 
@@ -229,3 +251,1291 @@ func TestIntegration_CRUD_Flow_Concurency(t *testing.T) {
 		t.Fatalf("Expected value ids=0; got: %d", len(ids))
 	}
 }
+
+func TestIntegration_CreateDuplicateConflict(t *testing.T) {
+	router := SetupTestRout()
+
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"Alex"}`), http.StatusCreated)
+	recorder := doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"Alex"}`), http.StatusConflict)
+
+	var response problemResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if response.Type != ProblemAlreadyExists || response.ExistingID != 1 {
+		t.Fatalf("expected existing_id=1, got: %+v", response)
+	}
+}
+
+func TestIntegration_CreateDuplicate_Concurency(t *testing.T) {
+	router := SetupTestRout()
+	const n = 20
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		codes = make(map[int]int)
+	)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			request := httptest.NewRequest(http.MethodPost, "/item", bytes.NewReader([]byte(`{"name":"same"}`)))
+			request.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+
+			router.ServeHTTP(recorder, request)
+
+			mu.Lock()
+			codes[recorder.Code]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if codes[http.StatusCreated] != 1 || codes[http.StatusConflict] != n-1 {
+		t.Fatalf("expected one 201 and %d 409, got: %v", n-1, codes)
+	}
+}
+
+func TestIntegration_UpdateFlow(t *testing.T) {
+	router := SetupTestRout()
+
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"Alex"}`), http.StatusCreated)
+	recorder := doRequest(t, router, http.MethodPut, "/item/1", []byte(`{"name":"Alice"}`), http.StatusOK)
+
+	var response ResponseResult
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if response.Item == nil || response.Item.ID != 1 || response.Item.Name != "Alice" {
+		t.Fatalf("unexpected responce, got: %+v", response)
+	}
+
+	recorder = doRequest(t, router, http.MethodGet, "/item/1", nil, http.StatusOK)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if response.Item == nil || response.Item.Name != "Alice" {
+		t.Fatalf("expected renamed item, got: %+v", response)
+	}
+}
+
+func TestIntegration_UpdateErrors(t *testing.T) {
+	router := SetupTestRout()
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"Alex"}`), http.StatusCreated)
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"Alice"}`), http.StatusCreated)
+
+	doRequest(t, router, http.MethodPut, "/item/42", []byte(`{"name":"Bob"}`), http.StatusNotFound)
+	doRequest(t, router, http.MethodPut, "/item/1", []byte(`{"name":""}`), http.StatusBadRequest)
+	doRequest(t, router, http.MethodPut, "/item/abc", []byte(`{"name":"Bob"}`), http.StatusBadRequest)
+	doRequest(t, router, http.MethodPut, "/item/2", []byte(`{"name":"Alex"}`), http.StatusConflict)
+}
+
+func doPatch(t *testing.T, router http.Handler, path, contentType string, body []byte, expectedCode int) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPatch, path, bytes.NewReader(body))
+	request.Header.Set("Content-Type", contentType)
+
+	recorder := httptest.NewRecorder()
+
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != expectedCode {
+		t.Errorf("Expected code %d, got: %d", expectedCode, recorder.Code)
+	}
+
+	return recorder
+}
+
+func TestIntegration_PatchFlow(t *testing.T) {
+	router := SetupTestRout()
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"Alex"}`), http.StatusCreated)
+
+	recorder := doPatch(t, router, "/item/1", MergePatchContentType, []byte(`{"name":"Alice"}`), http.StatusOK)
+
+	var response ResponseResult
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if response.Item == nil || response.Item.ID != 1 || response.Item.Name != "Alice" {
+		t.Fatalf("unexpected responce, got: %+v", response)
+	}
+
+	// Пустой патч ничего не меняет
+	recorder = doPatch(t, router, "/item/1", MergePatchContentType+"; charset=utf-8", []byte(`{}`), http.StatusOK)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if response.Item == nil || response.Item.Name != "Alice" {
+		t.Fatalf("expected unchanged item, got: %+v", response)
+	}
+}
+
+func TestIntegration_PatchErrors(t *testing.T) {
+	router := SetupTestRout()
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"Alex"}`), http.StatusCreated)
+
+	doPatch(t, router, "/item/1", "application/json", []byte(`{"name":"Alice"}`), http.StatusUnsupportedMediaType)
+	doPatch(t, router, "/item/1", MergePatchContentType, []byte(`{"unknown":1}`), http.StatusBadRequest)
+	doPatch(t, router, "/item/1", MergePatchContentType, []byte(`{"name":null}`), http.StatusBadRequest)
+	doPatch(t, router, "/item/1", MergePatchContentType, []byte(`{"name":`), http.StatusBadRequest)
+	doPatch(t, router, "/item/2", MergePatchContentType, []byte(`{"name":"Alice"}`), http.StatusNotFound)
+}
+
+func TestIntegration_CreateUnknownFieldBadRequest(t *testing.T) {
+	router := SetupTestRout()
+
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"Alex","extra":1}`), http.StatusBadRequest)
+}
+
+func listAll(t *testing.T, router http.Handler, limit int) []ItemResponse {
+	var (
+		all    []ItemResponse
+		cursor string
+	)
+
+	for {
+		path := fmt.Sprintf("/items?limit=%d", limit)
+		if cursor != "" {
+			path += "&cursor=" + cursor
+		}
+
+		recorder := doRequest(t, router, http.MethodGet, path, nil, http.StatusOK)
+
+		var response ListResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Unexpected error json: %v", err)
+		}
+		if len(response.Items) > limit {
+			t.Fatalf("page larger than limit: %d", len(response.Items))
+		}
+
+		all = append(all, response.Items...)
+		if response.NextCursor == "" {
+			return all
+		}
+		cursor = response.NextCursor
+	}
+}
+
+func TestIntegration_ListPagination(t *testing.T) {
+	router := SetupTestRout()
+	for i := 1; i <= 5; i++ {
+		doRequest(t, router, http.MethodPost, "/item", []byte(fmt.Sprintf(`{"name":"item-%d"}`, i)), http.StatusCreated)
+	}
+
+	items := listAll(t, router, 2)
+	if len(items) != 5 {
+		t.Fatalf("expected 5 items, got: %d", len(items))
+	}
+	for i, item := range items {
+		if item.ID != i+1 {
+			t.Fatalf("expected id=%d at position %d, got: %d", i+1, i, item.ID)
+		}
+	}
+}
+
+func TestIntegration_ListBadRequest(t *testing.T) {
+	router := SetupTestRout()
+
+	doRequest(t, router, http.MethodGet, "/items?limit=abc", nil, http.StatusBadRequest)
+	doRequest(t, router, http.MethodGet, "/items?limit=0", nil, http.StatusBadRequest)
+	doRequest(t, router, http.MethodGet, "/items?limit=100000", nil, http.StatusBadRequest)
+	doRequest(t, router, http.MethodGet, "/items?cursor=forged.cursor", nil, http.StatusBadRequest)
+}
+
+func TestIntegration_ListStableUnderConcurrentWrites(t *testing.T) {
+	router := SetupTestRout()
+	const n = 50
+
+	// Элементы 1..n живут всё время выборки
+	for i := 1; i <= n; i++ {
+		doRequest(t, router, http.MethodPost, "/item", []byte(fmt.Sprintf(`{"name":"stable-%d"}`, i)), http.StatusCreated)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			recorder := doRequest(t, router, http.MethodPost, "/item", []byte(fmt.Sprintf(`{"name":"churn-%d"}`, i)), http.StatusCreated)
+
+			var response ResponseResult
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil || response.Item == nil {
+				t.Errorf("Unexpected create response: %s", recorder.Body.String())
+				return
+			}
+			doRequest(t, router, http.MethodDelete, fmt.Sprintf("/item/%d", response.Item.ID), nil, http.StatusOK)
+		}
+	}()
+
+	items := listAll(t, router, 7)
+	close(stop)
+	wg.Wait()
+
+	seen := make(map[int]bool)
+	lastID := 0
+	for _, item := range items {
+		if item.ID <= lastID {
+			t.Fatalf("items are not strictly ordered: %d after %d", item.ID, lastID)
+		}
+		lastID = item.ID
+		seen[item.ID] = true
+	}
+	for id := 1; id <= n; id++ {
+		if !seen[id] {
+			t.Fatalf("stable item id=%d is missing from listing", id)
+		}
+	}
+}
+
+func TestIntegration_ListFilter(t *testing.T) {
+	router := SetupTestRout()
+	for _, name := range []string{"Alex", "alice", "Bob", "Alexander"} {
+		doRequest(t, router, http.MethodPost, "/item", []byte(fmt.Sprintf(`{"name":%q}`, name)), http.StatusCreated)
+	}
+
+	cases := map[string]int{
+		"/items?name=Alex":                      1,
+		"/items?name_prefix=Al":                 2,
+		"/items?name_contains=AL":               3,
+		"/items?name_prefix=Al&name_contains=x": 2,
+		"/items?name=Nobody":                    0,
+	}
+
+	for path, expected := range cases {
+		recorder := doRequest(t, router, http.MethodGet, path, nil, http.StatusOK)
+
+		var response ListResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Unexpected error json: %v", err)
+		}
+		if len(response.Items) != expected {
+			t.Errorf("%s: expected %d items, got: %+v", path, expected, response.Items)
+		}
+	}
+
+	doRequest(t, router, http.MethodGet, "/items?name=Alex&name_prefix=Al", nil, http.StatusBadRequest)
+}
+
+func doConditional(t *testing.T, router http.Handler, method, path string, headers map[string]string, body []byte, expectedCode int) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, bytes.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	recorder := httptest.NewRecorder()
+
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != expectedCode {
+		t.Errorf("%s %s: expected code %d, got: %d", method, path, expectedCode, recorder.Code)
+	}
+
+	return recorder
+}
+
+func TestIntegration_ETagAndIfMatch(t *testing.T) {
+	router := SetupTestRout()
+	created := doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"Alex"}`), http.StatusCreated)
+	if etag := created.Header().Get("ETag"); etag != `"1"` {
+		t.Fatalf(`expected ETag "1" on create, got: %q`, etag)
+	}
+
+	got := doRequest(t, router, http.MethodGet, "/item/1", nil, http.StatusOK)
+	etag := got.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf(`expected ETag "1", got: %q`, etag)
+	}
+
+	// Первый оператор обновляет элемент по актуальной версии
+	updated := doConditional(t, router, http.MethodPut, "/item/1", map[string]string{"If-Match": etag}, []byte(`{"name":"Alice"}`), http.StatusOK)
+	if newETag := updated.Header().Get("ETag"); newETag != `"2"` {
+		t.Fatalf(`expected ETag "2" after update, got: %q`, newETag)
+	}
+
+	// Второй оператор работает со старой версией и получает 412
+	doConditional(t, router, http.MethodPut, "/item/1", map[string]string{"If-Match": etag}, []byte(`{"name":"Bob"}`), http.StatusPreconditionFailed)
+	doConditional(t, router, http.MethodDelete, "/item/1", map[string]string{"If-Match": etag}, nil, http.StatusPreconditionFailed)
+
+	patch := httptest.NewRequest(http.MethodPatch, "/item/1", bytes.NewReader([]byte(`{"name":"Bob"}`)))
+	patch.Header.Set("Content-Type", MergePatchContentType)
+	patch.Header.Set("If-Match", etag)
+	patchRec := httptest.NewRecorder()
+	router.ServeHTTP(patchRec, patch)
+	if patchRec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 for stale PATCH, got: %d", patchRec.Code)
+	}
+
+	// Слабый ETag никогда не совпадает для If-Match
+	doConditional(t, router, http.MethodPut, "/item/1", map[string]string{"If-Match": `W/"2"`}, []byte(`{"name":"Bob"}`), http.StatusPreconditionFailed)
+
+	// Список ETag совпадает, если в нём есть текущая версия
+	doConditional(t, router, http.MethodPut, "/item/1", map[string]string{"If-Match": `"1", "5"`}, []byte(`{"name":"Bob"}`), http.StatusPreconditionFailed)
+	listed := doConditional(t, router, http.MethodPut, "/item/1", map[string]string{"If-Match": `"1", W/"3", "2"`}, []byte(`{"name":"Bob"}`), http.StatusOK)
+	if newETag := listed.Header().Get("ETag"); newETag != `"3"` {
+		t.Fatalf(`expected ETag "3" after listed If-Match, got: %q`, newETag)
+	}
+
+	doConditional(t, router, http.MethodPut, "/item/1", map[string]string{"If-Match": "*"}, []byte(`{"name":"Bob"}`), http.StatusOK)
+	doConditional(t, router, http.MethodDelete, "/item/1", map[string]string{"If-Match": `"3", "4"`}, nil, http.StatusOK)
+	doConditional(t, router, http.MethodPut, "/item/1", map[string]string{"If-Match": "*"}, []byte(`{"name":"Bob"}`), http.StatusNotFound)
+}
+
+func TestIntegration_ConditionalGet(t *testing.T) {
+	router := SetupTestRout()
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"Alex"}`), http.StatusCreated)
+
+	first := doRequest(t, router, http.MethodGet, "/item/1", nil, http.StatusOK)
+	etag := first.Header().Get("ETag")
+
+	t.Run("Matching ETag returns 304 without body", func(t *testing.T) {
+		recorder := doConditional(t, router, http.MethodGet, "/item/1", map[string]string{"If-None-Match": etag}, nil, http.StatusNotModified)
+		if recorder.Body.Len() != 0 {
+			t.Fatalf("expected empty body, got: %q", recorder.Body.String())
+		}
+		if recorder.Header().Get("ETag") != etag {
+			t.Fatalf("expected ETag %s on 304, got: %q", etag, recorder.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("Weak and listed ETags also match", func(t *testing.T) {
+		doConditional(t, router, http.MethodGet, "/item/1", map[string]string{"If-None-Match": "W/" + etag}, nil, http.StatusNotModified)
+		doConditional(t, router, http.MethodGet, "/item/1", map[string]string{"If-None-Match": `"7", ` + etag}, nil, http.StatusNotModified)
+		doConditional(t, router, http.MethodGet, "/item/1", map[string]string{"If-None-Match": "*"}, nil, http.StatusNotModified)
+	})
+
+	t.Run("Stale ETag returns 200 with body", func(t *testing.T) {
+		doRequest(t, router, http.MethodPut, "/item/1", []byte(`{"name":"Alice"}`), http.StatusOK)
+
+		recorder := doConditional(t, router, http.MethodGet, "/item/1", map[string]string{"If-None-Match": etag}, nil, http.StatusOK)
+
+		var response ResponseResult
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Unexpected error json: %v", err)
+		}
+		if response.Item == nil || response.Item.Name != "Alice" {
+			t.Fatalf("expected fresh item, got: %+v", response)
+		}
+		if recorder.Header().Get("ETag") == etag {
+			t.Fatalf("expected new ETag, got: %q", etag)
+		}
+	})
+
+	t.Run("Missing item returns 404", func(t *testing.T) {
+		doConditional(t, router, http.MethodGet, "/item/2", map[string]string{"If-None-Match": etag}, nil, http.StatusNotFound)
+	})
+}
+
+func TestIntegration_TrashAndRestore(t *testing.T) {
+	router := SetupTestRout()
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"Alex"}`), http.StatusCreated)
+	doRequest(t, router, http.MethodDelete, "/item/1", nil, http.StatusOK)
+
+	recorder := doRequest(t, router, http.MethodGet, "/trash", nil, http.StatusOK)
+
+	var trash TrashResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &trash); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(trash.Items) != 1 || trash.Items[0].ID != 1 || trash.Items[0].DeletedAt == "" {
+		t.Fatalf("expected deleted item in trash, got: %+v", trash)
+	}
+
+	recorder = doRequest(t, router, http.MethodPost, "/item/1/restore", nil, http.StatusOK)
+	if recorder.Header().Get("ETag") != `"2"` {
+		t.Fatalf(`expected ETag "2" after restore, got: %q`, recorder.Header().Get("ETag"))
+	}
+	doRequest(t, router, http.MethodGet, "/item/1", nil, http.StatusOK)
+	doRequest(t, router, http.MethodPost, "/item/1/restore", nil, http.StatusNotFound)
+
+	// Имя удалённого элемента занято - восстановление даёт конфликт
+	doRequest(t, router, http.MethodDelete, "/item/1", nil, http.StatusOK)
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"Alex"}`), http.StatusCreated)
+	doRequest(t, router, http.MethodPost, "/item/1/restore", nil, http.StatusConflict)
+}
+
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestIntegration_Timestamps(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	router := NewRouter(domain.NewService(storage.NewMemoryStorage(clock), clock))
+
+	recorder := doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"Alex"}`), http.StatusCreated)
+
+	var response ResponseResult
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if response.Item.CreatedAt != "2024-05-01T10:00:00Z" || response.Item.UpdatedAt != "2024-05-01T10:00:00Z" {
+		t.Fatalf("unexpected timestamps: %+v", response.Item)
+	}
+
+	clock.Advance(time.Hour)
+	recorder = doRequest(t, router, http.MethodPut, "/item/1", []byte(`{"name":"Alice"}`), http.StatusOK)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if response.Item.CreatedAt != "2024-05-01T10:00:00Z" || response.Item.UpdatedAt != "2024-05-01T11:00:00Z" {
+		t.Fatalf("unexpected timestamps after update: %+v", response.Item)
+	}
+
+	t.Run("Last-Modified and If-Modified-Since", func(t *testing.T) {
+		recorder := doRequest(t, router, http.MethodGet, "/item/1", nil, http.StatusOK)
+		lastModified := recorder.Header().Get("Last-Modified")
+		if lastModified != "Wed, 01 May 2024 11:00:00 GMT" {
+			t.Fatalf("unexpected Last-Modified: %q", lastModified)
+		}
+
+		doConditional(t, router, http.MethodGet, "/item/1", map[string]string{"If-Modified-Since": lastModified}, nil, http.StatusNotModified)
+		doConditional(t, router, http.MethodGet, "/item/1", map[string]string{"If-Modified-Since": "Wed, 01 May 2024 10:30:00 GMT"}, nil, http.StatusOK)
+		doConditional(t, router, http.MethodGet, "/item/1", map[string]string{"If-Modified-Since": "garbage"}, nil, http.StatusOK)
+
+		// If-None-Match важнее If-Modified-Since
+		doConditional(t, router, http.MethodGet, "/item/1", map[string]string{"If-None-Match": `"1"`, "If-Modified-Since": lastModified}, nil, http.StatusOK)
+	})
+}
+
+func TestIntegration_BatchCreate(t *testing.T) {
+	router := SetupTestRout()
+
+	recorder := doRequest(t, router, http.MethodPost, "/items:batch", []byte(`[{"name":"a"},{"name":"b"},{"name":"c"}]`), http.StatusCreated)
+
+	var response BatchCreateResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(response.Items) != 3 || response.Items[0].ID != 1 || response.Items[2].Name != "c" {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+
+	doRequest(t, router, http.MethodGet, "/item/3", nil, http.StatusOK)
+}
+
+func TestIntegration_BatchCreateErrors(t *testing.T) {
+	router := SetupTestRout()
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"taken"}`), http.StatusCreated)
+
+	decode := func(recorder *httptest.ResponseRecorder) BatchErrorResponse {
+		var response BatchErrorResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Unexpected error json: %v", err)
+		}
+		return response
+	}
+
+	recorder := doRequest(t, router, http.MethodPost, "/items:batch", []byte(`[{"name":"a"},{"name":""},{"name":"a"}]`), http.StatusBadRequest)
+	response := decode(recorder)
+	if len(response.Errors) != 2 || response.Errors[0].Index != 1 || response.Errors[1].Index != 2 {
+		t.Fatalf("expected errors at indexes 1 and 2, got: %+v", response)
+	}
+
+	recorder = doRequest(t, router, http.MethodPost, "/items:batch", []byte(`[{"name":"new"},{"name":"taken"}]`), http.StatusConflict)
+	response = decode(recorder)
+	if len(response.Errors) != 1 || response.Errors[0].Index != 1 || response.Errors[0].ExistingID != 1 {
+		t.Fatalf("expected conflict at index 1, got: %+v", response)
+	}
+
+	// Ни один элемент отклонённых пакетов не должен появиться
+	items := listAll(t, router, 10)
+	if len(items) != 1 {
+		t.Fatalf("expected only the original item, got: %+v", items)
+	}
+
+	doRequest(t, router, http.MethodPost, "/items:batch", []byte(`[]`), http.StatusBadRequest)
+	doRequest(t, router, http.MethodPost, "/items:batch", []byte(`{"name":"a"}`), http.StatusBadRequest)
+}
+
+func TestIntegration_BatchDelete(t *testing.T) {
+	router := SetupTestRout()
+	doRequest(t, router, http.MethodPost, "/items:batch", []byte(`[{"name":"test-a"},{"name":"keep"},{"name":"test-b"}]`), http.StatusCreated)
+
+	decode := func(recorder *httptest.ResponseRecorder) BatchDeleteResponse {
+		var response BatchDeleteResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Unexpected error json: %v", err)
+		}
+		return response
+	}
+
+	// dry run по фильтру только показывает, что будет удалено
+	response := decode(doRequest(t, router, http.MethodPost, "/items:batchDelete", []byte(`{"filter":{"name_prefix":"test-"},"dry_run":true}`), http.StatusOK))
+	if !response.DryRun || response.Deleted != 2 || len(response.Results) != 2 {
+		t.Fatalf("unexpected dry run response: %+v", response)
+	}
+	doRequest(t, router, http.MethodGet, "/item/1", nil, http.StatusOK)
+
+	response = decode(doRequest(t, router, http.MethodPost, "/items:batchDelete", []byte(`{"ids":[1,99]}`), http.StatusOK))
+	if response.DryRun || response.Deleted != 1 || len(response.Results) != 2 ||
+		response.Results[0] != (DeleteResultResponse{ID: 1, Status: "deleted"}) ||
+		response.Results[1] != (DeleteResultResponse{ID: 99, Status: "not_found"}) {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+	doRequest(t, router, http.MethodGet, "/item/1", nil, http.StatusNotFound)
+
+	response = decode(doRequest(t, router, http.MethodPost, "/items:batchDelete", []byte(`{"filter":{"name_contains":"TEST"}}`), http.StatusOK))
+	if response.Deleted != 1 || response.Results[0].ID != 3 {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+	doRequest(t, router, http.MethodGet, "/item/2", nil, http.StatusOK)
+
+	doRequest(t, router, http.MethodPost, "/items:batchDelete", []byte(`{}`), http.StatusBadRequest)
+	doRequest(t, router, http.MethodPost, "/items:batchDelete", []byte(`{"ids":[1],"filter":{"name":"keep"}}`), http.StatusBadRequest)
+	doRequest(t, router, http.MethodPost, "/items:batchDelete", []byte(`{"ids":[0]}`), http.StatusBadRequest)
+
+	// Фильтр из одних пробелов пуст и не должен удалять всё
+	recorder := doRequest(t, router, http.MethodPost, "/items:batchDelete", []byte(`{"filter":{"name_contains":"   "}}`), http.StatusBadRequest)
+	var problem problemResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(problem.Violations) != 1 || problem.Violations[0].Code != domain.CodeRequired {
+		t.Fatalf("expected required violation, got: %+v", problem.Violations)
+	}
+	doRequest(t, router, http.MethodGet, "/item/2", nil, http.StatusOK)
+}
+
+func TestIntegration_Attributes(t *testing.T) {
+	router := SetupTestRout()
+
+	decode := func(recorder *httptest.ResponseRecorder) ItemResponse {
+		var response ResponseResult
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Unexpected error json: %v", err)
+		}
+		return *response.Item
+	}
+
+	item := decode(doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"api","attributes":{"owner":"alice","env":"prod"}}`), http.StatusCreated))
+	if len(item.Attributes) != 2 || item.Attributes["owner"] != "alice" {
+		t.Fatalf("unexpected attributes: %+v", item.Attributes)
+	}
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"db","attributes":{"owner":"bob","env":"prod"}}`), http.StatusCreated)
+
+	// Без атрибутов в ответе пустой объект, а не null
+	recorder := doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"plain"}`), http.StatusCreated)
+	if !strings.Contains(recorder.Body.String(), `"attributes":{}`) {
+		t.Fatalf("expected empty attributes object, got: %s", recorder.Body.String())
+	}
+
+	// PATCH сливает атрибуты, null удаляет ключ
+	item = decode(doPatch(t, router, "/item/1", MergePatchContentType, []byte(`{"attributes":{"env":null,"color":"red"}}`), http.StatusOK))
+	if len(item.Attributes) != 2 || item.Attributes["color"] != "red" || item.Attributes["owner"] != "alice" {
+		t.Fatalf("unexpected attributes after patch: %+v", item.Attributes)
+	}
+
+	// PUT заменяет атрибуты целиком
+	item = decode(doRequest(t, router, http.MethodPut, "/item/1", []byte(`{"name":"api","attributes":{"env":"dev"}}`), http.StatusOK))
+	if len(item.Attributes) != 1 || item.Attributes["env"] != "dev" {
+		t.Fatalf("unexpected attributes after put: %+v", item.Attributes)
+	}
+
+	cases := map[string]int{
+		"/items?attr.env=prod":                1,
+		"/items?attr.env=dev":                 1,
+		"/items?attr.owner=bob&attr.env=prod": 1,
+		"/items?attr.owner=bob&attr.env=dev":  0,
+		"/items?attr.missing=x":               0,
+	}
+	for path, expected := range cases {
+		var response ListResponse
+		if err := json.Unmarshal(doRequest(t, router, http.MethodGet, path, nil, http.StatusOK).Body.Bytes(), &response); err != nil {
+			t.Fatalf("Unexpected error json: %v", err)
+		}
+		if len(response.Items) != expected {
+			t.Errorf("%s: expected %d items, got: %+v", path, expected, response.Items)
+		}
+	}
+
+	doRequest(t, router, http.MethodGet, "/items?attr.env=a&attr.env=b", nil, http.StatusBadRequest)
+	doRequest(t, router, http.MethodGet, "/items?attr.Bad-Key=x", nil, http.StatusBadRequest)
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"bad","attributes":{"Owner":"x"}}`), http.StatusBadRequest)
+	doPatch(t, router, "/item/2", MergePatchContentType, []byte(`{"attributes":{"owner":1}}`), http.StatusBadRequest)
+}
+
+func TestIntegration_Tags(t *testing.T) {
+	router := SetupTestRout()
+	for _, name := range []string{"a", "b", "c"} {
+		doRequest(t, router, http.MethodPost, "/item", []byte(fmt.Sprintf(`{"name":%q}`, name)), http.StatusCreated)
+	}
+
+	recorder := doRequest(t, router, http.MethodPost, "/item/1/tags/red", nil, http.StatusOK)
+	var response ResponseResult
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if fmt.Sprint(response.Item.Tags) != "[red]" || response.Item.Version != 2 {
+		t.Fatalf("unexpected item: %+v", response.Item)
+	}
+	if etag := recorder.Header().Get("ETag"); etag != `"2"` {
+		t.Fatalf("expected ETag \"2\", got: %s", etag)
+	}
+
+	doRequest(t, router, http.MethodPost, "/item/1/tags/big", nil, http.StatusOK)
+	doRequest(t, router, http.MethodPost, "/item/2/tags/red", nil, http.StatusOK)
+	doConditional(t, router, http.MethodPost, "/item/3/tags/red", map[string]string{"If-Match": `"7"`}, nil, http.StatusPreconditionFailed)
+
+	cases := map[string]int{
+		"/items?tag=red":               2,
+		"/items?tag=red&tag=big":       1,
+		"/items?tag=red&name_prefix=b": 1,
+		"/items?tag=none":              0,
+	}
+	for path, expected := range cases {
+		var list ListResponse
+		if err := json.Unmarshal(doRequest(t, router, http.MethodGet, path, nil, http.StatusOK).Body.Bytes(), &list); err != nil {
+			t.Fatalf("Unexpected error json: %v", err)
+		}
+		if len(list.Items) != expected {
+			t.Errorf("%s: expected %d items, got: %+v", path, expected, list.Items)
+		}
+	}
+
+	doRequest(t, router, http.MethodDelete, "/item/2/tags/red", nil, http.StatusOK)
+	doRequest(t, router, http.MethodDelete, "/item/2/tags/red", nil, http.StatusNotFound)
+
+	var tags TagsResponse
+	if err := json.Unmarshal(doRequest(t, router, http.MethodGet, "/tags", nil, http.StatusOK).Body.Bytes(), &tags); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	expected := []TagCountResponse{{Tag: "big", Count: 1}, {Tag: "red", Count: 1}}
+	if fmt.Sprint(tags.Tags) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, got: %v", expected, tags.Tags)
+	}
+
+	doRequest(t, router, http.MethodPost, "/item/1/tags/Bad%20Tag", nil, http.StatusBadRequest)
+	doRequest(t, router, http.MethodPost, "/item/42/tags/red", nil, http.StatusNotFound)
+	doRequest(t, router, http.MethodGet, "/items?tag=Bad", nil, http.StatusBadRequest)
+}
+
+func TestIntegration_ValidationErrors(t *testing.T) {
+	router := SetupTestRout()
+
+	decode := func(recorder *httptest.ResponseRecorder) problemResponse {
+		var response problemResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Unexpected error json: %v", err)
+		}
+		return response
+	}
+	fields := func(violations []ViolationResponse) string {
+		res := make([]string, 0, len(violations))
+		for _, violation := range violations {
+			if violation.Message == "" {
+				t.Fatalf("violation without message: %+v", violation)
+			}
+			res = append(res, violation.Field+":"+violation.Code)
+		}
+		return fmt.Sprint(res)
+	}
+
+	response := decode(doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"","attributes":{"Owner":"x"}}`), http.StatusBadRequest))
+	if response.Type != ProblemValidation || fields(response.Violations) != "[name:required attributes.Owner:invalid]" {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+
+	response = decode(doRequest(t, router, http.MethodGet, "/items?name=a&name_contains=b&tag=X", nil, http.StatusBadRequest))
+	if fields(response.Violations) != "[name:conflict tag:invalid]" {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+
+	response = decode(doRequest(t, router, http.MethodGet, "/item/abc", nil, http.StatusBadRequest))
+	if fields(response.Violations) != "[id:out_of_range]" {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+
+	response = decode(doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":`), http.StatusBadRequest))
+	if fields(response.Violations) != "[body:malformed]" {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+
+	var batch BatchErrorResponse
+	recorder := doRequest(t, router, http.MethodPost, "/items:batch", []byte(`[{"name":"ok"},{"name":"","attributes":{"1":"x"}}]`), http.StatusBadRequest)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &batch); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(batch.Errors) != 1 || batch.Errors[0].Index != 1 || fields(batch.Errors[0].Violations) != "[name:required attributes.1:invalid]" {
+		t.Fatalf("unexpected response: %+v", batch)
+	}
+
+	// Ошибки без нарушений сохраняют прежний вид
+	recorder = doRequest(t, router, http.MethodGet, "/item/42", nil, http.StatusNotFound)
+	if strings.Contains(recorder.Body.String(), "violations") {
+		t.Fatalf("not found must not carry violations: %s", recorder.Body.String())
+	}
+}
+
+func TestIntegration_ProblemDetails(t *testing.T) {
+	router := SetupTestRout()
+
+	request := httptest.NewRequest(http.MethodGet, "/item/42", nil)
+	request.Header.Set("X-Request-ID", "trace-42")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("Expected code 404, got: %d", recorder.Code)
+	}
+	if ct := recorder.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("expected problem+json, got: %s", ct)
+	}
+	if id := recorder.Header().Get("X-Request-ID"); id != "trace-42" {
+		t.Fatalf("expected request id to be echoed, got: %s", id)
+	}
+
+	var response problemResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	expected := problemResponse{Type: "about:blank", Title: "Not Found", Status: 404, Detail: "not found", Instance: "/item/42", RequestID: "trace-42"}
+	if fmt.Sprint(response) != fmt.Sprint(expected) {
+		t.Fatalf("expected %+v, got: %+v", expected, response)
+	}
+
+	// Пакетные ошибки приходят в расширении errors
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"taken"}`), http.StatusCreated)
+	recorder = doRequest(t, router, http.MethodPost, "/items:batch", []byte(`[{"name":"taken"}]`), http.StatusConflict)
+	response = problemResponse{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if response.Type != ProblemBatchRejected || response.Status != http.StatusConflict || len(response.Errors) != 1 || response.RequestID == "" {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+
+	recorder = doRequest(t, router, http.MethodGet, "/no/such/route", nil, http.StatusNotFound)
+	if ct := recorder.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("unknown routes must answer with problem+json, got: %s", ct)
+	}
+}
+
+func TestIntegration_LegacyErrors(t *testing.T) {
+	router := NewRouter(domain.NewService(storage.NewMemoryStorage(domain.SystemClock{}), domain.SystemClock{}), WithLegacyErrors())
+
+	recorder := doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":""}`), http.StatusBadRequest)
+	if ct := recorder.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected application/json, got: %s", ct)
+	}
+
+	var response ErrorResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if response.Error != "validation failed" || len(response.Violations) != 1 {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+	if strings.Contains(recorder.Body.String(), `"type"`) {
+		t.Fatalf("legacy body must not contain problem members: %s", recorder.Body.String())
+	}
+
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"taken"}`), http.StatusCreated)
+	recorder = doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"taken"}`), http.StatusConflict)
+
+	var conflict ErrorResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &conflict); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if conflict.ExistingID != 1 {
+		t.Fatalf("expected existing_id=1, got: %s", recorder.Body.String())
+	}
+
+	recorder = doRequest(t, router, http.MethodPost, "/items:batch", []byte(`[{"name":"taken"}]`), http.StatusConflict)
+
+	var batch BatchErrorResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &batch); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if batch.Error != "batch rejected" || len(batch.Errors) != 1 || batch.Errors[0].ExistingID != 1 {
+		t.Fatalf("unexpected response: %+v", batch)
+	}
+}
+
+func TestIntegration_Webhooks(t *testing.T) {
+	delivered := make(chan *http.Request, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(webhook.EventHeader) == string(domain.EventItemDeleted) {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		delivered <- r
+	}))
+	defer receiver.Close()
+
+	bus := events.NewBus()
+	dispatcher := webhook.NewDispatcher(webhook.Config{MaxAttempts: 2, BaseBackoff: time.Millisecond, AllowPrivate: true})
+	bus.Subscribe("webhooks", dispatcher.Handle)
+	svc := domain.NewService(storage.NewMemoryStorage(domain.SystemClock{}), domain.SystemClock{}, domain.WithEventPublisher(bus))
+	router := NewRouter(svc, WithWebhooks(dispatcher))
+
+	doRequest(t, router, http.MethodPost, "/webhooks", []byte(`{"url":"ftp://x"}`), http.StatusBadRequest)
+
+	body := fmt.Sprintf(`{"url":%q,"events":["item.created","item.deleted"]}`, receiver.URL)
+	var created WebhookResult
+	if err := json.Unmarshal(doRequest(t, router, http.MethodPost, "/webhooks", []byte(body), http.StatusCreated).Body.Bytes(), &created); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if created.Webhook.ID != 1 || created.Webhook.Secret == "" || len(created.Webhook.Events) != 2 {
+		t.Fatalf("unexpected webhook: %+v", created.Webhook)
+	}
+
+	var list WebhooksResponse
+	if err := json.Unmarshal(doRequest(t, router, http.MethodGet, "/webhooks", nil, http.StatusOK).Body.Bytes(), &list); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(list.Webhooks) != 1 || list.Webhooks[0].Secret != "" {
+		t.Fatalf("expected one webhook without secret, got: %+v", list.Webhooks)
+	}
+
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"a"}`), http.StatusCreated)
+	doRequest(t, router, http.MethodPost, "/item/1/tags/red", nil, http.StatusOK) // item.updated не выбран
+	doRequest(t, router, http.MethodDelete, "/item/1", nil, http.StatusOK)
+
+	select {
+	case r := <-delivered:
+		if r.Header.Get(webhook.EventHeader) != string(domain.EventItemCreated) {
+			t.Fatalf("expected item.created, got %s", r.Header.Get(webhook.EventHeader))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+	if err := dispatcher.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	var letters DeadLettersResponse
+	if err := json.Unmarshal(doRequest(t, router, http.MethodGet, "/webhooks/dead-letters", nil, http.StatusOK).Body.Bytes(), &letters); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(letters.DeadLetters) != 1 || letters.DeadLetters[0].Event != string(domain.EventItemDeleted) || letters.DeadLetters[0].WebhookID != 1 {
+		t.Fatalf("expected rejected item.deleted in dead letters, got: %+v", letters.DeadLetters)
+	}
+
+	doRequest(t, router, http.MethodDelete, "/webhooks/1", nil, http.StatusOK)
+	doRequest(t, router, http.MethodDelete, "/webhooks/1", nil, http.StatusNotFound)
+}
+
+// sseFrame - одно событие text/event-stream; комментарии (heartbeat) - в comment.
+type sseFrame struct {
+	id, event, data, comment string
+}
+
+func readSSE(t *testing.T, url, lastEventID string) (<-chan sseFrame, func()) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if lastEventID != "" {
+		req.Header.Set(LastEventIDHeader, lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	frames := make(chan sseFrame, 100)
+	go func() {
+		defer close(frames)
+		scanner := bufio.NewScanner(resp.Body)
+		var frame sseFrame
+		for scanner.Scan() {
+			line := scanner.Text()
+			field, value, _ := strings.Cut(line, ": ")
+			switch field {
+			case "":
+				if line == "" && frame != (sseFrame{}) {
+					frames <- frame
+					frame = sseFrame{}
+				} else if line != "" {
+					frame.comment = value
+				}
+			case "id":
+				frame.id = value
+			case "event":
+				frame.event = value
+			case "data":
+				frame.data = value
+			}
+		}
+	}()
+	return frames, func() { resp.Body.Close() }
+}
+
+func nextEvent(t *testing.T, frames <-chan sseFrame) sseFrame {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case frame, ok := <-frames:
+			if !ok {
+				t.Fatal("stream closed")
+			}
+			if frame.event != "" {
+				return frame
+			}
+		case <-timeout:
+			t.Fatal("no event received")
+		}
+	}
+}
+
+func TestIntegration_EventStream(t *testing.T) {
+	bus := events.NewBus()
+	broker := stream.NewBroker(2, 8)
+	bus.Subscribe("stream", broker.Handle)
+	svc := domain.NewService(storage.NewMemoryStorage(domain.SystemClock{}), domain.SystemClock{}, domain.WithEventPublisher(bus))
+	router := NewRouter(svc, WithEventStream(broker, 20*time.Millisecond))
+
+	srv := httptest.NewUnstartedServer(router)
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+	defer broker.Close()
+	url := srv.URL + "/items/events"
+
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"a"}`), http.StatusCreated)
+
+	frames, closeStream := readSSE(t, url, "0")
+	frame := nextEvent(t, frames)
+	var event EventResponse
+	if err := json.Unmarshal([]byte(frame.data), &event); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if frame.id != "1" || frame.event != "item.created" || event.ItemID != 1 || event.Item == nil || event.Item.Name != "a" {
+		t.Fatalf("unexpected replayed event: %+v %+v", frame, event)
+	}
+
+	// Поток переживает WriteTimeout сервера и шлёт heartbeat
+	time.Sleep(3 * srv.Config.WriteTimeout)
+	doRequest(t, router, http.MethodDelete, "/item/1", nil, http.StatusOK)
+	heartbeats := 0
+	for frame = range frames {
+		if frame.comment == "heartbeat" {
+			heartbeats++
+		}
+		if frame.event != "" {
+			break
+		}
+	}
+	if frame.id != "2" || frame.event != "item.deleted" || heartbeats == 0 {
+		t.Fatalf("expected live item.deleted after heartbeats, got %+v (heartbeats=%d)", frame, heartbeats)
+	}
+	closeStream()
+
+	frames, closeStream = readSSE(t, url, "1")
+	if frame := nextEvent(t, frames); frame.id != "2" {
+		t.Fatalf("expected resume from id 2, got %+v", frame)
+	}
+	closeStream()
+
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"b"}`), http.StatusCreated)
+	frames, closeStream = readSSE(t, url, "0") // событие 1 уже вытеснено
+	if frame := nextEvent(t, frames); frame.event != "reset" || frame.id != "3" {
+		t.Fatalf("expected reset at id 3, got %+v", frame)
+	}
+	closeStream()
+
+	recorder := doConditional(t, router, http.MethodGet, "/items/events", map[string]string{LastEventIDHeader: "abc"}, nil, http.StatusBadRequest)
+	var problem problemResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(problem.Violations) != 1 || problem.Violations[0].Field != LastEventIDHeader {
+		t.Fatalf("unexpected violations: %+v", problem.Violations)
+	}
+}
+
+func TestIntegration_Changes(t *testing.T) {
+	st := storage.NewMemoryStorage(domain.SystemClock{})
+	svc := domain.NewService(st, domain.SystemClock{})
+	router := NewRouter(svc)
+
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"a"}`), http.StatusCreated)
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"b"}`), http.StatusCreated)
+	doRequest(t, router, http.MethodPut, "/item/1", []byte(`{"name":"a2"}`), http.StatusOK)
+	doRequest(t, router, http.MethodDelete, "/item/2", nil, http.StatusOK)
+
+	// Клиент синхронизации читает журнал страницами до конца
+	var (
+		types []string
+		since int64
+	)
+	for {
+		var page ChangesResponse
+		path := fmt.Sprintf("/changes?since=%d&limit=3", since)
+		if err := json.Unmarshal(doRequest(t, router, http.MethodGet, path, nil, http.StatusOK).Body.Bytes(), &page); err != nil {
+			t.Fatalf("Unexpected error json: %v", err)
+		}
+		for _, change := range page.Changes {
+			types = append(types, fmt.Sprintf("%d:%s", change.Revision, change.Type))
+			if (change.Type == "item.deleted") != (change.Item == nil) {
+				t.Fatalf("unexpected item in change: %+v", change)
+			}
+		}
+		since = page.NextSince
+		if !page.HasMore {
+			if page.Revision != 4 {
+				t.Fatalf("expected revision 4, got %d", page.Revision)
+			}
+			break
+		}
+	}
+	if fmt.Sprint(types) != "[1:item.created 2:item.created 3:item.updated 4:item.deleted]" {
+		t.Fatalf("unexpected changes: %v", types)
+	}
+
+	var empty ChangesResponse
+	if err := json.Unmarshal(doRequest(t, router, http.MethodGet, "/changes?since=4", nil, http.StatusOK).Body.Bytes(), &empty); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(empty.Changes) != 0 || empty.NextSince != 4 || empty.HasMore {
+		t.Fatalf("unexpected empty page: %+v", empty)
+	}
+
+	if n, err := st.CompactChanges(context.Background(), time.Now().Add(time.Minute)); err != nil || n != 4 {
+		t.Fatalf("expected 4 compacted changes, got %d, %v", n, err)
+	}
+	doRequest(t, router, http.MethodGet, "/changes?since=0", nil, http.StatusGone)
+	doRequest(t, router, http.MethodGet, "/changes?since=4", nil, http.StatusOK)
+	recorder := doRequest(t, router, http.MethodGet, "/changes?since=99", nil, http.StatusBadRequest)
+	var beyond problemResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &beyond); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(beyond.Violations) != 1 || beyond.Violations[0].Field != "since" || beyond.Violations[0].Code != domain.CodeOutOfRange {
+		t.Fatalf("expected since out_of_range violation, got: %+v", beyond.Violations)
+	}
+
+	recorder = doRequest(t, router, http.MethodGet, "/changes?since=-1&limit=x", nil, http.StatusBadRequest)
+	var problem problemResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(problem.Violations) != 2 {
+		t.Fatalf("expected since and limit violations, got: %+v", problem.Violations)
+	}
+}
+
+func TestIntegration_Audit(t *testing.T) {
+	router := NewRouter(domain.NewService(storage.NewMemoryStorage(domain.SystemClock{}), domain.SystemClock{}, domain.WithAuditSink(audit.NewMemorySink())))
+	headers := map[string]string{"X-Actor": "alice", "X-Request-ID": "req-42"}
+
+	doConditional(t, router, http.MethodPost, "/item", headers, []byte(`{"name":"a"}`), http.StatusCreated)
+	doRequest(t, router, http.MethodPut, "/item/1", []byte(`{"name":"b"}`), http.StatusOK)
+	doConditional(t, router, http.MethodDelete, "/item/1", headers, nil, http.StatusOK)
+
+	fetch := func(path string) AuditResponse {
+		t.Helper()
+		var res AuditResponse
+		if err := json.Unmarshal(doRequest(t, router, http.MethodGet, path, nil, http.StatusOK).Body.Bytes(), &res); err != nil {
+			t.Fatalf("Unexpected error json: %v", err)
+		}
+		return res
+	}
+
+	deleted := fetch("/audit?actor=alice&action=delete&item_id=1")
+	if len(deleted.Records) != 1 {
+		t.Fatalf("expected 1 record, got: %+v", deleted.Records)
+	}
+	rec := deleted.Records[0]
+	if rec.Before == nil || rec.Before.Name != "b" || rec.After != nil {
+		t.Fatalf("unexpected states: %+v %+v", rec.Before, rec.After)
+	}
+	if rec.RequestID != "req-42" || rec.ClientIP == "" {
+		t.Fatalf("unexpected request metadata: %+v", rec)
+	}
+
+	// Запрос без X-Actor записан на анонима
+	if anonymous := fetch("/audit?actor=anonymous"); len(anonymous.Records) != 1 || anonymous.Records[0].Action != "update" {
+		t.Fatalf("unexpected anonymous records: %+v", anonymous.Records)
+	}
+
+	first := fetch("/audit?limit=2")
+	if len(first.Records) != 2 || first.NextAfter != first.Records[1].ID {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+	if rest := fetch(fmt.Sprintf("/audit?after=%d", first.NextAfter)); len(rest.Records) != 1 || rest.NextAfter != 0 {
+		t.Fatalf("unexpected last page: %+v", rest)
+	}
+
+	recorder := doRequest(t, router, http.MethodGet, "/audit?from=yesterday&limit=x", nil, http.StatusBadRequest)
+	var problem problemResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(problem.Violations) != 2 {
+		t.Fatalf("expected limit and from violations, got: %+v", problem.Violations)
+	}
+	doRequest(t, router, http.MethodGet, "/audit?action=rename", nil, http.StatusBadRequest)
+}
+
+func TestIntegration_Tenants(t *testing.T) {
+	router := SetupTestRout()
+	acme := map[string]string{TenantHeader: "acme", IdempotencyKeyHeader: "same-key"}
+	globex := map[string]string{TenantHeader: "globex", IdempotencyKeyHeader: "same-key"}
+
+	// Один ключ идемпотентности у разных арендаторов - разные запросы
+	for _, headers := range []map[string]string{acme, globex} {
+		recorder := doConditional(t, router, http.MethodPost, "/item", headers, []byte(`{"name":"Alex"}`), http.StatusCreated)
+		if recorder.Header().Get(IdempotentReplayed) != "" {
+			t.Fatalf("%s: response must not be replayed from another tenant", headers[TenantHeader])
+		}
+		var res ResponseResult
+		if err := json.Unmarshal(recorder.Body.Bytes(), &res); err != nil {
+			t.Fatalf("Unexpected error json: %v", err)
+		}
+		if res.Item == nil || res.Item.ID != 1 {
+			t.Fatalf("%s: expected id 1, got %d", headers[TenantHeader], res.Item.ID)
+		}
+	}
+
+	doConditional(t, router, http.MethodDelete, "/item/1", acme, nil, http.StatusOK)
+	doConditional(t, router, http.MethodGet, "/item/1", acme, nil, http.StatusNotFound)
+	doConditional(t, router, http.MethodGet, "/item/1", globex, nil, http.StatusOK)
+	doRequest(t, router, http.MethodGet, "/item/1", nil, http.StatusNotFound) // без заголовка - арендатор по умолчанию
+
+	recorder := doConditional(t, router, http.MethodGet, "/items", map[string]string{TenantHeader: "Not Valid"}, nil, http.StatusBadRequest)
+	var problem problemResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(problem.Violations) != 1 || problem.Violations[0].Field != "tenant" {
+		t.Fatalf("expected tenant violation, got: %+v", problem.Violations)
+	}
+}
+
+func TestIntegration_Tree(t *testing.T) {
+	router := SetupTestRout()
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"root"}`), http.StatusCreated)
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"child","parent_id":1}`), http.StatusCreated)
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"leaf","parent_id":2}`), http.StatusCreated)
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"orphan","parent_id":42}`), http.StatusBadRequest)
+
+	recorder := doRequest(t, router, http.MethodGet, "/item/1/children", nil, http.StatusOK)
+	var children ListResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &children); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(children.Items) != 1 || children.Items[0].ID != 2 || children.Items[0].ParentID != 1 {
+		t.Fatalf("expected only direct child 2, got: %+v", children.Items)
+	}
+	doRequest(t, router, http.MethodGet, "/item/42/children", nil, http.StatusNotFound)
+
+	recorder = doRequest(t, router, http.MethodGet, "/item/1/tree?depth=1", nil, http.StatusOK)
+	var tree TreeResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &tree); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(tree.Tree.Children) != 1 || len(tree.Tree.Children[0].Children) != 0 {
+		t.Fatalf("expected one level of children, got: %+v", tree.Tree)
+	}
+	recorder = doRequest(t, router, http.MethodGet, "/item/1/tree", nil, http.StatusOK)
+	tree = TreeResponse{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &tree); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(tree.Tree.Children) != 1 || len(tree.Tree.Children[0].Children) != 1 || tree.Tree.Children[0].Children[0].ID != 3 {
+		t.Fatalf("expected full tree, got: %+v", tree.Tree)
+	}
+	doRequest(t, router, http.MethodGet, "/item/1/tree?depth=0", nil, http.StatusBadRequest)
+
+	// Элемент нельзя сделать потомком самого себя
+	doPatch(t, router, "/item/1", MergePatchContentType, []byte(`{"parent_id":3}`), http.StatusBadRequest)
+
+	doRequest(t, router, http.MethodDelete, "/item/1", nil, http.StatusConflict)
+	doRequest(t, router, http.MethodDelete, "/item/1?cascade=x", nil, http.StatusBadRequest)
+
+	// Перенос в корень выводит лист из-под удаляемого поддерева
+	doPatch(t, router, "/item/3", MergePatchContentType, []byte(`{"parent_id":null}`), http.StatusOK)
+
+	recorder = doRequest(t, router, http.MethodDelete, "/item/1?cascade=true", nil, http.StatusOK)
+	var deleted CascadeDeleteResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &deleted); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(deleted.Deleted) != 2 || deleted.Deleted[0] != 2 || deleted.Deleted[1] != 1 {
+		t.Fatalf("expected descendants deleted before ancestors, got: %v", deleted.Deleted)
+	}
+	doRequest(t, router, http.MethodGet, "/item/3", nil, http.StatusOK)
+
+	// Ребёнка нельзя восстановить раньше родителя
+	doRequest(t, router, http.MethodPost, "/item/2/restore", nil, http.StatusConflict)
+	doRequest(t, router, http.MethodPost, "/item/1/restore", nil, http.StatusOK)
+	doRequest(t, router, http.MethodPost, "/item/2/restore", nil, http.StatusOK)
+}
+
+func TestIntegration_Expiry(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	service := domain.NewService(storage.NewMemoryStorage(clock), clock)
+	router := NewRouter(service)
+
+	recorder := doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"booking","ttl":"15m"}`), http.StatusCreated)
+	var response ResponseResult
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if response.Item.ExpiresAt != "2024-05-01T10:15:00Z" {
+		t.Fatalf("expected expires_at from ttl, got: %q", response.Item.ExpiresAt)
+	}
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"seat","parent_id":1,"expires_at":"2024-05-01T10:10:00Z"}`), http.StatusCreated)
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"keep"}`), http.StatusCreated)
+
+	for _, body := range []string{
+		`{"name":"a","ttl":"soon"}`,
+		`{"name":"a","ttl":"-1m"}`,
+		`{"name":"a","expires_at":"tomorrow"}`,
+		`{"name":"a","expires_at":"2024-05-01T09:00:00Z"}`,
+		`{"name":"a","ttl":"1m","expires_at":"2024-05-01T11:00:00Z"}`,
+		`{"name":"a","parent_id":1}`, // бессрочный ребёнок пережил бы родителя
+	} {
+		doRequest(t, router, http.MethodPost, "/item", []byte(body), http.StatusBadRequest)
+	}
+
+	recorder = doRequest(t, router, http.MethodPost, "/items:batch", []byte(`[{"name":"b"},{"name":"c","ttl":"soon"}]`), http.StatusBadRequest)
+	var problem problemResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Index != 1 || problem.Errors[0].Violations[0].Field != "ttl" {
+		t.Fatalf("expected ttl violation at index 1, got: %+v", problem.Errors)
+	}
+
+	// Истёкший элемент пропадает сразу, до работы жнеца
+	clock.Advance(10 * time.Minute)
+	doRequest(t, router, http.MethodGet, "/item/2", nil, http.StatusNotFound)
+	doRequest(t, router, http.MethodGet, "/item/1", nil, http.StatusOK)
+	clock.Advance(5 * time.Minute)
+	doRequest(t, router, http.MethodGet, "/item/1", nil, http.StatusNotFound)
+	doRequest(t, router, http.MethodDelete, "/item/1", nil, http.StatusNotFound)
+	if items := listAll(t, router, 10); len(items) != 1 || items[0].Name != "keep" {
+		t.Fatalf("expected only the live item, got: %+v", items)
+	}
+
+	if reaped, err := service.ReapExpired(context.Background()); err != nil || reaped != 2 {
+		t.Fatalf("expected 2 reaped items, got: %d, %v", reaped, err)
+	}
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"booking"}`), http.StatusCreated)
+}
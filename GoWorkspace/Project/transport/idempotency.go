@@ -0,0 +1,264 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"Goworkspace/Project/domain"
+	"Goworkspace/Project/middleware"
+)
+
+const (
+	IdempotencyKeyHeader  = "Idempotency-Key"
+	IdempotentReplayed    = "Idempotent-Replayed" // выставляется в ответах, взятых из кэша
+	DefaultIdempotencyTTL = 24 * time.Hour
+	maxIdempotencyKey     = 255
+)
+
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request") // Ключ уже использован для другого запроса
+
+// IdempotencyStore хранит ответы на запросы с Idempotency-Key.
+// Ключ занимается первым запросом; повторы с тем же телом ждут его завершения
+// и получают сохранённый ответ. Ответ хранится ttl после завершения.
+type IdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+type idempotencyEntry struct {
+	fingerprint [sha256.Size]byte
+	done        chan struct{} // закрывается, когда ответ сохранён или ключ освобождён
+
+	// Заполняются до закрытия done
+	released  bool // запрос не дал ответа, который можно повторять: ключ свободен
+	status    int
+	header    http.Header
+	body      []byte
+	expiresAt time.Time
+}
+
+func NewIdempotencyStore(ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		entries: make(map[string]*idempotencyEntry),
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+// begin занимает ключ или возвращает уже существующую запись.
+// owner == true - вызывающий выполняет запрос и обязан вызвать complete или release.
+func (s *IdempotencyStore) begin(key string, fingerprint [sha256.Size]byte) (entry *idempotencyEntry, owner bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.entries[key]; ok && !s.expired(existing) {
+		if existing.fingerprint != fingerprint {
+			return nil, false, ErrIdempotencyKeyReused
+		}
+		return existing, false, nil
+	}
+
+	entry = &idempotencyEntry{fingerprint: fingerprint, done: make(chan struct{})}
+	s.entries[key] = entry
+	return entry, true, nil
+}
+
+func (s *IdempotencyStore) complete(entry *idempotencyEntry, status int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.status, entry.header, entry.body = status, header, body
+	entry.expiresAt = s.now().Add(s.ttl)
+	close(entry.done)
+}
+
+// release освобождает ключ: ожидающие повторы выполнят запрос заново.
+func (s *IdempotencyStore) release(key string, entry *idempotencyEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.released = true
+	if s.entries[key] == entry {
+		delete(s.entries, key)
+	}
+	close(entry.done)
+}
+
+// expired вызывается под s.mu. Незавершённые записи не истекают.
+func (s *IdempotencyStore) expired(entry *idempotencyEntry) bool {
+	select {
+	case <-entry.done:
+		return !s.now().Before(entry.expiresAt)
+	default:
+		return false
+	}
+}
+
+// Sweep удаляет истёкшие ключи и возвращает их число.
+func (s *IdempotencyStore) Sweep() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for key, entry := range s.entries {
+		if s.expired(entry) {
+			delete(s.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// RunSweeper периодически вызывает Sweep до отмены ctx.
+func (s *IdempotencyStore) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if removed := s.Sweep(); removed > 0 {
+				log.Printf("[INFO]: idempotency sweeper: removed %d keys", removed)
+			}
+		}
+	}
+}
+
+// Idempotent оборачивает next поддержкой заголовка Idempotency-Key.
+// Запрос без заголовка выполняется как обычно. Ответы 5xx не сохраняются,
+// чтобы клиент мог повторить запрос после временного сбоя.
+func Idempotent(store *IdempotencyStore, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			HelperError(w, r, domain.NewValidationError(IdempotencyKeyHeader, domain.CodeTooLong, "must be at most 255 characters", domain.ErrInvalidValue))
+			return
+		}
+		key = domain.TenantFromContext(r.Context()) + "/" + key // у каждого арендатора свои ключи
+
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			HelperError(w, r, domain.NewValidationError("body", domain.CodeMalformed, err.Error(), domain.ErrBadRequest))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := requestFingerprint(r, body)
+
+		for {
+			entry, owner, err := store.begin(key, fingerprint)
+			if err != nil {
+				HelperError(w, r, err)
+				return
+			}
+
+			if owner {
+				serveAndRecord(store, key, entry, next, w, r)
+				return
+			}
+
+			// Тот же запрос уже выполняется или выполнен: ждём и повторяем его ответ
+			select {
+			case <-entry.done:
+			case <-r.Context().Done():
+				HelperError(w, r, r.Context().Err())
+				return
+			}
+			if entry.released {
+				continue // первый запрос не дал ответа - пробуем занять ключ сами
+			}
+
+			replay(w, entry)
+			log.Printf("[INFO]: %s %s: replayed idempotent response: status=%d", r.Method, r.URL.Path, entry.status)
+			return
+		}
+	}
+}
+
+func serveAndRecord(store *IdempotencyStore, key string, entry *idempotencyEntry, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	rec := &recordingWriter{ResponseWriter: w}
+	recorded := false
+	defer func() {
+		// При панике ключ освобождается, паника уходит дальше в RecoveryMiddleware
+		if !recorded {
+			store.release(key, entry)
+		}
+	}()
+
+	next.ServeHTTP(rec, r)
+
+	status := rec.statusCode()
+	if status >= http.StatusInternalServerError {
+		return
+	}
+	store.complete(entry, status, w.Header().Clone(), rec.body.Bytes())
+	recorded = true
+}
+
+func replay(w http.ResponseWriter, entry *idempotencyEntry) {
+	for name, values := range entry.header {
+		if name == http.CanonicalHeaderKey(middleware.RequestIDHeader) {
+			continue // у повтора свой ID запроса
+		}
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotentReplayed, "true")
+	w.WriteHeader(entry.status)
+	w.Write(entry.body)
+}
+
+// requestFingerprint различает запросы с одним ключом: метод, путь и тело.
+func requestFingerprint(r *http.Request, body []byte) [sha256.Size]byte {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// recordingWriter пропускает ответ клиенту и запоминает его копию.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *recordingWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
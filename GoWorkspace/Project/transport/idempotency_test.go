@@ -0,0 +1,208 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Goworkspace/Project/domain"
+	"Goworkspace/Project/storage"
+)
+
+func postWithKey(t *testing.T, router http.Handler, key, body string, expectedCode int) *httptest.ResponseRecorder {
+	t.Helper()
+
+	request := httptest.NewRequest(http.MethodPost, "/item", bytes.NewReader([]byte(body)))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(IdempotencyKeyHeader, key)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != expectedCode {
+		t.Fatalf("Expected code %d, got: %d (%s)", expectedCode, recorder.Code, recorder.Body.String())
+	}
+	return recorder
+}
+
+func TestIdempotency_Replay(t *testing.T) {
+	router := SetupTestRout()
+
+	first := postWithKey(t, router, "key-1", `{"name":"Alex"}`, http.StatusCreated)
+	second := postWithKey(t, router, "key-1", `{"name":"Alex"}`, http.StatusCreated)
+
+	if first.Body.String() != second.Body.String() || second.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Fatalf("replay must repeat the response, got: %s / %s", first.Body.String(), second.Body.String())
+	}
+	if second.Header().Get(IdempotentReplayed) != "true" || first.Header().Get(IdempotentReplayed) != "" {
+		t.Fatal("only the replayed response must be marked")
+	}
+	if second.Header().Get("X-Request-ID") == first.Header().Get("X-Request-ID") {
+		t.Fatal("replayed response must carry its own request id")
+	}
+
+	if items := listAll(t, router, 10); len(items) != 1 {
+		t.Fatalf("expected a single item, got: %+v", items)
+	}
+
+	// Другое тело с тем же ключом - ошибка клиента
+	postWithKey(t, router, "key-1", `{"name":"Bob"}`, http.StatusUnprocessableEntity)
+
+	// Без ключа повтор создаёт дубликат имени, как и раньше
+	doRequest(t, router, http.MethodPost, "/item", []byte(`{"name":"Alex"}`), http.StatusConflict)
+
+	// Ответы 4xx тоже повторяются
+	postWithKey(t, router, "key-2", `{"name":""}`, http.StatusBadRequest)
+	replayed := postWithKey(t, router, "key-2", `{"name":""}`, http.StatusBadRequest)
+	if replayed.Header().Get(IdempotentReplayed) != "true" {
+		t.Fatal("client errors must be replayed")
+	}
+}
+
+func TestIdempotency_ConcurrentRequestsWaitForFirst(t *testing.T) {
+	router := SetupTestRout()
+
+	const workers = 20
+	var wg sync.WaitGroup
+	bodies := make([]string, workers)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			request := httptest.NewRequest(http.MethodPost, "/item", bytes.NewReader([]byte(`{"name":"same"}`)))
+			request.Header.Set(IdempotencyKeyHeader, "shared")
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != http.StatusCreated {
+				t.Errorf("Expected code 201, got: %d", recorder.Code)
+			}
+			bodies[i] = recorder.Body.String()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, body := range bodies[1:] {
+		if body != bodies[0] {
+			t.Fatalf("all responses must be identical, got: %s / %s", bodies[0], body)
+		}
+	}
+	if items := listAll(t, router, 10); len(items) != 1 {
+		t.Fatalf("expected a single item, got: %+v", items)
+	}
+}
+
+func TestIdempotency_FailuresReleaseKey(t *testing.T) {
+	t.Run("Server error is not cached", func(t *testing.T) {
+		var calls atomic.Int32
+		handler := Idempotent(NewIdempotencyStore(time.Hour), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		postWithKey(t, handler, "k", `{}`, http.StatusServiceUnavailable)
+		postWithKey(t, handler, "k", `{}`, http.StatusCreated)
+		postWithKey(t, handler, "k", `{}`, http.StatusCreated)
+
+		if calls.Load() != 2 {
+			t.Fatalf("expected 2 executions, got: %d", calls.Load())
+		}
+	})
+
+	t.Run("Panic releases key", func(t *testing.T) {
+		var calls atomic.Int32
+		handler := Idempotent(NewIdempotencyStore(time.Hour), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				panic("boom")
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected panic to propagate")
+				}
+			}()
+			postWithKey(t, handler, "k", `{}`, http.StatusCreated)
+		}()
+
+		postWithKey(t, handler, "k", `{}`, http.StatusCreated)
+	})
+
+	t.Run("Waiter gives up with its context", func(t *testing.T) {
+		release := make(chan struct{})
+		started := make(chan struct{})
+		handler := Idempotent(NewIdempotencyStore(time.Hour), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		first := make(chan int)
+		go func() {
+			request := httptest.NewRequest(http.MethodPost, "/item", bytes.NewReader([]byte(`{}`)))
+			request.Header.Set(IdempotencyKeyHeader, "k")
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			first <- recorder.Code
+		}()
+		<-started
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		request := httptest.NewRequest(http.MethodPost, "/item", bytes.NewReader([]byte(`{}`))).WithContext(ctx)
+		request.Header.Set(IdempotencyKeyHeader, "k")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		close(release)
+		if code := <-first; code != http.StatusCreated {
+			t.Fatalf("Expected code 201 for the first request, got: %d", code)
+		}
+		if recorder.Code == http.StatusCreated {
+			t.Fatal("canceled waiter must not get the response")
+		}
+	})
+}
+
+func TestIdempotency_TTL(t *testing.T) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewIdempotencyStore(time.Hour)
+	store.now = clock.Now
+
+	router := NewRouter(domain.NewService(storage.NewMemoryStorage(domain.SystemClock{}), domain.SystemClock{}), WithIdempotencyStore(store))
+
+	postWithKey(t, router, "k", `{"name":"a"}`, http.StatusCreated)
+
+	clock.Advance(59 * time.Minute)
+	if removed := store.Sweep(); removed != 0 {
+		t.Fatalf("key must live until TTL, removed: %d", removed)
+	}
+	postWithKey(t, router, "k", `{"name":"b"}`, http.StatusUnprocessableEntity)
+
+	clock.Advance(time.Minute)
+	if removed := store.Sweep(); removed != 1 {
+		t.Fatalf("expected expired key to be removed, got: %d", removed)
+	}
+
+	recorder := postWithKey(t, router, "k", `{"name":"b"}`, http.StatusCreated)
+	var response ResponseResult
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unexpected error json: %v", err)
+	}
+	if response.Item.Name != "b" {
+		t.Fatalf("expired key must allow a new request, got: %+v", response.Item)
+	}
+}
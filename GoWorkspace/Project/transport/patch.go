@@ -0,0 +1,47 @@
+package transport
+
+import (
+	"encoding/json"
+
+	"Goworkspace/Project/domain"
+)
+
+const MergePatchContentType = "application/merge-patch+json"
+
+// ApplyMergePatch применяет patch к JSON-документу target по правилам RFC 7396.
+func ApplyMergePatch(target, patch []byte) ([]byte, error) {
+	var targetDoc, patchDoc any
+
+	if err := json.Unmarshal(target, &targetDoc); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return nil, domain.NewValidationError("body", domain.CodeMalformed, err.Error(), domain.ErrBadRequest)
+	}
+
+	return json.Marshal(mergePatch(targetDoc, patchDoc))
+}
+
+// mergePatch: объекты сливаются рекурсивно, null удаляет ключ,
+// любое другое значение заменяет целевое целиком.
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = make(map[string]any)
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+
+	return targetObj
+}
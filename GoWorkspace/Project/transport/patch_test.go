@@ -0,0 +1,47 @@
+package transport
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	// Примеры из приложения A RFC 7396
+	cases := []struct {
+		target, patch, expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+	}
+
+	for _, tc := range cases {
+		result, err := ApplyMergePatch([]byte(tc.target), []byte(tc.patch))
+		if err != nil {
+			t.Fatalf("target %s patch %s: unexpected error: %v", tc.target, tc.patch, err)
+		}
+
+		var got, expected any
+		json.Unmarshal(result, &got)
+		json.Unmarshal([]byte(tc.expected), &expected)
+
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("target %s patch %s: expected %s, got %s", tc.target, tc.patch, tc.expected, result)
+		}
+	}
+}
+
+func TestApplyMergePatch_InvalidPatch(t *testing.T) {
+	if _, err := ApplyMergePatch([]byte(`{"a":"b"}`), []byte(`{"a":`)); err == nil {
+		t.Fatal("expected error for malformed patch")
+	}
+}
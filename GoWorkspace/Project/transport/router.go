@@ -3,21 +3,109 @@ package transport
 import (
 	"Goworkspace/Project/domain"
 	"Goworkspace/Project/middleware"
+	"Goworkspace/Project/stream"
+	"Goworkspace/Project/webhook"
+	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
-func NewRouter(service *domain.Service) *chi.Mux {
+type routerConfig struct {
+	legacyErrors bool
+	idempotency  *IdempotencyStore
+	webhooks     *webhook.Dispatcher
+	stream       *stream.Broker
+	heartbeat    time.Duration
+}
+
+type RouterOption func(*routerConfig)
+
+// WithLegacyErrors возвращает ошибки в прежнем виде {"error": ...} вместо application/problem+json.
+func WithLegacyErrors() RouterOption {
+	return func(cfg *routerConfig) { cfg.legacyErrors = true }
+}
+
+// WithIdempotencyStore задаёт хранилище ключей Idempotency-Key для POST /item.
+// Без него используется хранилище с DefaultIdempotencyTTL, которое чистится
+// только при повторном обращении к ключу.
+func WithIdempotencyStore(store *IdempotencyStore) RouterOption {
+	return func(cfg *routerConfig) { cfg.idempotency = store }
+}
+
+// WithWebhooks включает управление подписками /webhooks. Без него маршрутов нет.
+func WithWebhooks(d *webhook.Dispatcher) RouterOption {
+	return func(cfg *routerConfig) { cfg.webhooks = d }
+}
+
+// WithEventStream включает поток GET /items/events. heartbeat <= 0 - DefaultHeartbeat.
+func WithEventStream(broker *stream.Broker, heartbeat time.Duration) RouterOption {
+	return func(cfg *routerConfig) { cfg.stream, cfg.heartbeat = broker, heartbeat }
+}
+
+func NewRouter(service *domain.Service, opts ...RouterOption) *chi.Mux {
+	var cfg routerConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.idempotency == nil {
+		cfg.idempotency = NewIdempotencyStore(DefaultIdempotencyTTL)
+	}
+	if cfg.heartbeat <= 0 {
+		cfg.heartbeat = DefaultHeartbeat
+	}
+
 	r := chi.NewRouter()
 
+	r.Use(middleware.RequestIDMiddleware)
+	r.Use(middleware.ActorMiddleware)
+	if cfg.legacyErrors {
+		r.Use(middleware.LegacyErrorsMiddleware)
+	}
 	r.Use(middleware.RecoveryMiddleware)
 	r.Use(middleware.LoggingMiddleware)
-	r.Use(middleware.TimeoutMiddleware(60 * time.Second))
+	r.Use(TenantMiddleware)
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		WriteError(w, r, http.StatusNotFound, ErrorResponse{Error: "not found"})
+	})
+
+	// Поток открыт, пока подключён клиент, поэтому он вне TimeoutMiddleware
+	if cfg.stream != nil {
+		r.Get("/items/events", EventsHandler(cfg.stream, cfg.heartbeat))
+	}
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.TimeoutMiddleware(60 * time.Second))
+
+		r.Post("/item", Idempotent(cfg.idempotency, PostHandler(service)))
+		r.Get("/item/{id}", GetHandler(service))
+		r.Put("/item/{id}", PutHandler(service))
+		r.Patch("/item/{id}", PatchHandler(service))
+		r.Delete("/item/{id}", DeleteHandler(service))
+		r.Get("/item/{id}/children", ChildrenHandler(service))
+		r.Get("/item/{id}/tree", TreeHandler(service))
+		r.Get("/items", ListHandler(service))
+		r.Post("/items:batch", BatchCreateHandler(service))
+		r.Post("/items:batchDelete", BatchDeleteHandler(service))
+
+		r.Get("/trash", TrashHandler(service))
+		r.Post("/item/{id}/restore", RestoreHandler(service))
+
+		r.Get("/changes", ChangesHandler(service))
+		r.Get("/audit", AuditHandler(service))
+
+		r.Get("/tags", TagsHandler(service))
+		r.Post("/item/{id}/tags/{tag}", AddTagHandler(service))
+		r.Delete("/item/{id}/tags/{tag}", RemoveTagHandler(service))
 
-	r.Post("/item", PostHandler(service))
-	r.Get("/item/{id}", GetHandler(service))
-	r.Delete("/item/{id}", DeleteHandler(service))
+		if cfg.webhooks != nil {
+			r.Post("/webhooks", CreateWebhookHandler(cfg.webhooks))
+			r.Get("/webhooks", WebhooksHandler(cfg.webhooks))
+			r.Delete("/webhooks/{id}", DeleteWebhookHandler(cfg.webhooks))
+			r.Get("/webhooks/dead-letters", DeadLettersHandler(cfg.webhooks))
+		}
+	})
 
 	return r
 }
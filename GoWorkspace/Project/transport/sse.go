@@ -0,0 +1,119 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"Goworkspace/Project/domain"
+	"Goworkspace/Project/middleware"
+	"Goworkspace/Project/stream"
+)
+
+const (
+	LastEventIDHeader = "Last-Event-ID"
+	LastEventIDQuery  = "last_event_id" // для клиентов, которые не могут задать заголовок
+	DefaultHeartbeat  = 15 * time.Second
+	sseRetry          = 3 * time.Second // пауза перед переподключением EventSource
+)
+
+// EventsHandler отдаёт события элементов как text/event-stream.
+// С Last-Event-ID поток продолжается с пропущенных событий; если они уже вытеснены,
+// первым приходит событие reset: клиенту нужно заново загрузить состояние.
+func EventsHandler(broker *stream.Broker, heartbeat time.Duration) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastID, resume, err := parseLastEventID(r)
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		// Поток живёт дольше WriteTimeout сервера
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			log.Printf("[ERROR]: %s %s: clear write deadline: %v", r.Method, r.URL.Path, err)
+		}
+
+		client, replay := broker.Subscribe(domain.TenantFromContext(r.Context()), lastID, resume)
+		defer broker.Unsubscribe(client)
+
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no") // иначе прокси копит поток
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+		if !replay.Complete {
+			fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {}\n\n", replay.LastID)
+		}
+		for _, msg := range replay.Messages {
+			if err := writeEvent(w, msg); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			log.Printf("[ERROR]: %s %s: flush: %v", r.Method, r.URL.Path, err)
+			return
+		}
+
+		log.Printf("[INFO]: %s %s: stream opened: last_id=%d replayed=%d request_id=%s", r.Method, r.URL.Path, replay.LastID, len(replay.Messages), middleware.RequestID(r.Context()))
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			var err error
+			select {
+			case <-r.Context().Done():
+				return
+			case <-client.Done():
+				if client.Slow() {
+					log.Printf("[WARN]: %s %s: slow consumer disconnected: request_id=%s", r.Method, r.URL.Path, middleware.RequestID(r.Context()))
+				}
+				return
+			case msg := <-client.Messages():
+				err = writeEvent(w, msg)
+			case <-ticker.C:
+				_, err = io.WriteString(w, ": heartbeat\n\n")
+			}
+			if err == nil {
+				err = rc.Flush()
+			}
+			if err != nil {
+				return // клиент отключился
+			}
+		}
+	})
+}
+
+func writeEvent(w io.Writer, msg stream.Message) error {
+	data, err := json.Marshal(NewEventResponse(msg.Event))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.ID, msg.Event.EventType(), data)
+	return err
+}
+
+// parseLastEventID читает номер последнего полученного события. resume == false - номера нет.
+func parseLastEventID(r *http.Request) (lastID uint64, resume bool, err error) {
+	field, value := LastEventIDHeader, r.Header.Get(LastEventIDHeader)
+	if value == "" {
+		field, value = LastEventIDQuery, r.URL.Query().Get(LastEventIDQuery)
+	}
+	if value == "" {
+		return 0, false, nil
+	}
+
+	lastID, err = strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, domain.NewValidationError(field, domain.CodeInvalid, "must be a non-negative integer", domain.ErrInvalidValue)
+	}
+	return lastID, true, nil
+}
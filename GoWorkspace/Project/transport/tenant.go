@@ -0,0 +1,26 @@
+package transport
+
+import (
+	"net/http"
+
+	"Goworkspace/Project/domain"
+)
+
+const TenantHeader = "X-Tenant-ID"
+
+// TenantMiddleware кладёт в контекст арендатора из X-Tenant-ID; без заголовка - domain.DefaultTenant.
+// Неверное имя отклоняется, а не заменяется: иначе запрос записал бы данные чужому арендатору.
+func TenantMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := r.Header.Get(TenantHeader)
+		if tenant == "" {
+			tenant = domain.DefaultTenant
+		}
+		if err := domain.ValidateTenant(tenant); err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(domain.ContextWithTenant(r.Context(), tenant)))
+	})
+}
@@ -0,0 +1,81 @@
+package transport
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"Goworkspace/Project/domain"
+)
+
+// parseCascade читает параметр cascade DELETE: true удаляет элемент вместе с потомками,
+// false (по умолчанию) отказывает, если у элемента есть дети.
+func parseCascade(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("cascade")
+	if value == "" {
+		return false, nil
+	}
+	cascade, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, domain.NewValidationError("cascade", domain.CodeInvalid, "must be true or false", domain.ErrInvalidValue)
+	}
+	return cascade, nil
+}
+
+// ChildrenHandler отдаёт страницу детей элемента. Параметры - как у GET /items.
+func ChildrenHandler(src *domain.Service) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqID, err := ParseID(r)
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		query, err := ParseListQuery(r)
+		if err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		page, err := src.Children(r.Context(), reqID, query)
+		if err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		res := ListResponse{Items: NewItemsResponse(page.Items), NextCursor: page.NextCursor, Status: "Children OK"}
+		WriteJSON(w, r, http.StatusOK, res)
+
+		log.Printf("[INFO]: %s %s: successful: id=%d count=%d", r.Method, r.URL.Path, reqID, len(page.Items))
+	})
+}
+
+// TreeHandler отдаёт элемент с вложенными потомками; depth ограничивает число уровней.
+func TreeHandler(src *domain.Service) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqID, err := ParseID(r)
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		depth := 0
+		if value := r.URL.Query().Get("depth"); value != "" {
+			depth, err = strconv.Atoi(value)
+			if err != nil || depth < 1 {
+				HelperError(w, r, domain.NewValidationError("depth", domain.CodeOutOfRange, "must be a positive integer", domain.ErrInvalidValue), reqID)
+				return
+			}
+		}
+
+		tree, err := src.Subtree(r.Context(), reqID, depth)
+		if err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		WriteJSON(w, r, http.StatusOK, TreeResponse{Tree: NewTreeNodeResponse(tree), Status: "Tree OK"})
+
+		log.Printf("[INFO]: %s %s: successful: id=%d", r.Method, r.URL.Path, reqID)
+	})
+}
@@ -2,17 +2,87 @@ package transport
 
 import (
 	"Goworkspace/Project/domain"
+	"Goworkspace/Project/middleware"
+	"Goworkspace/Project/problem"
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
 )
 
+const AttributeQueryPrefix = "attr." // ?attr.<ключ>=<значение> - фильтр списка по атрибуту
+
+// Типы проблем со своей семантикой; остальные ошибки отдаются с типом about:blank.
+const (
+	ProblemValidation    = "/problems/validation-failed" // в расширении violations - все нарушения
+	ProblemBatchRejected = "/problems/batch-rejected"    // в расширении errors - ошибки элементов пакета
+	ProblemAlreadyExists = "/problems/already-exists"    // в расширении existing_id - ID элемента с тем же именем
+)
+
+var ErrUnsupportedMediaType = errors.New("unsupported media type") // Неподдерживаемый Content-Type
+
+// AttributeFilter собирает фильтр по атрибутам из параметров вида attr.<ключ>=<значение>.
+// Несколько значений одного ключа ничего не могут найти и считаются ошибкой запроса.
+func AttributeFilter(query url.Values) (map[string]string, error) {
+	var attrs map[string]string
+	for param, values := range query {
+		key, ok := strings.CutPrefix(param, AttributeQueryPrefix)
+		if !ok {
+			continue
+		}
+		if len(values) != 1 {
+			return nil, domain.NewValidationError(param, domain.CodeConflict, fmt.Sprintf("given %d times", len(values)), domain.ErrInvalidValue)
+		}
+		if attrs == nil {
+			attrs = make(map[string]string)
+		}
+		attrs[key] = values[0]
+	}
+	return attrs, nil
+}
+
 func DecodeJSONBody(r *http.Request, dst any) error {
-	decoder := json.NewDecoder(r.Body)
 	defer r.Body.Close()
+	return decodeJSON(r.Body, dst)
+}
+
+func DecodeJSONBytes(data []byte, dst any) error {
+	return decodeJSON(bytes.NewReader(data), dst)
+}
+
+func decodeJSON(src io.Reader, dst any) error {
+	decoder := json.NewDecoder(src)
 	decoder.DisallowUnknownFields()
-	return decoder.Decode(dst)
+	if err := decoder.Decode(dst); err != nil {
+		return domain.NewValidationError("body", domain.CodeMalformed, err.Error(), domain.ErrBadRequest)
+	}
+	return nil
+}
+
+// ParseID читает положительный ID из параметра пути {id}.
+func ParseID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id < 1 {
+		return 0, domain.NewValidationError("id", domain.CodeOutOfRange, "must be a positive integer", domain.ErrInvalidValue)
+	}
+	return id, nil
+}
+
+func RequireContentType(r *http.Request, contentType string) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != contentType {
+		return fmt.Errorf("%w: expected %s", ErrUnsupportedMediaType, contentType)
+	}
+	return nil
 }
 
 func WriteJSON(w http.ResponseWriter, r *http.Request, status int, data any) {
@@ -25,35 +95,108 @@ func WriteJSON(w http.ResponseWriter, r *http.Request, status int, data any) {
 	}
 }
 
-func WriteError(w http.ResponseWriter, r *http.Request, status int, msg string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(ErrorResponse{Error: msg}); err != nil {
-		log.Printf("[ERROR]: %s: %v", r.URL.Path, err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+// WriteError отвечает в формате application/problem+json, а при включённом
+// прежнем формате (WithLegacyErrors) - объектом ErrorResponse.
+func WriteError(w http.ResponseWriter, r *http.Request, status int, res ErrorResponse) {
+	if middleware.LegacyErrors(r.Context()) {
+		WriteJSON(w, r, status, res)
 		return
 	}
 
+	details := NewProblem(r, status, res.Error)
+	if len(res.Violations) > 0 {
+		details.Type = ProblemValidation
+		details.Extensions = map[string]any{"violations": res.Violations}
+	}
+	if res.ExistingID != 0 {
+		details.Type = ProblemAlreadyExists
+		details.Extensions = map[string]any{"existing_id": res.ExistingID}
+	}
+	WriteProblem(w, r, details)
+}
+
+// NewProblem заполняет instance и request_id из запроса.
+func NewProblem(r *http.Request, status int, detail string) problem.Details {
+	details := problem.New(status, detail)
+	details.Instance = r.URL.Path
+	details.RequestID = middleware.RequestID(r.Context())
+	return details
+}
+
+func WriteProblem(w http.ResponseWriter, r *http.Request, details problem.Details) {
+	if err := problem.Write(w, details); err != nil {
+		log.Printf("[ERROR]: %s: %v", r.URL.Path, err)
+	}
 }
 
 func HelperError(w http.ResponseWriter, r *http.Request, err error, id ...int) {
-	status, strState := MapDomainErrorToHTTP(err)
+	status, res := MapDomainErrorToHTTP(err)
+	requestID := middleware.RequestID(r.Context())
 	if len(id) > 0 {
-		log.Printf("[ERROR]: %s %s id=%d: %v", r.Method, r.URL.Path, id[0], err)
+		log.Printf("[ERROR]: %s %s id=%d request_id=%s: %v", r.Method, r.URL.Path, id[0], requestID, err)
 	} else {
-		log.Printf("[ERROR]: %s %s: %v", r.Method, r.URL.Path, err)
+		log.Printf("[ERROR]: %s %s request_id=%s: %v", r.Method, r.URL.Path, requestID, err)
 	}
-	WriteError(w, r, status, strState)
+
+	var batchErr *domain.BatchError
+	if errors.As(err, &batchErr) {
+		batchRes := NewBatchErrorResponse(res.Error, batchErr)
+		if middleware.LegacyErrors(r.Context()) {
+			WriteJSON(w, r, status, batchRes)
+			return
+		}
+
+		details := NewProblem(r, status, res.Error)
+		details.Type = ProblemBatchRejected
+		details.Extensions = map[string]any{"errors": batchRes.Errors}
+		WriteProblem(w, r, details)
+		return
+	}
+
+	WriteError(w, r, status, res)
 }
-func MapDomainErrorToHTTP(err error) (int, string) {
+
+// MapDomainErrorToHTTP выбирает статус и тело ответа для ошибки.
+// Для ValidationError в тело попадают все нарушения.
+func MapDomainErrorToHTTP(err error) (int, ErrorResponse) {
+	var (
+		batchErr      *domain.BatchError
+		existsErr     *domain.AlreadyExistsError
+		validationErr *domain.ValidationError
+	)
+
 	switch {
+	case errors.As(err, &batchErr):
+		// Ошибки валидации важнее конфликтов: пакет всё равно нужно исправить
+		if errors.Is(err, domain.ErrEmptyName) || errors.Is(err, domain.ErrInvalidValue) || errors.Is(err, domain.ErrBadRequest) {
+			return http.StatusBadRequest, ErrorResponse{Error: "batch rejected"}
+		}
+		return http.StatusConflict, ErrorResponse{Error: "batch rejected"}
+	case errors.As(err, &validationErr):
+		return http.StatusBadRequest, ErrorResponse{Error: "validation failed", Violations: NewViolationsResponse(validationErr)}
+	case errors.As(err, &existsErr):
+		return http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("already exists: id=%d", existsErr.ID), ExistingID: existsErr.ID}
+	case errors.Is(err, domain.ErrAlreadyExists):
+		return http.StatusConflict, ErrorResponse{Error: "already exists"}
 	case errors.Is(err, domain.ErrEmptyName),
 		errors.Is(err, domain.ErrBadRequest),
 		errors.Is(err, domain.ErrInvalidValue):
-		return http.StatusBadRequest, "bad request"
+		return http.StatusBadRequest, ErrorResponse{Error: "bad request"}
 	case errors.Is(err, domain.ErrNotFound):
-		return http.StatusNotFound, "not found"
+		return http.StatusNotFound, ErrorResponse{Error: "not found"}
+	case errors.Is(err, domain.ErrHasChildren):
+		return http.StatusConflict, ErrorResponse{Error: "item has children: delete with cascade=true"}
+	case errors.Is(err, domain.ErrParentDeleted):
+		return http.StatusConflict, ErrorResponse{Error: "parent is deleted: restore it first"}
+	case errors.Is(err, domain.ErrVersionConflict):
+		return http.StatusPreconditionFailed, ErrorResponse{Error: "precondition failed"}
+	case errors.Is(err, domain.ErrRevisionCompacted):
+		return http.StatusGone, ErrorResponse{Error: "revision compacted: full resync required"}
+	case errors.Is(err, ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType, ErrorResponse{Error: "unsupported media type"}
+	case errors.Is(err, ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity, ErrorResponse{Error: ErrIdempotencyKeyReused.Error()}
 	default:
-		return http.StatusInternalServerError, "internal server error"
+		return http.StatusInternalServerError, ErrorResponse{Error: "internal server error"}
 	}
 }
@@ -0,0 +1,74 @@
+package transport
+
+import (
+	"log"
+	"net/http"
+
+	"Goworkspace/Project/domain"
+	"Goworkspace/Project/webhook"
+)
+
+func CreateWebhookHandler(d *webhook.Dispatcher) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req WebhookRequest
+
+		if err := DecodeJSONBody(r, &req); err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		events := make([]domain.EventType, 0, len(req.Events))
+		for _, t := range req.Events {
+			events = append(events, domain.EventType(t))
+		}
+
+		sub, err := d.Subscribe(webhook.Subscription{Tenant: domain.TenantFromContext(r.Context()), URL: req.URL, Secret: req.Secret, Events: events})
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		res := NewWebhookResponse(sub)
+		res.Secret = sub.Secret
+		WriteJSON(w, r, http.StatusCreated, WebhookResult{Webhook: res, Status: "Webhook created"})
+
+		log.Printf("[INFO]: %s %s: successful: id=%d", r.Method, r.URL.Path, sub.ID)
+	})
+}
+
+func WebhooksHandler(d *webhook.Dispatcher) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subs := d.Subscriptions(domain.TenantFromContext(r.Context()))
+		WriteJSON(w, r, http.StatusOK, NewWebhooksResponse(subs))
+
+		log.Printf("[INFO]: %s %s: successful: count=%d", r.Method, r.URL.Path, len(subs))
+	})
+}
+
+func DeleteWebhookHandler(d *webhook.Dispatcher) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqID, err := ParseID(r)
+		if err != nil {
+			HelperError(w, r, err)
+			return
+		}
+
+		if err := d.Unsubscribe(domain.TenantFromContext(r.Context()), reqID); err != nil {
+			HelperError(w, r, err, reqID)
+			return
+		}
+
+		WriteJSON(w, r, http.StatusOK, WebhookResult{Status: "Webhook deleted"})
+
+		log.Printf("[INFO]: %s %s: successful: id=%d", r.Method, r.URL.Path, reqID)
+	})
+}
+
+func DeadLettersHandler(d *webhook.Dispatcher) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		letters := d.DeadLetters(domain.TenantFromContext(r.Context()))
+		WriteJSON(w, r, http.StatusOK, NewDeadLettersResponse(letters))
+
+		log.Printf("[INFO]: %s %s: successful: count=%d", r.Method, r.URL.Path, len(letters))
+	})
+}
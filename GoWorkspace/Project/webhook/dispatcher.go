@@ -0,0 +1,358 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	mathrand "math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"Goworkspace/Project/domain"
+)
+
+var (
+	ErrQueueFull = errors.New("delivery queue is full") // Доставка не поставлена в очередь
+	ErrShutdown  = errors.New("dispatcher shut down")   // Доставка прервана остановкой сервера
+)
+
+type Config struct {
+	Workers        int           // одновременных доставок
+	QueueSize      int           // доставок, ожидающих воркера
+	MaxAttempts    int           // попыток до переноса в dead-letter
+	BaseBackoff    time.Duration // пауза после первой неудачи, дальше удваивается
+	MaxBackoff     time.Duration
+	Timeout        time.Duration // на одну попытку
+	MaxDeadLetters int           // хранятся последние, старые вытесняются
+	Client         *http.Client  // nil - клиент без редиректов и, без AllowPrivate, без внутренних адресов
+	AllowPrivate   bool          // разрешить адреса во внутренней сети: для локальной разработки и тестов
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Workers:        4,
+		QueueSize:      1024,
+		MaxAttempts:    6,
+		BaseBackoff:    time.Second,
+		MaxBackoff:     time.Minute,
+		Timeout:        10 * time.Second,
+		MaxDeadLetters: 1000,
+	}
+}
+
+// DeadLetter - доставка, от которой отказались: попытки кончились,
+// получатель ответил неповторяемой ошибкой или сервер остановился.
+type DeadLetter struct {
+	DeliveryID     string
+	Tenant         string
+	SubscriptionID int
+	URL            string
+	Event          domain.EventType
+	ItemID         int
+	Payload        []byte
+	Attempts       int
+	LastError      string
+	FailedAt       time.Time
+}
+
+// Dispatcher хранит подписки и доставляет им события из шины.
+// Handle подходит как асинхронный подписчик events.Bus.
+type Dispatcher struct {
+	cfg    Config
+	subs   *registry
+	queue  chan delivery
+	ctx    context.Context // отменяется, если Close не дождался воркеров
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
+	dead   []DeadLetter
+}
+
+type delivery struct {
+	id    string
+	subID int
+	event domain.Event
+	body  []byte
+}
+
+// NewDispatcher запускает воркеры доставки. Нулевые поля cfg берутся из DefaultConfig.
+func NewDispatcher(cfg Config) *Dispatcher {
+	def := DefaultConfig()
+	if cfg.Workers <= 0 {
+		cfg.Workers = def.Workers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = def.QueueSize
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = def.MaxAttempts
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = def.BaseBackoff
+	}
+	if cfg.MaxBackoff < cfg.BaseBackoff {
+		cfg.MaxBackoff = max(def.MaxBackoff, cfg.BaseBackoff)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+	if cfg.MaxDeadLetters <= 0 {
+		cfg.MaxDeadLetters = def.MaxDeadLetters
+	}
+	if cfg.Client == nil {
+		cfg.Client = newClient(cfg.AllowPrivate)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{cfg: cfg, subs: newRegistry(), queue: make(chan delivery, cfg.QueueSize), ctx: ctx, cancel: cancel}
+	d.wg.Add(cfg.Workers)
+	for range cfg.Workers {
+		go d.work()
+	}
+	return d
+}
+
+// Subscribe проверяет и сохраняет подписку. В ответе - с секретом и ID.
+func (d *Dispatcher) Subscribe(sub Subscription) (Subscription, error) {
+	sub, err := sub.normalize()
+	if err != nil {
+		return Subscription{}, err
+	}
+	if !d.cfg.AllowPrivate {
+		if err := checkTarget(sub.URL, d.cfg.Timeout); err != nil {
+			return Subscription{}, err
+		}
+	}
+	sub.CreatedAt = time.Now()
+	return d.subs.add(sub), nil
+}
+
+func (d *Dispatcher) Subscriptions(tenant string) []Subscription {
+	return d.subs.list(tenant, "")
+}
+
+// Unsubscribe удаляет подписку арендатора. Доставки, ещё не отправленные ей, отбрасываются.
+// Чужая подписка не найдена так же, как несуществующая.
+func (d *Dispatcher) Unsubscribe(tenant string, id int) error {
+	if !d.subs.remove(tenant, id) {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// DeadLetters возвращает доставки арендатора, от которых отказались, от старых к новым.
+func (d *Dispatcher) DeadLetters(tenant string) []DeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	res := make([]DeadLetter, 0, len(d.dead))
+	for _, letter := range d.dead {
+		if letter.Tenant == tenant {
+			res = append(res, letter)
+		}
+	}
+	return res
+}
+
+// Handle ставит событие в очередь для каждой подходящей подписки арендатора из ctx.
+// Не блокируется: если очередь полна, доставка сразу попадает в dead-letter.
+func (d *Dispatcher) Handle(ctx context.Context, event domain.Event) error {
+	for _, sub := range d.subs.list(domain.TenantFromContext(ctx), event.EventType()) {
+		id := newDeliveryID()
+		body, err := encodePayload(id, event)
+		if err != nil {
+			return fmt.Errorf("encode %s payload: %w", event.EventType(), err)
+		}
+		job := delivery{id: id, subID: sub.ID, event: event, body: body}
+
+		if err := d.enqueue(job); err != nil {
+			d.bury(job, sub, 0, err)
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) enqueue(job delivery) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return ErrShutdown
+	}
+	select {
+	case d.queue <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close перестаёт принимать события и ждёт, пока очередь будет доставлена.
+// Когда ctx истекает, текущие попытки прерываются, а недоставленное уходит в dead-letter.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for job := range d.queue {
+		d.deliver(job)
+	}
+}
+
+func (d *Dispatcher) deliver(job delivery) {
+	for attempt := 1; ; attempt++ {
+		sub, ok := d.subs.get(job.subID)
+		if !ok {
+			return // подписку удалили
+		}
+
+		err := d.send(sub, job)
+		if err == nil {
+			log.Printf("[INFO]: webhook %s delivered: subscription=%d event=%s attempts=%d", job.id, sub.ID, job.event.EventType(), attempt)
+			return
+		}
+		if d.ctx.Err() != nil {
+			d.bury(job, sub, attempt, fmt.Errorf("%w: %v", ErrShutdown, err))
+			return
+		}
+		if !retryable(err) || attempt >= d.cfg.MaxAttempts {
+			d.bury(job, sub, attempt, err)
+			return
+		}
+
+		wait := d.backoff(attempt)
+		log.Printf("[WARN]: webhook %s attempt %d failed, retrying in %s: %v", job.id, attempt, wait, err)
+		if !d.sleep(wait) {
+			d.bury(job, sub, attempt, fmt.Errorf("%w: %v", ErrShutdown, err))
+			return
+		}
+	}
+}
+
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("receiver responded %d %s", e.code, http.StatusText(e.code))
+}
+
+// retryable: сетевые ошибки, таймауты, 5xx, 408 и 429. Прочие 4xx повторять бессмысленно.
+func retryable(err error) bool {
+	var statusErr *statusError
+	if !errors.As(err, &statusErr) {
+		return true
+	}
+	return statusErr.code >= 500 || statusErr.code == http.StatusRequestTimeout || statusErr.code == http.StatusTooManyRequests
+}
+
+func (d *Dispatcher) send(sub Subscription, job delivery) error {
+	ctx, cancel := context.WithTimeout(d.ctx, d.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(job.body))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryIDHeader, job.id)
+	req.Header.Set(EventHeader, string(job.event.EventType()))
+	req.Header.Set(TimestampHeader, fmt.Sprint(now.Unix()))
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, now, job.body))
+
+	resp, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // чтобы соединение вернулось в пул
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{code: resp.StatusCode}
+	}
+	return nil
+}
+
+// backoff - экспоненциальная пауза с джиттером: случайное значение
+// в [b/2, b], чтобы получатели не получали повторы одновременно.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	b := d.cfg.BaseBackoff
+	for i := 1; i < attempt && b < d.cfg.MaxBackoff; i++ {
+		b *= 2
+	}
+	b = min(b, d.cfg.MaxBackoff)
+	return b/2 + mathrand.N(b/2+1)
+}
+
+func (d *Dispatcher) sleep(wait time.Duration) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-d.ctx.Done():
+		return false
+	}
+}
+
+func (d *Dispatcher) bury(job delivery, sub Subscription, attempts int, err error) {
+	log.Printf("[ERROR]: webhook %s dead-lettered: subscription=%d event=%s attempts=%d: %v", job.id, sub.ID, job.event.EventType(), attempts, err)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.dead) >= d.cfg.MaxDeadLetters {
+		d.dead = slices.Delete(d.dead, 0, len(d.dead)-d.cfg.MaxDeadLetters+1)
+	}
+	d.dead = append(d.dead, DeadLetter{
+		DeliveryID:     job.id,
+		Tenant:         sub.Tenant,
+		SubscriptionID: sub.ID,
+		URL:            sub.URL,
+		Event:          job.event.EventType(),
+		ItemID:         job.event.ItemID(),
+		Payload:        job.body,
+		Attempts:       attempts,
+		LastError:      err.Error(),
+		FailedAt:       time.Now(),
+	})
+}
+
+func newDeliveryID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,363 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Goworkspace/Project/domain"
+	"Goworkspace/Project/webhook"
+)
+
+const secret = "0123456789abcdef"
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver - httptest-получатель, отвечающий статусами из statuses по очереди, затем 200.
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []received
+	statuses []int
+	got      chan struct{}
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	rec := &receiver{statuses: statuses, got: make(chan struct{}, 100)}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		rec.requests = append(rec.requests, received{header: r.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(rec.statuses) > 0 {
+			status, rec.statuses = rec.statuses[0], rec.statuses[1:]
+		}
+		rec.mu.Unlock()
+		w.WriteHeader(status)
+		rec.got <- struct{}{}
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+func (rec *receiver) wait(t *testing.T, n int) []received {
+	t.Helper()
+	for range n {
+		select {
+		case <-rec.got:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected %d requests", n)
+		}
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]received(nil), rec.requests...)
+}
+
+func fastConfig() webhook.Config {
+	return webhook.Config{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, Timeout: time.Second, AllowPrivate: true}
+}
+
+func newDispatcher(t *testing.T, cfg webhook.Config) *webhook.Dispatcher {
+	d := webhook.NewDispatcher(cfg)
+	t.Cleanup(func() { d.Close(context.Background()) })
+	return d
+}
+
+func created(id int) domain.Event {
+	return domain.ItemCreated{Item: domain.Item{ID: id, Name: "a", Version: 1}, At: time.Now()}
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	t.Run("Signed payload reaches receiver", func(t *testing.T) {
+		rec := newReceiver(t)
+		d := newDispatcher(t, fastConfig())
+		sub, err := d.Subscribe(webhook.Subscription{URL: rec.URL, Secret: secret})
+		if err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+
+		d.Handle(context.Background(), created(7))
+		req := rec.wait(t, 1)[0]
+
+		sig, ts := req.header.Get(webhook.SignatureHeader), req.header.Get(webhook.TimestampHeader)
+		if !webhook.Verify(sub.Secret, sig, ts, req.body, time.Minute) {
+			t.Fatalf("signature %q does not verify", sig)
+		}
+		if webhook.Verify("another-secret-value", sig, ts, req.body, time.Minute) {
+			t.Fatal("signature verified with a wrong secret")
+		}
+
+		var payload webhook.Payload
+		if err := json.Unmarshal(req.body, &payload); err != nil {
+			t.Fatalf("decode payload: %v", err)
+		}
+		if payload.Type != domain.EventItemCreated || payload.ItemID != 7 || payload.Item == nil || payload.Item.Name != "a" {
+			t.Fatalf("unexpected payload: %s", req.body)
+		}
+		if payload.ID != req.header.Get(webhook.DeliveryIDHeader) || req.header.Get(webhook.EventHeader) != string(domain.EventItemCreated) {
+			t.Fatalf("unexpected headers: %v", req.header)
+		}
+	})
+
+	t.Run("Only subscribed events are delivered", func(t *testing.T) {
+		rec := newReceiver(t)
+		d := newDispatcher(t, fastConfig())
+		d.Subscribe(webhook.Subscription{URL: rec.URL, Events: []domain.EventType{domain.EventItemDeleted}})
+
+		d.Handle(context.Background(), created(1))
+		d.Handle(context.Background(), domain.ItemDeleted{ID: 1, At: time.Now()})
+
+		req := rec.wait(t, 1)[0]
+		if req.header.Get(webhook.EventHeader) != string(domain.EventItemDeleted) {
+			t.Fatalf("expected only item.deleted, got %s", req.header.Get(webhook.EventHeader))
+		}
+		if err := d.Close(context.Background()); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+		if n := len(rec.wait(t, 0)); n != 1 {
+			t.Fatalf("expected 1 request, got %d", n)
+		}
+	})
+
+	t.Run("Retries server errors with the same delivery ID", func(t *testing.T) {
+		rec := newReceiver(t, http.StatusInternalServerError, http.StatusTooManyRequests)
+		d := newDispatcher(t, fastConfig())
+		d.Subscribe(webhook.Subscription{URL: rec.URL})
+
+		d.Handle(context.Background(), created(1))
+		reqs := rec.wait(t, 3)
+
+		id := reqs[0].header.Get(webhook.DeliveryIDHeader)
+		for _, req := range reqs {
+			if req.header.Get(webhook.DeliveryIDHeader) != id {
+				t.Fatal("delivery ID changed between attempts")
+			}
+		}
+		d.Close(context.Background())
+		if letters := d.DeadLetters(domain.DefaultTenant); len(letters) != 0 {
+			t.Fatalf("expected no dead letters, got %+v", letters)
+		}
+	})
+}
+
+func TestDispatcher_DeadLetters(t *testing.T) {
+	t.Run("Exhausted retries", func(t *testing.T) {
+		rec := newReceiver(t, 500, 502, 503)
+		d := newDispatcher(t, fastConfig())
+		sub, _ := d.Subscribe(webhook.Subscription{URL: rec.URL})
+
+		d.Handle(context.Background(), created(3))
+		rec.wait(t, 3)
+		d.Close(context.Background())
+
+		letters := d.DeadLetters(domain.DefaultTenant)
+		if len(letters) != 1 {
+			t.Fatalf("expected 1 dead letter, got %+v", letters)
+		}
+		l := letters[0]
+		if l.Attempts != 3 || l.SubscriptionID != sub.ID || l.ItemID != 3 || l.Event != domain.EventItemCreated || len(l.Payload) == 0 || l.LastError == "" {
+			t.Fatalf("unexpected dead letter: %+v", l)
+		}
+	})
+
+	t.Run("Redirects are not followed", func(t *testing.T) {
+		target := newReceiver(t)
+		var calls atomic.Int32
+		redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+		}))
+		defer redirect.Close()
+
+		d := newDispatcher(t, fastConfig())
+		d.Subscribe(webhook.Subscription{URL: redirect.URL})
+		d.Handle(context.Background(), created(1))
+		d.Close(context.Background())
+
+		if n := len(target.wait(t, 0)); n != 0 || calls.Load() != 1 {
+			t.Fatalf("expected one redirect and no request to its target, got %d redirects and %d requests", calls.Load(), n)
+		}
+		if letters := d.DeadLetters(domain.DefaultTenant); len(letters) != 1 || letters[0].Attempts != 1 {
+			t.Fatalf("expected redirected delivery in dead letters, got %+v", letters)
+		}
+	})
+
+	t.Run("Client errors are not retried", func(t *testing.T) {
+		rec := newReceiver(t, http.StatusBadRequest)
+		d := newDispatcher(t, fastConfig())
+		d.Subscribe(webhook.Subscription{URL: rec.URL})
+
+		d.Handle(context.Background(), created(1))
+		rec.wait(t, 1)
+		d.Close(context.Background())
+
+		if letters := d.DeadLetters(domain.DefaultTenant); len(letters) != 1 || letters[0].Attempts != 1 {
+			t.Fatalf("expected single attempt dead letter, got %+v", letters)
+		}
+	})
+
+	t.Run("Close deadline aborts pending retries", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		cfg := fastConfig()
+		cfg.MaxAttempts, cfg.BaseBackoff, cfg.MaxBackoff = 10, time.Hour, time.Hour
+		d := webhook.NewDispatcher(cfg)
+		d.Subscribe(webhook.Subscription{URL: srv.URL})
+		d.Handle(context.Background(), created(1))
+
+		for calls.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := d.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected DeadlineExceeded, got: %v", err)
+		}
+
+		letters := d.DeadLetters(domain.DefaultTenant)
+		if len(letters) != 1 || letters[0].Attempts != 1 {
+			t.Fatalf("expected aborted delivery in dead letters, got %+v", letters)
+		}
+
+		// После остановки события сразу попадают в dead-letter
+		d.Handle(context.Background(), created(2))
+		if letters := d.DeadLetters(domain.DefaultTenant); len(letters) != 2 || letters[1].Attempts != 0 {
+			t.Fatalf("expected rejected delivery in dead letters, got %+v", letters)
+		}
+	})
+}
+
+func TestDispatcher_Subscriptions(t *testing.T) {
+	t.Run("Invalid subscription returns ValidationError", func(t *testing.T) {
+		d := newDispatcher(t, fastConfig())
+
+		_, err := d.Subscribe(webhook.Subscription{URL: "ftp://example.com", Secret: "short", Events: []domain.EventType{"item.renamed"}})
+		var validationErr *domain.ValidationError
+		if !errors.As(err, &validationErr) || !errors.Is(err, domain.ErrInvalidValue) {
+			t.Fatalf("expected ValidationError, got: %v", err)
+		}
+		if len(validationErr.Violations) != 3 {
+			t.Fatalf("expected url, secret and events violations, got %+v", validationErr.Violations)
+		}
+	})
+
+	t.Run("Internal targets are rejected", func(t *testing.T) {
+		cfg := fastConfig()
+		cfg.AllowPrivate = false
+		d := newDispatcher(t, cfg)
+
+		for _, target := range []string{
+			"http://127.0.0.1:8080/hook",
+			"http://localhost/hook",
+			"http://169.254.169.254/latest/meta-data",
+			"http://10.0.0.5/hook",
+			"http://192.168.1.1/hook",
+			"http://[::1]/hook",
+			"http://0.0.0.0/hook",
+		} {
+			_, err := d.Subscribe(webhook.Subscription{URL: target})
+			var validationErr *domain.ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Violations[0].Field != "url" {
+				t.Fatalf("%s: expected url violation, got: %v", target, err)
+			}
+		}
+		if subs := d.Subscriptions(domain.DefaultTenant); len(subs) != 0 {
+			t.Fatalf("expected no subscriptions, got %+v", subs)
+		}
+	})
+
+	t.Run("Secret is generated", func(t *testing.T) {
+		d := newDispatcher(t, fastConfig())
+
+		sub, err := d.Subscribe(webhook.Subscription{URL: "https://example.com/hook"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(sub.Secret) < webhook.MinSecretLength || sub.ID != 1 || sub.CreatedAt.IsZero() {
+			t.Fatalf("unexpected subscription: %+v", sub)
+		}
+	})
+
+	t.Run("Unsubscribe stops deliveries", func(t *testing.T) {
+		rec := newReceiver(t)
+		d := newDispatcher(t, fastConfig())
+		sub, _ := d.Subscribe(webhook.Subscription{URL: rec.URL})
+
+		if err := d.Unsubscribe(domain.DefaultTenant, sub.ID); err != nil {
+			t.Fatalf("unsubscribe: %v", err)
+		}
+		if err := d.Unsubscribe(domain.DefaultTenant, sub.ID); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got: %v", err)
+		}
+
+		d.Handle(context.Background(), created(1))
+		d.Close(context.Background())
+		if n := len(rec.wait(t, 0)); n != 0 {
+			t.Fatalf("expected no requests, got %d", n)
+		}
+	})
+
+	t.Run("Subscriptions are scoped to tenant", func(t *testing.T) {
+		rec := newReceiver(t)
+		d := newDispatcher(t, fastConfig())
+		sub, _ := d.Subscribe(webhook.Subscription{Tenant: "acme", URL: rec.URL})
+
+		if subs := d.Subscriptions(domain.DefaultTenant); len(subs) != 0 {
+			t.Fatalf("expected no default subscriptions, got %+v", subs)
+		}
+		if err := d.Unsubscribe(domain.DefaultTenant, sub.ID); !errors.Is(err, domain.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound for foreign subscription, got: %v", err)
+		}
+
+		d.Handle(context.Background(), created(1))
+		d.Handle(domain.ContextWithTenant(context.Background(), "acme"), created(2))
+		d.Close(context.Background())
+
+		reqs := rec.wait(t, 0)
+		if len(reqs) != 1 {
+			t.Fatalf("expected only the acme event, got %d requests", len(reqs))
+		}
+		var payload webhook.Payload
+		if err := json.Unmarshal(reqs[0].body, &payload); err != nil || payload.ItemID != 2 {
+			t.Fatalf("unexpected payload: %s", reqs[0].body)
+		}
+	})
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	now := time.Now()
+	sig := webhook.Sign(secret, now, body)
+	ts := func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }
+
+	if !webhook.Verify(secret, sig, ts(now), body, time.Minute) {
+		t.Fatal("expected valid signature")
+	}
+	if webhook.Verify(secret, sig, ts(now), []byte(`{"id":"2"}`), time.Minute) {
+		t.Fatal("tampered body verified")
+	}
+	old := now.Add(-time.Hour)
+	if webhook.Verify(secret, webhook.Sign(secret, old, body), ts(old), body, time.Minute) {
+		t.Fatal("stale timestamp verified")
+	}
+	if webhook.Verify(secret, sig, "not-a-number", body, 0) {
+		t.Fatal("malformed timestamp verified")
+	}
+}
@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"Goworkspace/Project/domain"
+)
+
+// Payload - тело запроса доставки. Поля элемента совпадают с ответами API.
+type Payload struct {
+	ID         string           `json:"id"` // ID доставки, как в заголовке X-Webhook-Id
+	Type       domain.EventType `json:"type"`
+	OccurredAt string           `json:"occurred_at"`
+	ItemID     int              `json:"item_id"`
+	Item       *ItemPayload     `json:"item,omitempty"` // нет у item.deleted
+}
+
+type ItemPayload struct {
+	ID         int               `json:"id"`
+	Name       string            `json:"name"`
+	Version    int               `json:"version"`
+	CreatedAt  string            `json:"created_at"`
+	UpdatedAt  string            `json:"updated_at"`
+	ParentID   int               `json:"parent_id,omitempty"`
+	ExpiresAt  string            `json:"expires_at,omitempty"`
+	Attributes map[string]string `json:"attributes"`
+	Tags       []string          `json:"tags"`
+}
+
+func newPayload(id string, event domain.Event) Payload {
+	p := Payload{ID: id, Type: event.EventType(), OccurredAt: formatTime(event.OccurredAt()), ItemID: event.ItemID()}
+	switch e := event.(type) {
+	case domain.ItemCreated:
+		p.Item = newItemPayload(e.Item)
+	case domain.ItemUpdated:
+		p.Item = newItemPayload(e.Item)
+	}
+	return p
+}
+
+func newItemPayload(item domain.Item) *ItemPayload {
+	res := &ItemPayload{
+		ID:         item.ID,
+		Name:       item.Name,
+		Version:    item.Version,
+		CreatedAt:  formatTime(item.CreatedAt),
+		UpdatedAt:  formatTime(item.UpdatedAt),
+		ParentID:   item.ParentID,
+		ExpiresAt:  formatTime(item.ExpiresAt),
+		Attributes: item.Attributes,
+		Tags:       item.Tags,
+	}
+	if res.Attributes == nil {
+		res.Attributes = map[string]string{}
+	}
+	if res.Tags == nil {
+		res.Tags = []string{}
+	}
+	return res
+}
+
+func encodePayload(id string, event domain.Event) ([]byte, error) {
+	return json.Marshal(newPayload(id, event))
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
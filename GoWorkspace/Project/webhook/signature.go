@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки запроса доставки.
+const (
+	SignatureHeader  = "X-Webhook-Signature" // sha256=<hex HMAC-SHA256 от "<timestamp>.<тело>">
+	TimestampHeader  = "X-Webhook-Timestamp" // Unix-время отправки попытки, в секундах
+	DeliveryIDHeader = "X-Webhook-Id"        // одинаков во всех попытках одной доставки
+	EventHeader      = "X-Webhook-Event"
+)
+
+const signaturePrefix = "sha256="
+
+// Sign подписывает тело вместе со временем отправки: старую подпись нельзя
+// приложить к повторно отправленному запросу с другим временем.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись на стороне получателя. Запросы старше tolerance отклоняются;
+// tolerance <= 0 отключает проверку времени.
+func Verify(secret, signature, timestamp string, body []byte, tolerance time.Duration) bool {
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	ts := time.Unix(sec, 0)
+	if tolerance > 0 {
+		if age := time.Since(ts); age > tolerance || age < -tolerance {
+			return false
+		}
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, ts, body)))
+}
@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"Goworkspace/Project/domain"
+)
+
+const (
+	MaxURLLength    = 2048
+	MinSecretLength = 16
+	MaxSecretLength = 256
+)
+
+// Subscription - адрес, на который отправляются события арендатора Tenant. Пустой Events - все события.
+type Subscription struct {
+	ID        int
+	Tenant    string // пустой - domain.DefaultTenant
+	URL       string
+	Secret    string // ключ HMAC подписи; генерируется, если не задан
+	Events    []domain.EventType
+	CreatedAt time.Time
+}
+
+func (s Subscription) wants(t domain.EventType) bool {
+	return len(s.Events) == 0 || slices.Contains(s.Events, t)
+}
+
+var knownEvents = []domain.EventType{domain.EventItemCreated, domain.EventItemUpdated, domain.EventItemDeleted}
+
+// normalize проверяет подписку и заполняет секрет и список событий.
+func (s Subscription) normalize() (Subscription, error) {
+	var v domain.ValidationError
+
+	if s.Tenant == "" {
+		s.Tenant = domain.DefaultTenant
+	}
+	if s.URL == "" {
+		v.Add("url", domain.CodeRequired, "must not be empty", domain.ErrInvalidValue)
+	} else if len(s.URL) > MaxURLLength {
+		v.Add("url", domain.CodeTooLong, "must be at most 2048 bytes", domain.ErrInvalidValue)
+	} else if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.Add("url", domain.CodeInvalid, "must be an absolute http or https URL", domain.ErrInvalidValue)
+	}
+
+	switch {
+	case s.Secret == "":
+		s.Secret = newSecret()
+	case len(s.Secret) < MinSecretLength:
+		v.Add("secret", domain.CodeInvalid, "must be at least 16 bytes", domain.ErrInvalidValue)
+	case len(s.Secret) > MaxSecretLength:
+		v.Add("secret", domain.CodeTooLong, "must be at most 256 bytes", domain.ErrInvalidValue)
+	}
+
+	events := slices.Clone(s.Events)
+	for _, t := range events {
+		if !slices.Contains(knownEvents, t) {
+			v.Add("events", domain.CodeInvalid, "unknown event type "+string(t), domain.ErrInvalidValue)
+		}
+	}
+	slices.Sort(events)
+	s.Events = slices.Compact(events)
+
+	return s, v.Err()
+}
+
+func newSecret() string {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// registry - потокобезопасный список подписок по возрастанию ID.
+type registry struct {
+	mu   sync.RWMutex
+	subs map[int]Subscription
+	next int
+}
+
+func newRegistry() *registry {
+	return &registry{subs: make(map[int]Subscription), next: 1}
+}
+
+func (r *registry) add(sub Subscription) Subscription {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub.ID = r.next
+	r.next++
+	r.subs[sub.ID] = sub
+	return sub
+}
+
+func (r *registry) get(id int) (Subscription, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sub, ok := r.subs[id]
+	return sub, ok
+}
+
+// remove удаляет подписку, только если она принадлежит tenant.
+func (r *registry) remove(tenant string, id int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if sub, ok := r.subs[id]; !ok || sub.Tenant != tenant {
+		return false
+	}
+	delete(r.subs, id)
+	return true
+}
+
+// list возвращает подписки tenant, которым нужен тип t; пустой t - все подписки tenant.
+func (r *registry) list(tenant string, t domain.EventType) []Subscription {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]Subscription, 0, len(r.subs))
+	for _, sub := range r.subs {
+		if sub.Tenant == tenant && (t == "" || sub.wants(t)) {
+			res = append(res, sub)
+		}
+	}
+	slices.SortFunc(res, func(a, b Subscription) int { return a.ID - b.ID })
+	return res
+}
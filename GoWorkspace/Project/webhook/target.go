@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	"Goworkspace/Project/domain"
+)
+
+// Адрес подписки задаёт любой клиент API, поэтому без Config.AllowPrivate доставка
+// на адреса из blockedPrefixes запрещена дважды: при подписке и при каждом соединении -
+// на случай смены DNS-записи.
+
+var errInternalTarget = errors.New("webhook target resolves to an internal address")
+
+// blockedPrefixes - сети, не доступные из интернета: внутренние, служебные, зарезервированные,
+// а также префиксы IPv6, в которые вложен произвольный адрес IPv4 (NAT64, 6to4, Teredo).
+var blockedPrefixes = mustParsePrefixes(
+	"0.0.0.0/8",       // "эта" сеть
+	"10.0.0.0/8",      // частная
+	"100.64.0.0/10",   // CGNAT
+	"127.0.0.0/8",     // loopback
+	"169.254.0.0/16",  // link-local, метаданные облаков
+	"172.16.0.0/12",   // частная
+	"192.0.0.0/24",    // служебные назначения IETF
+	"192.0.2.0/24",    // документация
+	"192.88.99.0/24",  // ретрансляторы 6to4
+	"192.168.0.0/16",  // частная
+	"198.18.0.0/15",   // тестирование производительности
+	"198.51.100.0/24", // документация
+	"203.0.113.0/24",  // документация
+	"224.0.0.0/4",     // multicast
+	"240.0.0.0/4",     // зарезервировано, включая broadcast
+	"::/96",           // неуказанный, loopback и устаревшие IPv4-совместимые адреса
+	"64:ff9b::/96",    // NAT64
+	"64:ff9b:1::/48",  // локальный NAT64
+	"100::/64",        // discard
+	"2001::/23",       // служебные назначения IETF, включая Teredo
+	"2001:db8::/32",   // документация
+	"2002::/16",       // 6to4
+	"fc00::/7",        // unique local
+	"fe80::/10",       // link-local
+	"fec0::/10",       // site-local, устарел
+	"ff00::/8",        // multicast
+)
+
+func mustParsePrefixes(prefixes ...string) []netip.Prefix {
+	parsed := make([]netip.Prefix, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		parsed = append(parsed, netip.MustParsePrefix(prefix))
+	}
+	return parsed
+}
+
+// internalAddr сообщает, ведёт ли адрес во внутреннюю или служебную сеть.
+// Адреса IPv4 в виде IPv6 (::ffff:a.b.c.d) проверяются как IPv4.
+func internalAddr(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return true
+	}
+	addr = addr.Unmap().WithZone("")
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTarget разрешает хост URL и отклоняет подписку, если хоть один его адрес внутренний.
+func checkTarget(rawURL string, timeout time.Duration) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return domain.NewValidationError("url", domain.CodeInvalid, "must be an absolute http or https URL", domain.ErrInvalidValue)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return domain.NewValidationError("url", domain.CodeInvalid, "host does not resolve", domain.ErrInvalidValue)
+	}
+	for _, addr := range addrs {
+		if internalAddr(addr) {
+			return domain.NewValidationError("url", domain.CodeInvalid, "must not point to an internal or reserved address", domain.ErrInvalidValue)
+		}
+	}
+	return nil
+}
+
+// newClient создаёт клиент доставки. Редиректы не выполняются - иначе получатель мог бы
+// перенаправить запрос на внутренний адрес; ответ 3xx - неудачная доставка без повторов.
+func newClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || internalAddr(addrPort.Addr()) {
+				return errInternalTarget
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // через прокси проверка адреса при соединении бессмысленна
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
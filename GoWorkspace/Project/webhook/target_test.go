@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestInternalAddr(t *testing.T) {
+	cases := []struct {
+		addr     string
+		internal bool
+	}{
+		{"0.1.2.3", true},
+		{"10.0.0.5", true},
+		{"100.64.0.1", true},
+		{"100.127.255.254", true},
+		{"127.0.0.2", true},
+		{"169.254.169.254", true},
+		{"172.16.0.1", true},
+		{"192.0.0.170", true},
+		{"192.0.2.1", true},
+		{"192.88.99.1", true},
+		{"192.168.1.1", true},
+		{"198.18.0.1", true},
+		{"198.19.255.255", true},
+		{"198.51.100.1", true},
+		{"203.0.113.1", true},
+		{"224.0.0.1", true},
+		{"240.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::", true},
+		{"::1", true},
+		{"::ffff:10.0.0.1", true},
+		{"::ffff:169.254.169.254", true},
+		{"64:ff9b::a00:1", true},
+		{"64:ff9b:1::a00:1", true},
+		{"100::1", true},
+		{"2001::a00:1", true},
+		{"2001:db8::1", true},
+		{"2002:a00:1::1", true},
+		{"fd00::1", true},
+		{"fe80::1%eth0", true},
+		{"fec0::1", true},
+		{"ff02::1", true},
+
+		{"8.8.8.8", false},
+		{"100.63.255.255", false},
+		{"100.128.0.1", false},
+		{"172.32.0.1", false},
+		{"198.20.0.1", false},
+		{"::ffff:8.8.8.8", false},
+		{"2606:4700:4700::1111", false},
+	}
+
+	for _, c := range cases {
+		if got := internalAddr(netip.MustParseAddr(c.addr)); got != c.internal {
+			t.Errorf("%s: expected internal=%v, got %v", c.addr, c.internal, got)
+		}
+	}
+	if !internalAddr(netip.Addr{}) {
+		t.Error("invalid address must be treated as internal")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	t.Run("Internal address is refused on connect", func(t *testing.T) {
+		_, err := newClient(false).Get(srv.URL)
+		if !errors.Is(err, errInternalTarget) {
+			t.Fatalf("expected errInternalTarget, got: %v", err)
+		}
+	})
+
+	t.Run("AllowPrivate connects", func(t *testing.T) {
+		resp, err := newClient(true).Get(srv.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	})
+}